@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"flag"
+	"os"
 	"testing"
 	"time"
 
@@ -38,7 +40,7 @@ func createServer(bindAddress string) app2.App {
 	appFlags := app2.CreateImmutableServerFlagsWithBindAddress(bindAddress)
 	k8sClient := fake.NewSimpleClientset()
 	aController := controller.Create(k8sClient)
-	anApp := app2.Create(appFlags, aController)
+	anApp := app2.Create(appFlags, k8sClient, nil, aController)
 	return anApp
 }
 
@@ -50,6 +52,10 @@ func startServer(t *testing.T, anApp app2.App) {
 }
 
 func createClient(server string) client2.Client {
+	// The client and the server are separate commands, and both have
+	// flags such as -kubeconfig, so the client's are defined anew.
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+
 	clientFlags := client2.CreateImmutableClientFlagsWithServerAddress(server)
 	return client2.Create(clientFlags)
 }
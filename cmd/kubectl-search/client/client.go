@@ -1,10 +1,17 @@
 package client
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"os"
+	"strings"
 
 	"github.com/kubideh/kubesearch/search/api"
+	"github.com/kubideh/kubesearch/search/federation"
+	"k8s.io/klog/v2"
 )
 
 // ConfigureDefault configures and returns a new Client.
@@ -24,21 +31,309 @@ func Create(flags ImmutableClientFlags) Client {
 
 // Client provides everything needed to run kubectl-search.
 type Client struct {
-	flags ImmutableClientFlags
+	flags     ImmutableClientFlags
+	config    Config // config is loaded by Run
+	namespace string // namespace is the only namespace that's searched, or empty for all namespaces
 }
 
-func (c Client) serverEndpoint() string {
-	return "http://" + c.flags.Server()
+// serverEndpoint returns the given server. It uses `https://` if
+// useTLS is true, and `http://` otherwise, unless the server already
+// includes a scheme.
+func serverEndpoint(server string, useTLS bool) string {
+	if strings.Contains(server, "://") {
+		return server
+	}
+
+	if useTLS {
+		return "https://" + server
+	}
+
+	return "http://" + server
+}
+
+// server returns the server given by flags, or else by the
+// configuration of the current kubeconfig context. It's empty if the
+// server should be discovered.
+func (c Client) server() string {
+	if c.flags.Server() != "" {
+		return c.flags.Server()
+	}
+	return c.config.Contexts[currentContext(c.flags)].Server
+}
+
+// output returns the output format given by flags, or else by the
+// configuration file.
+func (c Client) output() string {
+	if !c.flags.IsSet("output", "o") && c.config.Output != "" {
+		return c.config.Output
+	}
+	return c.flags.Output()
+}
+
+// scope returns the only namespace that's searched, or an empty
+// string if every namespace is searched. The namespace given by
+// flags comes first, and then the namespace of the kubeconfig
+// context unless the configuration file scopes searches to every
+// namespace. Every namespace is searched if there's no kubeconfig,
+// e.g., when -server is given outside of a cluster, or unless -n is
+// given when searching local manifests.
+func (c Client) scope() string {
+	switch {
+	case c.flags.AllNamespaces():
+		return ""
+	case c.flags.Local():
+		return c.flags.Namespace()
+	case c.flags.Namespace() != "":
+		return c.flags.Namespace()
+	case c.config.NamespaceScope == NamespaceScopeAll:
+		return ""
+	}
+
+	namespace, err := currentNamespace(c.flags)
+
+	if err != nil {
+		klog.V(2).Infof("Searching every namespace, because the namespace of the kubeconfig context is unknown: %v", err)
+		return ""
+	}
+
+	return namespace
+}
+
+// namespaces returns the namespaces to which the server restricts
+// searches, or nil if every namespace is searched.
+func (c Client) namespaces() []string {
+	if c.namespace == "" {
+		return nil
+	}
+	return []string{c.namespace}
+}
+
+// query returns the given query with its aliases expanded.
+func (c Client) query(query string) string {
+	return c.config.expandAliases(query)
+}
+
+// connect returns a connection to the server given by flags or by the
+// configuration file, or else to the server discovered in the current
+// kubeconfig context. Local manifests are searched in this process.
+func (c Client) connect() (connection, error) {
+	if c.flags.Local() {
+		return connectLocal(c.flags.Filenames())
+	}
+
+	server := c.server()
+
+	if server == "" {
+		return discoverServer(context.Background(), c.flags)
+	}
+
+	endpoint := serverEndpoint(server, c.flags.UseTLS())
+	httpClient, err := createHTTPClient(c.flags, endpoint)
+
+	if err != nil {
+		return connection{}, err
+	}
+
+	return connection{
+		endpoint: endpoint,
+		client:   httpClient,
+		close:    func() {},
+	}, nil
 }
 
 // Run creates a client that uses the given server endpoint to
 // queryString for Kubernetes objects.
 func (c Client) Run() error {
-	result, err := api.Search(c.serverEndpoint(), queryString())
+	config, err := loadConfig(c.flags.Config())
+
+	if err != nil {
+		return err
+	}
+
+	c.config = config
+
+	if _, err := createPrinter(c.output()); err != nil {
+		return err
+	}
+
+	if c.flags.AllNamespaces() && c.flags.Namespace() != "" {
+		return errors.New("-namespace can't be used with -all-namespaces")
+	}
+
+	c.namespace = c.scope()
+
+	if c.flags.Federated() && (c.flags.Get() || c.flags.Describe()) {
+		return errors.New("-get and -describe can't be used with -federated, because results are from other clusters")
+	}
 
-	fmt.Println(result)
+	if c.flags.Local() != (len(c.flags.Filenames()) > 0) {
+		return errors.New("-local and -filename must be used together")
+	}
+
+	if c.flags.Local() && (c.flags.Federated() || c.flags.Watch() || c.flags.Interactive() || c.flags.Get() || c.flags.Describe()) {
+		return errors.New("-local can't be used with -federated, -watch, -interactive, -get or -describe, because results aren't live objects")
+	}
+
+	if c.flags.Watch() && (c.flags.Federated() || c.flags.Interactive() || c.flags.Get() || c.flags.Describe()) {
+		return errors.New("-watch can't be used with -federated, -interactive, -get or -describe")
+	}
+
+	conn, err := c.connect()
+
+	if err != nil {
+		return err
+	}
+
+	defer conn.close()
+
+	if c.flags.Federated() {
+		return c.runFederated(conn)
+	}
+
+	if c.flags.Interactive() {
+		return c.runInteractive(c.searchFunc(conn))
+	}
+
+	if c.flags.Watch() {
+		return c.runWatch(conn)
+	}
+
+	results, facets, err := c.searchAllPages(conn)
+
+	if err != nil {
+		return err
+	}
+
+	if c.flags.Get() || c.flags.Describe() {
+		return c.inspect(topResults(results, c.flags.Top()))
+	}
+
+	if err := printResults(os.Stdout, c.output(), results, markersFor(os.Stdout)); err != nil {
+		return err
+	}
+
+	if len(results) == 0 && isTableOutput(c.output()) {
+		fmt.Fprintln(os.Stderr, "No results found.")
+	}
+
+	if c.flags.Explain() && isTableOutput(c.output()) {
+		if err := printExplanations(os.Stdout, results); err != nil {
+			return err
+		}
+	}
+
+	return printFacets(c.summaryOutput(), facets)
+}
+
+// summaryOutput returns where summaries such as facets are printed.
+// Structured output formats are meant to be piped into scripts, so
+// summaries are printed to stderr instead of stdout.
+func (c Client) summaryOutput() io.Writer {
+	if isTableOutput(c.output()) {
+		return os.Stdout
+	}
+	return os.Stderr
+}
+
+// searchAllPages fetches every page of results, and each page has
+// at most the chunk size given by flags. Facets are counted over
+// every hit, so they're asked for only with the first page.
+func (c Client) searchAllPages(conn connection) (results []api.ResultV2, facets []api.Facet, err error) {
+	options := api.Options{
+		Explain:    c.flags.Explain(),
+		Limit:      c.flags.ChunkSize(),
+		Facets:     c.flags.Facets(),
+		Sort:       c.flags.Sort(),
+		Namespaces: c.namespaces(),
+	}
+
+	for {
+		response, err := conn.search(context.Background(), c.query(queryString()), options)
+
+		if err != nil {
+			return results, facets, err
+		}
+
+		results = append(results, response.Results...)
+
+		if options.Continue == "" {
+			facets = response.Facets
+		}
+
+		if response.Metadata.Continue == "" {
+			return results, facets, nil
+		}
+
+		options.Continue = response.Metadata.Continue
+		options.Facets = nil
+	}
+}
+
+// inspect fetches the live objects of the given results, and then it
+// prints and describes them as asked for by flags.
+func (c Client) inspect(results []api.ResultV2) error {
+	fetcher, err := c.createObjectFetcher()
+
+	if err != nil {
+		return err
+	}
+
+	objects, err := fetcher.fetch(context.Background(), results, os.Stderr)
+
+	if err != nil {
+		return err
+	}
+
+	if c.flags.Get() {
+		if err := printObjects(os.Stdout, c.output(), objects); err != nil {
+			return err
+		}
+	}
+
+	if c.flags.Describe() {
+		return describeObjects(os.Stdout, createDescriberFunc(c.flags.KubeConfigFlags()), objects)
+	}
+
+	return nil
+}
+
+// searchFunc returns a function that fetches the first page of
+// results of a query, as many as are shown by the interactive finder.
+func (c Client) searchFunc(conn connection) finderSearchFunc {
+	return func(query string) ([]api.ResultV2, error) {
+		options := api.Options{Limit: finderLimit, Sort: c.flags.Sort(), Namespaces: c.namespaces()}
+		response, err := conn.search(context.Background(), c.query(query), options)
+		return response.Results, err
+	}
+}
+
+// createObjectFetcher returns an objectFetcher that uses the
+// kubeconfig given by flags. It returns an error if the kubeconfig
+// can't be loaded, e.g., because there's none.
+func (c Client) createObjectFetcher() (*objectFetcher, error) {
+	if _, err := createKubernetesConfig(c.flags); err != nil {
+		return nil, err
+	}
+
+	return createObjectFetcher(c.flags.KubeConfigFlags()), nil
+}
+
+func (c Client) runFederated(conn connection) error {
+	response, err := federation.SearchWithClient(conn.client, conn.endpoint, c.query(queryString()), c.namespaces(), c.flags.Facets())
+
+	if err := printFederatedResults(os.Stdout, c.output(), response.Results); err != nil {
+		return err
+	}
+
+	for _, f := range response.Failures {
+		fmt.Fprintf(os.Stderr, "warning: peer %s failed: %s\n", f.Peer, f.Error)
+	}
+
+	if err != nil {
+		return err
+	}
 
-	return err
+	return printFacets(c.summaryOutput(), response.Facets)
 }
 
 func queryString() string {
@@ -0,0 +1,105 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Scopes of searches given by the configuration file.
+const (
+	NamespaceScopeAll     = "all"     // search every namespace, unless -namespace is given
+	NamespaceScopeCurrent = "current" // search the namespace of the kubeconfig context, unless -all-namespaces is given; it's the default
+)
+
+// Config is the configuration file of kubectl-search. Flags given on
+// the command-line override it.
+//
+//	contexts:
+//	  prod:
+//	    server: https://kubesearch.prod.example.com
+//	output: wide
+//	namespaceScope: all
+//	aliases:
+//	  crashing: restarts:>5
+type Config struct {
+	Contexts       map[string]ContextConfig `json:"contexts,omitempty"`       // Contexts are keyed by the name of a kubeconfig context
+	Output         string                   `json:"output,omitempty"`         // Output is the default of -output
+	NamespaceScope string                   `json:"namespaceScope,omitempty"` // NamespaceScope is either NamespaceScopeAll or NamespaceScopeCurrent
+	Aliases        map[string]string        `json:"aliases,omitempty"`        // Aliases are words of a query that are replaced by their values
+}
+
+// ContextConfig configures kubectl-search for a kubeconfig context.
+type ContextConfig struct {
+	Server string `json:"server,omitempty"` // Server is the default of -server
+}
+
+// defaultConfigFile returns `$XDG_CONFIG_HOME/kubesearch/config.yaml`,
+// or `~/.config/kubesearch/config.yaml` if XDG_CONFIG_HOME isn't set.
+func defaultConfigFile() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "kubesearch", "config.yaml")
+	}
+
+	home, err := os.UserHomeDir()
+
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(home, ".config", "kubesearch", "config.yaml")
+}
+
+// loadConfig reads the given configuration file. A missing file is
+// the same as an empty one.
+func loadConfig(path string) (Config, error) {
+	var result Config
+
+	if path == "" {
+		return result, nil
+	}
+
+	data, err := os.ReadFile(path)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return result, nil
+	}
+
+	if err != nil {
+		return result, err
+	}
+
+	if err := yaml.UnmarshalStrict(data, &result); err != nil {
+		return result, fmt.Errorf("invalid configuration file %s: %w", path, err)
+	}
+
+	switch result.NamespaceScope {
+	case "", NamespaceScopeAll, NamespaceScopeCurrent:
+	default:
+		return result, fmt.Errorf("invalid configuration file %s: namespaceScope must be %q or %q", path, NamespaceScopeAll, NamespaceScopeCurrent)
+	}
+
+	return result, nil
+}
+
+// expandAliases replaces each word of the given query that's the
+// name of an alias by the value of that alias.
+func (c Config) expandAliases(query string) string {
+	if len(c.Aliases) == 0 {
+		return query
+	}
+
+	words := strings.Fields(query)
+
+	for i, w := range words {
+		if expansion, ok := c.Aliases[w]; ok {
+			words[i] = expansion
+		}
+	}
+
+	return strings.Join(words, " ")
+}
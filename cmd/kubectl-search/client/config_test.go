@@ -0,0 +1,82 @@
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	path := writeConfig(t, `contexts:
+  prod:
+    server: https://kubesearch.prod.example.com
+output: wide
+namespaceScope: all
+aliases:
+  crashing: restarts:>5
+`)
+
+	config, err := loadConfig(path)
+
+	require.NoError(t, err)
+	assert.Equal(t, Config{
+		Contexts:       map[string]ContextConfig{"prod": {Server: "https://kubesearch.prod.example.com"}},
+		Output:         OutputWide,
+		NamespaceScope: NamespaceScopeAll,
+		Aliases:        map[string]string{"crashing": "restarts:>5"},
+	}, config)
+}
+
+func TestLoadConfig_missing(t *testing.T) {
+	config, err := loadConfig(filepath.Join(t.TempDir(), "config.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, Config{}, config)
+
+	config, err = loadConfig("")
+	require.NoError(t, err)
+	assert.Equal(t, Config{}, config)
+}
+
+func TestLoadConfig_invalid(t *testing.T) {
+	cases := []struct {
+		name   string
+		config string
+	}{
+		{name: "unknown field", config: "flargle: blargle\n"},
+		{name: "invalid namespaceScope", config: "namespaceScope: flargle\n"},
+		{name: "invalid YAML", config: "contexts: [\n"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := loadConfig(writeConfig(t, c.config))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestDefaultConfigFile(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "/flargle")
+	assert.Equal(t, "/flargle/kubesearch/config.yaml", defaultConfigFile())
+
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("HOME", "/blargle")
+	assert.Equal(t, "/blargle/.config/kubesearch/config.yaml", defaultConfigFile())
+}
+
+func TestExpandAliases(t *testing.T) {
+	config := Config{Aliases: map[string]string{"crashing": "restarts:>5", "prod": "namespace:prod"}}
+
+	assert.Equal(t, "payment restarts:>5 namespace:prod", config.expandAliases("payment  crashing prod"))
+	assert.Equal(t, "crashingly", config.expandAliases("crashingly"))
+	assert.Equal(t, "payment  crashing", Config{}.expandAliases("payment  crashing"))
+}
+
+func writeConfig(t *testing.T, config string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(config), 0o600))
+	return path
+}
@@ -0,0 +1,88 @@
+package client
+
+import (
+	"net/http"
+	"strings"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
+)
+
+// createHTTPClient returns a client that forwards credentials, e.g.,
+// a bearer token, an exec plugin or a client certificate, to the
+// KubeSearch server at the given endpoint. They're those of the
+// kubeconfig context given by flags, or else of the current context.
+// If no kubeconfig can be loaded, or if the endpoint isn't trusted
+// with credentials, then no credentials are forwarded. The TLS flags
+// override the client certificate of the kubeconfig.
+func createHTTPClient(flags ImmutableClientFlags, endpoint string) (*http.Client, error) {
+	config := &rest.Config{}
+
+	if trustedWithCredentials(flags, endpoint) {
+		config = loadCredentials(flags)
+	} else {
+		klog.V(2).Infof("Not forwarding credentials to %s, because it isn't an https server whose certificate is verified; use -insecure-forward-credentials to forward them anyway", endpoint)
+	}
+
+	if flags.ClientCertificate() != "" {
+		config.TLSClientConfig.CertFile = flags.ClientCertificate()
+		config.TLSClientConfig.KeyFile = flags.ClientKey()
+		config.TLSClientConfig.CertData = nil
+		config.TLSClientConfig.KeyData = nil
+	}
+
+	config.TLSClientConfig.CAFile = flags.CertificateAuthority()
+	config.TLSClientConfig.Insecure = flags.InsecureSkipTLSVerify()
+
+	transport, err := rest.TransportFor(config)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Client{Transport: transport}, nil
+}
+
+// trustedWithCredentials returns true if the given endpoint is an
+// https server whose certificate is verified, so that credentials
+// can't leak to a mistyped or hostile server, or if forwarding
+// credentials anyway is asked for by flags.
+func trustedWithCredentials(flags ImmutableClientFlags, endpoint string) bool {
+	if flags.InsecureForwardCredentials() {
+		return true
+	}
+	return strings.HasPrefix(endpoint, "https://") && !flags.InsecureSkipTLSVerify()
+}
+
+func loadCredentials(flags ImmutableClientFlags) *rest.Config {
+	config, err := createKubernetesConfig(flags)
+
+	if err != nil {
+		klog.V(2).Infoln("not forwarding credentials: ", err)
+		return &rest.Config{}
+	}
+
+	return credentialsOnly(config)
+}
+
+// credentialsOnly returns a copy of the given config that keeps the
+// credentials of the user, but not the CA or server name of the
+// Kubernetes API server, because they don't apply to KubeSearch.
+func credentialsOnly(config *rest.Config) *rest.Config {
+	return &rest.Config{
+		Host:                config.Host,
+		Username:            config.Username,
+		Password:            config.Password,
+		BearerToken:         config.BearerToken,
+		BearerTokenFile:     config.BearerTokenFile,
+		AuthProvider:        config.AuthProvider,
+		AuthConfigPersister: config.AuthConfigPersister,
+		ExecProvider:        config.ExecProvider,
+		TLSClientConfig: rest.TLSClientConfig{
+			CertFile: config.CertFile,
+			KeyFile:  config.KeyFile,
+			CertData: config.CertData,
+			KeyData:  config.KeyData,
+		},
+	}
+}
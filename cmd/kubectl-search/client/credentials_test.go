@@ -0,0 +1,110 @@
+package client
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const kubeConfigWithToken = `apiVersion: v1
+kind: Config
+clusters:
+- name: flargle
+  cluster:
+    server: https://flargle.example.com
+users:
+- name: blargle
+  user:
+    token: bobble
+contexts:
+- name: flargle
+  context:
+    cluster: flargle
+    user: blargle
+current-context: flargle
+`
+
+func TestCreateHTTPClient(t *testing.T) {
+	cases := []struct {
+		name          string
+		tls           bool
+		forward       bool
+		authorization string
+	}{
+		{name: "http", tls: false, forward: false, authorization: ""},
+		{name: "https", tls: true, forward: false, authorization: "Bearer bobble"},
+		{name: "http with -insecure-forward-credentials", tls: false, forward: true, authorization: "Bearer bobble"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var authorization string
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				authorization = r.Header.Get("Authorization")
+			})
+
+			server := httptest.NewServer(handler)
+			caFile := ""
+
+			if c.tls {
+				server.Close()
+				server = httptest.NewTLSServer(handler)
+				caFile = writeCertificateAuthority(t, server)
+			}
+			defer server.Close()
+
+			flags := createCredentialsFlags(t, caFile, c.forward)
+
+			client, err := createHTTPClient(flags, server.URL)
+			require.NoError(t, err)
+
+			response, err := client.Get(server.URL)
+			require.NoError(t, err)
+			response.Body.Close()
+
+			assert.Equal(t, c.authorization, authorization)
+		})
+	}
+}
+
+func TestTrustedWithCredentials(t *testing.T) {
+	assert.False(t, trustedWithCredentials(createCredentialsFlags(t, "", false), "http://flargle:8080"))
+	assert.True(t, trustedWithCredentials(createCredentialsFlags(t, "", false), "https://flargle:8443"))
+	assert.True(t, trustedWithCredentials(createCredentialsFlags(t, "", true), "http://flargle:8080"))
+
+	insecure := createCredentialsFlags(t, "", false)
+	*insecure.insecureSkipTLSVerify = true
+	assert.False(t, trustedWithCredentials(insecure, "https://flargle:8443"))
+}
+
+// createCredentialsFlags returns flags that load the credentials of
+// a kubeconfig with a bearer token and that verify the server using
+// the given CA file.
+func createCredentialsFlags(t *testing.T, caFile string, forward bool) ImmutableClientFlags {
+	kubeConfig := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, os.WriteFile(kubeConfig, []byte(kubeConfigWithToken), 0o600))
+
+	empty, insecure := "", false
+
+	return ImmutableClientFlags{
+		kubeConfigFlags:            createConfigFlags(kubeConfig, empty),
+		certificateAuthority:       &caFile,
+		clientCertificate:          &empty,
+		clientKey:                  &empty,
+		insecureSkipTLSVerify:      &insecure,
+		insecureForwardCredentials: &forward,
+	}
+}
+
+func writeCertificateAuthority(t *testing.T, server *httptest.Server) string {
+	file := filepath.Join(t.TempDir(), "ca.crt")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	require.NoError(t, os.WriteFile(file, data, 0o600))
+	return file
+}
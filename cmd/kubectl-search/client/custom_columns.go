@@ -0,0 +1,134 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/printers"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// customColumnsPrinter prints a table whose columns are given by
+// `-output custom-columns=<header>:<path>,...`, the same as kubectl.
+type customColumnsPrinter struct {
+	headers []string
+	paths   []*jsonpath.JSONPath
+}
+
+// createCustomColumnsPrinter returns a printer of the given columns,
+// e.g., `NAME:.metadata.name,RANK:.search.rank`. Braces around each
+// path are optional.
+func createCustomColumnsPrinter(spec string) (*customColumnsPrinter, error) {
+	result := &customColumnsPrinter{}
+
+	for _, column := range strings.Split(spec, ",") {
+		parts := strings.SplitN(column, ":", 2)
+
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid custom column %q; expected <header>:<path>", column)
+		}
+
+		path := jsonpath.New(parts[0]).AllowMissingKeys(true)
+
+		if err := path.Parse(relaxedPath(parts[1])); err != nil {
+			return nil, fmt.Errorf("invalid path of custom column %q: %w", parts[0], err)
+		}
+
+		result.headers = append(result.headers, parts[0])
+		result.paths = append(result.paths, path)
+	}
+
+	return result, nil
+}
+
+// relaxedPath returns the given path surrounded by braces, and it
+// adds a leading dot if there's none.
+func relaxedPath(path string) string {
+	path = strings.TrimSuffix(strings.TrimPrefix(path, "{"), "}")
+
+	if !strings.HasPrefix(path, ".") {
+		path = "." + path
+	}
+
+	return "{" + path + "}"
+}
+
+// PrintObj prints a row for the given object, or for each item if
+// it's a list, headed by a row of the headers of each column.
+func (p *customColumnsPrinter) PrintObj(obj runtime.Object, out io.Writer) error {
+	items := []runtime.Object{obj}
+
+	if meta.IsListType(obj) {
+		var err error
+
+		if items, err = meta.ExtractList(obj); err != nil {
+			return err
+		}
+	}
+
+	writer := printers.GetNewTabWriter(out)
+
+	fmt.Fprintln(writer, strings.Join(p.headers, "\t"))
+
+	for _, item := range items {
+		row, err := p.row(item)
+
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(writer, strings.Join(row, "\t"))
+	}
+
+	return writer.Flush()
+}
+
+func (p *customColumnsPrinter) row(obj runtime.Object) ([]string, error) {
+	var content map[string]interface{}
+
+	if u, ok := obj.(runtime.Unstructured); ok {
+		content = u.UnstructuredContent()
+	} else {
+		var err error
+
+		if content, err = runtime.DefaultUnstructuredConverter.ToUnstructured(obj); err != nil {
+			return nil, err
+		}
+	}
+
+	row := make([]string, 0, len(p.paths))
+
+	for _, path := range p.paths {
+		values, err := path.FindResults(content)
+
+		if err != nil {
+			return nil, err
+		}
+
+		row = append(row, cell(values))
+	}
+
+	return row, nil
+}
+
+// cell returns the comma-separated values found by a path, or
+// `<none>` if none were found.
+func cell(results [][]reflect.Value) string {
+	var values []string
+
+	for _, result := range results {
+		for _, v := range result {
+			values = append(values, fmt.Sprint(v.Interface()))
+		}
+	}
+
+	if len(values) == 0 {
+		return "<none>"
+	}
+
+	return strings.Join(values, ",")
+}
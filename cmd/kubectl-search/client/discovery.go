@@ -0,0 +1,267 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/kubideh/kubesearch/search/api"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+	"k8s.io/klog/v2"
+)
+
+// servicePortName is the name of the port of the Service on which
+// KubeSearch serves plain HTTP, as in deploy/kubesearch.yaml.
+const servicePortName = "http"
+
+// connection is how the KubeSearch server is reached. Close releases
+// anything opened to reach it, e.g., a port-forward. If local is
+// set, then searches are done in this process instead.
+type connection struct {
+	endpoint string
+	client   *http.Client
+	close    func()
+	local    api.SearchV2Func
+}
+
+// search returns a page of the results of the given query, either
+// from the server or from the objects indexed in this process.
+func (c connection) search(ctx context.Context, query string, options api.Options) (api.ResponseV2, error) {
+	if c.local != nil {
+		return c.local(ctx, query, options)
+	}
+
+	return api.SearchV2WithClient(ctx, c.client, c.endpoint, query, options)
+}
+
+// discoverServer finds the KubeSearch Service in the current
+// kubeconfig context, and it reaches the Service using a port-forward
+// to one of its ready Pods. If that fails, e.g., because the user
+// may not create port-forwards, then it uses the service proxy of the
+// Kubernetes API server instead, but only if flags allow it, because
+// the service proxy doesn't forward the credentials of the user.
+func discoverServer(ctx context.Context, flags ImmutableClientFlags) (connection, error) {
+	config, err := createKubernetesConfig(flags)
+
+	if err != nil {
+		return connection{}, fmt.Errorf("can't discover the KubeSearch server; use -server to give its address: %w", err)
+	}
+
+	clientset, err := kubernetes.NewForConfig(config)
+
+	if err != nil {
+		return connection{}, err
+	}
+
+	service, port, err := findService(ctx, clientset, flags.ServiceNamespace(), flags.ServiceSelector())
+
+	if err != nil {
+		return connection{}, err
+	}
+
+	result, err := portForward(ctx, config, clientset, flags, service, port)
+
+	if err == nil {
+		return result, nil
+	}
+
+	if !flags.ServiceProxy() {
+		return connection{}, fmt.Errorf("can't port-forward to %s/%s; use -service-proxy to reach it using the service proxy of the Kubernetes API server, or -server to give its address: %w", service.Namespace, service.Name, err)
+	}
+
+	klog.Warningf("Using the service proxy of the Kubernetes API server, which doesn't forward credentials, because a port-forward to %s/%s failed: %v", service.Namespace, service.Name, err)
+
+	return serviceProxy(config, service, port)
+}
+
+// findService returns the first Service, by name, in the given
+// namespace that matches the given label selector, and the port on
+// which it serves plain HTTP, or else its first port.
+func findService(ctx context.Context, clientset kubernetes.Interface, namespace, selector string) (*corev1.Service, corev1.ServicePort, error) {
+	services, err := clientset.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+
+	if err != nil {
+		return nil, corev1.ServicePort{}, fmt.Errorf("can't discover the KubeSearch server; use -server to give its address: %w", err)
+	}
+
+	sort.Slice(services.Items, func(i, j int) bool {
+		return services.Items[i].Name < services.Items[j].Name
+	})
+
+	for i := range services.Items {
+		for _, port := range services.Items[i].Spec.Ports {
+			if port.Name == servicePortName {
+				return &services.Items[i], port, nil
+			}
+		}
+	}
+
+	for i := range services.Items {
+		if len(services.Items[i].Spec.Ports) > 0 {
+			return &services.Items[i], services.Items[i].Spec.Ports[0], nil
+		}
+	}
+
+	return nil, corev1.ServicePort{}, fmt.Errorf("no KubeSearch Service matches %q in namespace %q; use -server to give its address", selector, namespace)
+}
+
+// portForward opens a port-forward from a random local port to the
+// given port of a ready Pod of the given Service. The credentials of
+// the user are forwarded to KubeSearch through the port-forward only
+// over https with a verified certificate, e.g., with
+// -certificate-authority, because the port-forward itself reaches the
+// Pod over plain TCP.
+func portForward(ctx context.Context, config *rest.Config, clientset kubernetes.Interface, flags ImmutableClientFlags, service *corev1.Service, port corev1.ServicePort) (connection, error) {
+	pod, err := findReadyPod(ctx, clientset, service)
+
+	if err != nil {
+		return connection{}, err
+	}
+
+	podPort, err := targetPort(pod, port.TargetPort)
+
+	if err != nil {
+		return connection{}, err
+	}
+
+	transport, upgrader, err := spdy.RoundTripperFor(config)
+
+	if err != nil {
+		return connection{}, err
+	}
+
+	url := clientset.CoreV1().RESTClient().Post().Resource("pods").Namespace(pod.Namespace).Name(pod.Name).SubResource("portforward").URL()
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, url)
+
+	stop, ready := make(chan struct{}), make(chan struct{})
+	forwarder, err := portforward.NewOnAddresses(dialer, []string{"localhost"}, []string{fmt.Sprintf("0:%d", podPort)}, stop, ready, io.Discard, io.Discard)
+
+	if err != nil {
+		return connection{}, err
+	}
+
+	errs := make(chan error, 1)
+
+	go func() {
+		errs <- forwarder.ForwardPorts()
+	}()
+
+	select {
+	case <-ready:
+	case err := <-errs:
+		return connection{}, err
+	}
+
+	ports, err := forwarder.GetPorts()
+
+	if err != nil || len(ports) == 0 {
+		close(stop)
+		return connection{}, fmt.Errorf("no local port was forwarded: %v", err)
+	}
+
+	endpoint := serverEndpoint("localhost:"+strconv.Itoa(int(ports[0].Local)), flags.UseTLS())
+	httpClient, err := createHTTPClient(flags, endpoint)
+
+	if err != nil {
+		close(stop)
+		return connection{}, err
+	}
+
+	klog.V(2).Infof("Forwarding localhost:%d to %s/%s port %d", ports[0].Local, pod.Namespace, pod.Name, podPort)
+
+	return connection{
+		endpoint: endpoint,
+		client:   httpClient,
+		close:    func() { close(stop) },
+	}, nil
+}
+
+// findReadyPod returns a running and ready Pod selected by the given
+// Service.
+func findReadyPod(ctx context.Context, clientset kubernetes.Interface, service *corev1.Service) (*corev1.Pod, error) {
+	selector := labels.SelectorFromSet(service.Spec.Selector).String()
+	pods, err := clientset.CoreV1().Pods(service.Namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range pods.Items {
+		if isReady(&pods.Items[i]) {
+			return &pods.Items[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("no Pod of the Service %s/%s is ready", service.Namespace, service.Name)
+}
+
+func isReady(pod *corev1.Pod) bool {
+	if pod.Status.Phase != corev1.PodRunning || pod.DeletionTimestamp != nil {
+		return false
+	}
+
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+
+	return false
+}
+
+// targetPort returns the number of the given target port of a Service
+// in the given Pod, and it looks up named ports in the containers.
+func targetPort(pod *corev1.Pod, target intstr.IntOrString) (int32, error) {
+	if target.Type == intstr.Int {
+		return target.IntVal, nil
+	}
+
+	for _, c := range pod.Spec.Containers {
+		for _, p := range c.Ports {
+			if p.Name == target.StrVal {
+				return p.ContainerPort, nil
+			}
+		}
+	}
+
+	return 0, fmt.Errorf("the Pod %s/%s has no port named %q", pod.Namespace, pod.Name, target.StrVal)
+}
+
+// serviceProxy reaches the given port of the given Service using the
+// service proxy of the Kubernetes API server. The API server doesn't
+// forward the credentials of the user, so KubeSearch must not require
+// authentication.
+func serviceProxy(config *rest.Config, service *corev1.Service, port corev1.ServicePort) (connection, error) {
+	if config.Host == "" {
+		return connection{}, errors.New("the kubeconfig has no server")
+	}
+
+	transport, err := rest.TransportFor(config)
+
+	if err != nil {
+		return connection{}, err
+	}
+
+	portName := port.Name
+
+	if portName == "" {
+		portName = strconv.Itoa(int(port.Port))
+	}
+
+	return connection{
+		endpoint: fmt.Sprintf("%s/api/v1/namespaces/%s/services/%s:%s/proxy", config.Host, service.Namespace, service.Name, portName),
+		client:   &http.Client{Transport: transport},
+		close:    func() {},
+	}, nil
+}
@@ -0,0 +1,136 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+)
+
+func TestFindService(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		createService("kubesearch", "b", corev1.ServicePort{Name: "metrics", Port: 9090}, corev1.ServicePort{Name: "http", Port: 8080}),
+		createService("kubesearch", "a", corev1.ServicePort{Name: "metrics", Port: 9090}),
+		createService("flargle", "c", corev1.ServicePort{Name: "http", Port: 8080}),
+	)
+
+	service, port, err := findService(context.Background(), clientset, "kubesearch", "app.kubernetes.io/name=kubesearch")
+
+	require.NoError(t, err)
+	assert.Equal(t, "b", service.Name, "the port named http comes first")
+	assert.Equal(t, int32(8080), port.Port)
+}
+
+func TestFindService_firstPort(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		createService("kubesearch", "b", corev1.ServicePort{Port: 8080}),
+		createService("kubesearch", "a"),
+		createService("kubesearch", "c", corev1.ServicePort{Port: 9090}),
+	)
+
+	service, port, err := findService(context.Background(), clientset, "kubesearch", "app.kubernetes.io/name=kubesearch")
+
+	require.NoError(t, err)
+	assert.Equal(t, "b", service.Name)
+	assert.Equal(t, int32(8080), port.Port)
+}
+
+func TestFindService_notFound(t *testing.T) {
+	clientset := fake.NewSimpleClientset(createService("kubesearch", "a", corev1.ServicePort{Port: 8080}))
+
+	_, _, err := findService(context.Background(), clientset, "kubesearch", "app.kubernetes.io/name=flargle")
+
+	assert.EqualError(t, err, `no KubeSearch Service matches "app.kubernetes.io/name=flargle" in namespace "kubesearch"; use -server to give its address`)
+}
+
+func TestTargetPort(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "blargle", Namespace: "kubesearch"},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{
+			{Ports: []corev1.ContainerPort{{Name: "metrics", ContainerPort: 9090}}},
+			{Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: 8080}}},
+		}},
+	}
+
+	port, err := targetPort(pod, intstr.FromInt(8443))
+	require.NoError(t, err)
+	assert.Equal(t, int32(8443), port)
+
+	port, err = targetPort(pod, intstr.FromString("http"))
+	require.NoError(t, err)
+	assert.Equal(t, int32(8080), port)
+
+	_, err = targetPort(pod, intstr.FromString("flargle"))
+	assert.EqualError(t, err, `the Pod kubesearch/blargle has no port named "flargle"`)
+}
+
+func TestIsReady(t *testing.T) {
+	ready := corev1.PodCondition{Type: corev1.PodReady, Status: corev1.ConditionTrue}
+	notReady := corev1.PodCondition{Type: corev1.PodReady, Status: corev1.ConditionFalse}
+	deleted := metav1.Now()
+
+	cases := []struct {
+		name  string
+		pod   corev1.Pod
+		ready bool
+	}{
+		{name: "running and ready", pod: corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodRunning, Conditions: []corev1.PodCondition{ready}}}, ready: true},
+		{name: "running but not ready", pod: corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodRunning, Conditions: []corev1.PodCondition{notReady}}}, ready: false},
+		{name: "running without conditions", pod: corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodRunning}}, ready: false},
+		{name: "pending", pod: corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodPending, Conditions: []corev1.PodCondition{ready}}}, ready: false},
+		{name: "deleted", pod: corev1.Pod{ObjectMeta: metav1.ObjectMeta{DeletionTimestamp: &deleted}, Status: corev1.PodStatus{Phase: corev1.PodRunning, Conditions: []corev1.PodCondition{ready}}}, ready: false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.ready, isReady(&c.pod))
+		})
+	}
+}
+
+func TestFindReadyPod(t *testing.T) {
+	service := createService("kubesearch", "kubesearch", corev1.ServicePort{Port: 8080})
+	service.Spec.Selector = map[string]string{"app": "kubesearch"}
+
+	clientset := fake.NewSimpleClientset(
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "kubesearch", Labels: map[string]string{"app": "kubesearch"}}, Status: corev1.PodStatus{Phase: corev1.PodPending}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "b", Namespace: "kubesearch", Labels: map[string]string{"app": "kubesearch"}}, Status: corev1.PodStatus{Phase: corev1.PodRunning, Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}}},
+	)
+
+	pod, err := findReadyPod(context.Background(), clientset, service)
+
+	require.NoError(t, err)
+	assert.Equal(t, "b", pod.Name)
+
+	service.Spec.Selector = map[string]string{"app": "flargle"}
+	_, err = findReadyPod(context.Background(), clientset, service)
+	assert.EqualError(t, err, "no Pod of the Service kubesearch/kubesearch is ready")
+}
+
+func TestServiceProxy(t *testing.T) {
+	service := createService("kubesearch", "kubesearch")
+
+	conn, err := serviceProxy(&rest.Config{Host: "https://flargle:6443"}, service, corev1.ServicePort{Port: 8080})
+	require.NoError(t, err)
+	assert.Equal(t, "https://flargle:6443/api/v1/namespaces/kubesearch/services/kubesearch:8080/proxy", conn.endpoint)
+
+	conn, err = serviceProxy(&rest.Config{Host: "https://flargle:6443"}, service, corev1.ServicePort{Name: "http", Port: 8080})
+	require.NoError(t, err)
+	assert.Equal(t, "https://flargle:6443/api/v1/namespaces/kubesearch/services/kubesearch:http/proxy", conn.endpoint)
+
+	_, err = serviceProxy(&rest.Config{}, service, corev1.ServicePort{Port: 8080})
+	assert.EqualError(t, err, "the kubeconfig has no server")
+}
+
+func createService(namespace, name string, ports ...corev1.ServicePort) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: map[string]string{"app.kubernetes.io/name": "kubesearch"}},
+		Spec:       corev1.ServiceSpec{Ports: ports},
+	}
+}
@@ -0,0 +1,29 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/kubideh/kubesearch/search/api"
+)
+
+// printExplanations prints the explanation of each of the given
+// results, headed by the command of that result.
+func printExplanations(out io.Writer, results []api.ResultV2) error {
+	for _, r := range results {
+		if r.Explanation == nil {
+			continue
+		}
+
+		explanation, err := json.MarshalIndent(r.Explanation, "", "  ")
+
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(out, "\n# %s\n%s\n", r.Command, explanation)
+	}
+
+	return nil
+}
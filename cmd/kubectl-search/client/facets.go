@@ -0,0 +1,38 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/kubideh/kubesearch/search/api"
+)
+
+// printFacets prints a summary of the given facets, e.g., how many
+// hits are Pods and in how many namespaces, followed by the count of
+// each value.
+func printFacets(out io.Writer, facets []api.Facet) error {
+	if len(facets) == 0 {
+		return nil
+	}
+
+	fmt.Fprintln(out)
+
+	for _, f := range facets {
+		fmt.Fprintf(out, "%d distinct values of %s\n", len(f.Counts), f.Dimension)
+	}
+
+	fmt.Fprintln(out)
+
+	writer := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintln(writer, "FACET\tVALUE\tCOUNT")
+
+	for _, f := range facets {
+		for _, c := range f.Counts {
+			fmt.Fprintf(writer, "%s\t%s\t%d\n", f.Dimension, c.Value, c.Count)
+		}
+	}
+
+	return writer.Flush()
+}
@@ -4,14 +4,46 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
 )
 
 // CreateImmutableClientFlags returns the ImmutableClientFlags for
 // Client. A list of the flags and their defaults are now given.
 //
-// -server (default: localhost:8080)
+// -server (default: empty string, i.e., discover the server)
+// -service-namespace (default: kubesearch)
+// -service-selector (default: app.kubernetes.io/name=kubesearch)
+// -config (default: $XDG_CONFIG_HOME/kubesearch/config.yaml)
+// -all-namespaces, -A (default: false)
+// -output, -o (default: table)
+// -federated (default: false)
+// -explain (default: false)
+// -chunk-size (default: 500)
+// -sort (default: empty string)
+// -facets (default: false)
+// -facet-dimensions (default: kind,namespace)
+// -interactive, -i (default: false)
+// -watch, -w (default: false)
+// -get (default: false)
+// -describe (default: false)
+// -top (default: 1)
+// -local (default: false)
+// -filename, -f (default: empty string)
+// -certificate-authority (default: empty string)
+// -client-certificate (default: empty string)
+// -client-key (default: empty string)
+// -insecure-skip-tls-verify (default: false)
+// -insecure-forward-credentials (default: false)
+// -service-proxy (default: false)
+//
+// The kubeconfig flags of kubectl are given as well, e.g.,
+// -kubeconfig, -context, -namespace or -n, -user and -as, except for
+// -server and the TLS flags, which are those of the KubeSearch server.
 func CreateImmutableClientFlags() ImmutableClientFlags {
-	return CreateImmutableClientFlagsWithServerAddress("localhost:8080")
+	return CreateImmutableClientFlagsWithServerAddress("")
 }
 
 // CreateImmutableClientFlagsWithServerAddress returns the
@@ -20,11 +52,74 @@ func CreateImmutableClientFlags() ImmutableClientFlags {
 func CreateImmutableClientFlagsWithServerAddress(server string) ImmutableClientFlags {
 	flag.Usage = printUsage
 
+	kubeConfigFlags := genericclioptions.NewConfigFlags(true)
+	kubeConfigFlags.APIServer = nil
+	kubeConfigFlags.CAFile = nil
+	kubeConfigFlags.CertFile = nil
+	kubeConfigFlags.KeyFile = nil
+	kubeConfigFlags.Insecure = nil
+	bindConfigFlags(flag.CommandLine, kubeConfigFlags)
+
+	allNamespaces := flag.Bool("all-namespaces", false, "search every namespace instead of the namespace of the kubeconfig context")
+	flag.BoolVar(allNamespaces, "A", false, "shorthand for -all-namespaces")
+
+	interactive := flag.Bool("interactive", false, "open a finder that searches as you type, previews the selected object and runs kubectl on it; the queryString is optional")
+	flag.BoolVar(interactive, "i", false, "shorthand for -interactive")
+
+	watch := flag.Bool("watch", false, "print the current results, and then keep printing results that are added, modified or deleted as the server indexes changes")
+	flag.BoolVar(watch, "w", false, "shorthand for -watch")
+
+	filename := flag.String("filename", "", "comma-separated files or directories of YAML or JSON manifests searched by -local, or - for stdin, e.g., the output of helm template or kustomize build")
+	flag.StringVar(filename, "f", "", "shorthand for -filename")
+
+	output := flag.String("output", OutputTable, "output format: table, wide, json, yaml, name, jsonpath=<template> or custom-columns=<header>:<path>,...")
+	flag.StringVar(output, "o", OutputTable, "shorthand for -output")
+
 	return ImmutableClientFlags{
-		server: flag.String("server", server, "the address and port of the KubeSearch server"),
+		server:                     flag.String("server", server, "(optional) the address and port of the KubeSearch server, optionally prefixed by http:// or https://; if not given, the KubeSearch Service is discovered in the current kubeconfig context and reached using a port-forward"),
+		config:                     flag.String("config", defaultConfigFile(), "(optional) path to the configuration file of kubectl-search"),
+		kubeConfigFlags:            kubeConfigFlags,
+		allNamespaces:              allNamespaces,
+		serviceNamespace:           flag.String("service-namespace", "kubesearch", "the namespace in which the KubeSearch Service is discovered if -server isn't given"),
+		serviceSelector:            flag.String("service-selector", "app.kubernetes.io/name=kubesearch", "the label selector by which the KubeSearch Service is discovered if -server isn't given"),
+		output:                     output,
+		federated:                  flag.Bool("federated", false, "query the federated search API of the KubeSearch server"),
+		explain:                    flag.Bool("explain", false, "explain how each result matched the query and how it was ranked"),
+		chunkSize:                  flag.Int("chunk-size", 500, "fetch results in chunks of this size, or all at once if 0"),
+		sort:                       flag.String("sort", "", "(optional) comma-separated fields by which results are sorted instead of relevance, e.g., -creationTimestamp or namespace,name; prefix a field with - to sort in descending order"),
+		facets:                     flag.Bool("facets", false, "print a summary of the number of hits by each of -facet-dimensions"),
+		facetDimensions:            flag.String("facet-dimensions", "kind,namespace", "comma-separated dimensions used by -facets, e.g., kind, namespace, label, label:<key>, ownerKind, node, or cluster if -federated"),
+		interactive:                interactive,
+		watch:                      watch,
+		get:                        flag.Bool("get", false, "fetch and print the live objects of the top results, as YAML unless -output is given"),
+		describe:                   flag.Bool("describe", false, "describe the live objects of the top results, the same as kubectl describe"),
+		top:                        flag.Int("top", 1, "the number of top results used by -get and -describe, or all results if 0"),
+		local:                      flag.Bool("local", false, "search the manifests given by -filename instead of a cluster, without a KubeSearch server"),
+		filename:                   filename,
+		certificateAuthority:       flag.String("certificate-authority", "", "(optional) path to a CA bundle used to verify the KubeSearch server; implies https://"),
+		clientCertificate:          flag.String("client-certificate", "", "(optional) path to a client certificate used to authenticate to the KubeSearch server; implies https://"),
+		clientKey:                  flag.String("client-key", "", "(optional) path to the private key matching -client-certificate"),
+		insecureSkipTLSVerify:      flag.Bool("insecure-skip-tls-verify", false, "don't verify the certificate of the KubeSearch server; implies https://"),
+		insecureForwardCredentials: flag.Bool("insecure-forward-credentials", false, "forward the credentials of the kubeconfig even over plain http:// or to a KubeSearch server whose certificate isn't verified"),
+		serviceProxy:               flag.Bool("service-proxy", false, "if a port-forward to the discovered KubeSearch Service fails, reach it using the service proxy of the Kubernetes API server, which doesn't forward credentials"),
 	}
 }
 
+// bindConfigFlags adds the given kubeconfig flags of kubectl, and
+// their shorthands, to the given flag set.
+func bindConfigFlags(flags *flag.FlagSet, kubeConfigFlags *genericclioptions.ConfigFlags) {
+	pflags := pflag.NewFlagSet("kubeconfig", pflag.ContinueOnError)
+	kubeConfigFlags.AddFlags(pflags)
+
+	pflags.VisitAll(func(f *pflag.Flag) {
+		flags.Var(f.Value, f.Name, f.Usage)
+
+		if f.Shorthand != "" {
+			flags.Var(f.Value, f.Shorthand, "shorthand for -"+f.Name)
+		}
+	})
+}
+
 func printUsage() {
 	fmt.Fprintf(flag.CommandLine.Output(), "Usage of %s:\n", os.Args[0])
 	flag.PrintDefaults()
@@ -36,23 +131,248 @@ func printUsage() {
 // the Client. Each flag will be populated with values from the
 // command-line after calling Parse().
 type ImmutableClientFlags struct {
-	server *string // server is an address and port that can be used by `http.Get`
+	server                     *string                        // server is an address and port that can be used by `http.Get`
+	config                     *string                        // config is the path to the configuration file
+	kubeConfigFlags            *genericclioptions.ConfigFlags // kubeConfigFlags are the kubeconfig flags of kubectl
+	allNamespaces              *bool                          // allNamespaces is whether every namespace is searched
+	serviceNamespace           *string                        // serviceNamespace is where the KubeSearch Service is discovered
+	serviceSelector            *string                        // serviceSelector selects the KubeSearch Service
+	output                     *string                        // output is the format in which results are printed
+	federated                  *bool                          // federated is whether to use the federated search API
+	explain                    *bool                          // explain is whether to explain each result
+	chunkSize                  *int                           // chunkSize is the number of results fetched per request
+	sort                       *string                        // sort is a comma-separated list of fields by which results are sorted
+	facets                     *bool                          // facets is whether to print a summary of facet counts
+	facetDimensions            *string                        // facetDimensions are the dimensions by which hits are counted
+	interactive                *bool                          // interactive is whether to open the interactive finder
+	watch                      *bool                          // watch is whether to keep printing changes to the results
+	get                        *bool                          // get is whether to print the live objects of the top results
+	describe                   *bool                          // describe is whether to describe the live objects of the top results
+	top                        *int                           // top is the number of results used by get and describe
+	local                      *bool                          // local is whether to search manifests instead of a cluster
+	filename                   *string                        // filename is a comma-separated list of manifests searched locally
+	certificateAuthority       *string                        // certificateAuthority is the path to a CA bundle for the server
+	clientCertificate          *string                        // clientCertificate is the path to a client certificate
+	clientKey                  *string                        // clientKey is the path to the client private key
+	insecureSkipTLSVerify      *bool                          // insecureSkipTLSVerify is whether to skip verifying the server
+	insecureForwardCredentials *bool                          // insecureForwardCredentials is whether to forward credentials to untrusted servers
+	serviceProxy               *bool                          // serviceProxy is whether to fall back to the service proxy of the API server
 }
 
 // Server returns an address and port that can be used by
 // `http.Get`, and it's populated by a value from the
-// command-line.
+// command-line. It's empty if the server should be discovered.
 func (f ImmutableClientFlags) Server() string {
 	return *f.server
 }
 
+// Config returns the path to the configuration file, and it's
+// populated by a value from the command-line.
+func (f ImmutableClientFlags) Config() string {
+	return *f.config
+}
+
+// Namespace returns the only namespace that's searched, and it's
+// populated by a value from the command-line.
+func (f ImmutableClientFlags) Namespace() string {
+	return *f.kubeConfigFlags.Namespace
+}
+
+// AllNamespaces returns whether every namespace is searched, and it's
+// populated by a value from the command-line.
+func (f ImmutableClientFlags) AllNamespaces() bool {
+	return *f.allNamespaces
+}
+
+// ServiceNamespace returns the namespace in which the KubeSearch
+// Service is discovered, and it's populated by a value from the
+// command-line.
+func (f ImmutableClientFlags) ServiceNamespace() string {
+	return *f.serviceNamespace
+}
+
+// ServiceSelector returns the label selector by which the KubeSearch
+// Service is discovered, and it's populated by a value from the
+// command-line.
+func (f ImmutableClientFlags) ServiceSelector() string {
+	return *f.serviceSelector
+}
+
+// Output returns the format in which results are printed, and it's
+// populated by a value from the command-line.
+func (f ImmutableClientFlags) Output() string {
+	return *f.output
+}
+
+// Federated returns whether the federated search API of the server
+// should be used, and it's populated by a value from the
+// command-line.
+func (f ImmutableClientFlags) Federated() bool {
+	return *f.federated
+}
+
+// Explain returns whether each result should be explained, and it's
+// populated by a value from the command-line.
+func (f ImmutableClientFlags) Explain() bool {
+	return *f.explain
+}
+
+// ChunkSize returns the number of results fetched per request, and
+// it's populated by a value from the command-line.
+func (f ImmutableClientFlags) ChunkSize() int {
+	return *f.chunkSize
+}
+
+// Sort returns a comma-separated list of fields by which results are
+// sorted, and it's populated by a value from the command-line.
+func (f ImmutableClientFlags) Sort() string {
+	return *f.sort
+}
+
+// Facets returns the dimensions by which hits are counted if a
+// facet summary should be printed, and nil otherwise. It's
+// populated by values from the command-line.
+func (f ImmutableClientFlags) Facets() []string {
+	if !*f.facets {
+		return nil
+	}
+	return strings.Split(*f.facetDimensions, ",")
+}
+
+// Interactive returns whether the interactive finder should be
+// opened, and it's populated by a value from the command-line.
+func (f ImmutableClientFlags) Interactive() bool {
+	return *f.interactive
+}
+
+// Watch returns whether changes to the results should be printed
+// after the current results, and it's populated by a value from the
+// command-line.
+func (f ImmutableClientFlags) Watch() bool {
+	return *f.watch
+}
+
+// Get returns whether the live objects of the top results should be
+// printed, and it's populated by a value from the command-line.
+func (f ImmutableClientFlags) Get() bool {
+	return *f.get
+}
+
+// Describe returns whether the live objects of the top results
+// should be described, and it's populated by a value from the
+// command-line.
+func (f ImmutableClientFlags) Describe() bool {
+	return *f.describe
+}
+
+// Top returns the number of top results used by Get and Describe,
+// and it's populated by a value from the command-line.
+func (f ImmutableClientFlags) Top() int {
+	return *f.top
+}
+
+// Local returns whether the manifests given by Filenames are
+// searched instead of a cluster, and it's populated by a value from
+// the command-line.
+func (f ImmutableClientFlags) Local() bool {
+	return *f.local
+}
+
+// Filenames returns the files and directories of manifests that are
+// searched locally, and it's populated by values from the
+// command-line.
+func (f ImmutableClientFlags) Filenames() []string {
+	if *f.filename == "" {
+		return nil
+	}
+	return strings.Split(*f.filename, ",")
+}
+
+// KubeConfig returns the path to a kubeconfig file, and it's
+// populated by a value from the command-line.
+func (f ImmutableClientFlags) KubeConfig() string {
+	return *f.kubeConfigFlags.KubeConfig
+}
+
+// Context returns the kubeconfig context, and it's populated by a
+// value from the command-line.
+func (f ImmutableClientFlags) Context() string {
+	return *f.kubeConfigFlags.Context
+}
+
+// KubeConfigFlags returns the kubeconfig flags of kubectl, which load
+// the kubeconfig the same as kubectl, and they're populated by values
+// from the command-line.
+func (f ImmutableClientFlags) KubeConfigFlags() *genericclioptions.ConfigFlags {
+	return f.kubeConfigFlags
+}
+
+// CertificateAuthority returns the path to a CA bundle used to
+// verify the server, and it's populated by a value from the
+// command-line.
+func (f ImmutableClientFlags) CertificateAuthority() string {
+	return *f.certificateAuthority
+}
+
+// ClientCertificate returns the path to a client certificate, and
+// it's populated by a value from the command-line.
+func (f ImmutableClientFlags) ClientCertificate() string {
+	return *f.clientCertificate
+}
+
+// ClientKey returns the path to the private key of the client
+// certificate, and it's populated by a value from the command-line.
+func (f ImmutableClientFlags) ClientKey() string {
+	return *f.clientKey
+}
+
+// InsecureSkipTLSVerify returns whether to skip verifying the
+// certificate of the server, and it's populated by a value from the
+// command-line.
+func (f ImmutableClientFlags) InsecureSkipTLSVerify() bool {
+	return *f.insecureSkipTLSVerify
+}
+
+// InsecureForwardCredentials returns whether to forward credentials
+// even over plain http or to a server whose certificate isn't
+// verified, and it's populated by a value from the command-line.
+func (f ImmutableClientFlags) InsecureForwardCredentials() bool {
+	return *f.insecureForwardCredentials
+}
+
+// ServiceProxy returns whether the discovered KubeSearch Service is
+// reached using the service proxy of the Kubernetes API server if a
+// port-forward fails, and it's populated by a value from the
+// command-line.
+func (f ImmutableClientFlags) ServiceProxy() bool {
+	return *f.serviceProxy
+}
+
+// IsSet returns true if any of the flags with the given names was
+// given on the command-line, e.g., `-output` or its shorthand `-o`.
+func (f ImmutableClientFlags) IsSet(names ...string) (result bool) {
+	flag.Visit(func(given *flag.Flag) {
+		for _, name := range names {
+			if given.Name == name {
+				result = true
+			}
+		}
+	})
+	return
+}
+
+// UseTLS returns true if any TLS flag is given.
+func (f ImmutableClientFlags) UseTLS() bool {
+	return f.CertificateAuthority() != "" || f.ClientCertificate() != "" || f.InsecureSkipTLSVerify()
+}
+
 // Parse populates this collection of ImmutableClientFlags with
 // values from the command-line, and it validates command-line
 // arguments.
 func (f ImmutableClientFlags) Parse() {
 	flag.Parse()
 
-	if len(flag.Args()) < 1 {
+	if len(flag.Args()) < 1 && !f.Interactive() {
 		printUsageAndExitWithFailure()
 	}
 }
@@ -0,0 +1,203 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kubideh/kubesearch/search/api"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/kubectl/pkg/describe"
+)
+
+// liveObject is a live object of a result, and the resource by which
+// it was fetched.
+type liveObject struct {
+	mapping *meta.RESTMapping
+	object  *unstructured.Unstructured
+}
+
+// resourceArg returns the resource of the object in the form used by
+// kubectl, e.g., `deployments.apps`.
+func (o liveObject) resourceArg() string {
+	if o.mapping.Resource.Group == "" {
+		return o.mapping.Resource.Resource
+	}
+	return o.mapping.Resource.Resource + "." + o.mapping.Resource.Group
+}
+
+// topResults returns at most the given number of results, or every
+// result if n is 0.
+func topResults(results []api.ResultV2, n int) []api.ResultV2 {
+	if n > 0 && n < len(results) {
+		return results[:n]
+	}
+	return results
+}
+
+// objectFetcher fetches the live objects of results from the
+// Kubernetes API using the resource builder of kubectl. Its
+// RESTMapper caches discovery, so it's created once and reused,
+// e.g., by every preview of the interactive finder.
+type objectFetcher struct {
+	builder func() *resource.Builder
+}
+
+// createObjectFetcher returns an objectFetcher that uses the given
+// kubeconfig, e.g., the kubeconfig flags of kubectl.
+func createObjectFetcher(getter genericclioptions.RESTClientGetter) *objectFetcher {
+	return &objectFetcher{builder: func() *resource.Builder { return resource.NewBuilder(getter) }}
+}
+
+// fetch fetches the live object of each of the given results.
+// Objects that were deleted since they were indexed are skipped with
+// a warning written to the given writer.
+func (f *objectFetcher) fetch(ctx context.Context, results []api.ResultV2, warnings io.Writer) ([]liveObject, error) {
+	var objects []liveObject
+
+	for _, r := range results {
+		info, err := f.info(r, true)
+
+		if apierrors.IsNotFound(err) {
+			fmt.Fprintf(warnings, "warning: %s %q was not found; it may have been deleted since it was indexed\n", strings.ToLower(r.Kind), r.Name)
+			continue
+		}
+
+		if err != nil {
+			return objects, err
+		}
+
+		object, ok := info.Object.(*unstructured.Unstructured)
+
+		if !ok {
+			return objects, fmt.Errorf("unexpected object of type %T", info.Object)
+		}
+
+		objects = append(objects, liveObject{mapping: info.Mapping, object: object})
+	}
+
+	return objects, nil
+}
+
+// delete deletes the live object of the given result.
+func (f *objectFetcher) delete(ctx context.Context, result api.ResultV2) error {
+	info, err := f.info(result, false)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = resource.NewHelper(info.Client, info.Mapping).Delete(info.Namespace, info.Name)
+
+	return err
+}
+
+// info returns the resource builder's Info of the object of the given
+// result. Its object is fetched only if fetch is true.
+func (f *objectFetcher) info(result api.ResultV2, fetch bool) (*resource.Info, error) {
+	gvk := schema.FromAPIVersionAndKind(result.APIVersion, result.Kind)
+	infos, err := f.builder().
+		Unstructured().
+		NamespaceParam(result.Namespace).
+		ResourceNames(gvk.Kind+"."+gvk.Version+"."+gvk.Group, result.Name).
+		RequireObject(fetch).
+		Do().
+		Infos()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(infos) != 1 {
+		return nil, fmt.Errorf("expected one %s %q, got %d", strings.ToLower(result.Kind), result.Name, len(infos))
+	}
+
+	return infos[0], nil
+}
+
+// printObjects prints the given live objects in the given output
+// format, or as YAML if the output format is a table, the same as
+// `kubectl get -o yaml`. A single object isn't wrapped in a List.
+func printObjects(out io.Writer, output string, objects []liveObject) error {
+	if isTableOutput(output) {
+		output = OutputYAML
+	}
+
+	printer, err := createPrinter(output)
+
+	if err != nil {
+		return err
+	}
+
+	if len(objects) == 1 {
+		return printer.PrintObj(objects[0].object, out)
+	}
+
+	list := &unstructured.UnstructuredList{}
+	list.SetAPIVersion("v1")
+	list.SetKind("List")
+
+	for _, o := range objects {
+		list.Items = append(list.Items, *o.object)
+	}
+
+	return printer.PrintObj(list, out)
+}
+
+// describerFunc returns the describer of kubectl for objects of the
+// given resource.
+type describerFunc func(mapping *meta.RESTMapping) (describe.ResourceDescriber, error)
+
+// createDescriberFunc returns a describerFunc whose describers use the
+// given kubeconfig, e.g., the kubeconfig flags of kubectl.
+func createDescriberFunc(getter genericclioptions.RESTClientGetter) describerFunc {
+	return func(mapping *meta.RESTMapping) (describe.ResourceDescriber, error) {
+		return describe.DescriberFn(getter, mapping)
+	}
+}
+
+// describeObjects writes a description of each of the given live
+// objects, including their events, the same as `kubectl describe`.
+func describeObjects(out io.Writer, describer describerFunc, objects []liveObject) error {
+	for i, o := range objects {
+		d, err := describer(o.mapping)
+
+		if err != nil {
+			return err
+		}
+
+		description, err := d.Describe(o.object.GetNamespace(), o.object.GetName(), describe.DescriberSettings{ShowEvents: true, ChunkSize: 500})
+
+		if err != nil {
+			return fmt.Errorf("describe %s %s: %w", o.resourceArg(), o.object.GetName(), err)
+		}
+
+		if i > 0 {
+			fmt.Fprint(out, "\n\n")
+		}
+
+		fmt.Fprint(out, description)
+	}
+
+	return nil
+}
+
+// kubectlFlags returns the given arguments of kubectl followed by the
+// kubeconfig file and context given by flags.
+func kubectlFlags(flags ImmutableClientFlags, args []string) []string {
+	if flags.KubeConfig() != "" {
+		args = append(args, "--kubeconfig", flags.KubeConfig())
+	}
+
+	if flags.Context() != "" {
+		args = append(args, "--context", flags.Context())
+	}
+
+	return args
+}
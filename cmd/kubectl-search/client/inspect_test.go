@@ -0,0 +1,186 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/kubideh/kubesearch/search/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/resource"
+	restfake "k8s.io/client-go/rest/fake"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/kubectl/pkg/describe"
+)
+
+func TestObjectFetcher(t *testing.T) {
+	fetcher := createTestObjectFetcher(
+		&corev1.Pod{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"}, ObjectMeta: metav1.ObjectMeta{Name: "blargle", Namespace: "flargle"}},
+		&corev1.Node{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Node"}, ObjectMeta: metav1.ObjectMeta{Name: "bobble"}},
+	)
+
+	var warnings bytes.Buffer
+
+	objects, err := fetcher.fetch(context.Background(), []api.ResultV2{
+		{APIVersion: "v1", Kind: "Pod", Name: "blargle", Namespace: "flargle"},
+		{APIVersion: "v1", Kind: "Pod", Name: "foo", Namespace: "flargle"},
+		{APIVersion: "v1", Kind: "Node", Name: "bobble"},
+	}, &warnings)
+
+	require.NoError(t, err)
+	require.Len(t, objects, 2)
+	assert.Equal(t, "blargle", objects[0].object.GetName())
+	assert.Equal(t, "pods", objects[0].resourceArg())
+	assert.Equal(t, "bobble", objects[1].object.GetName())
+	assert.Equal(t, "warning: pod \"foo\" was not found; it may have been deleted since it was indexed\n", warnings.String())
+}
+
+func TestObjectFetcher_unknownKind(t *testing.T) {
+	fetcher := createTestObjectFetcher()
+
+	_, err := fetcher.fetch(context.Background(), []api.ResultV2{{APIVersion: "example.com/v1", Kind: "Flargle", Name: "blargle"}}, &bytes.Buffer{})
+
+	assert.Error(t, err)
+}
+
+func TestPrintObjects(t *testing.T) {
+	objects := fetchTestObjects(t)
+
+	var single bytes.Buffer
+	require.NoError(t, printObjects(&single, OutputTable, objects[:1]))
+	assert.Equal(t, `apiVersion: v1
+kind: Pod
+metadata:
+  creationTimestamp: null
+  name: blargle
+  namespace: flargle
+spec:
+  containers: null
+status: {}
+`, single.String(), "a single object is printed as YAML unless an output format is given")
+
+	var many bytes.Buffer
+	require.NoError(t, printObjects(&many, OutputName, objects))
+	assert.Equal(t, "pod/blargle\npod/foo\n", many.String())
+}
+
+func TestDescribeObjects(t *testing.T) {
+	objects := fetchTestObjects(t)
+	describer := func(mapping *meta.RESTMapping) (describe.ResourceDescriber, error) {
+		return testDescriber{resource: mapping.Resource.Resource}, nil
+	}
+
+	var out bytes.Buffer
+	require.NoError(t, describeObjects(&out, describer, objects))
+	assert.Equal(t, "pods flargle/blargle\n\npods flargle/foo", out.String())
+}
+
+// testDescriber describes objects by their resource, namespace and
+// name.
+type testDescriber struct {
+	resource string
+}
+
+func (d testDescriber) Describe(namespace, name string, _ describe.DescriberSettings) (string, error) {
+	return d.resource + " " + namespace + "/" + name, nil
+}
+
+func TestResourceArg(t *testing.T) {
+	o := liveObject{mapping: &meta.RESTMapping{Resource: schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}}}
+
+	assert.Equal(t, "deployments.apps", o.resourceArg())
+}
+
+func TestTopResults(t *testing.T) {
+	results := []api.ResultV2{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+
+	assert.Equal(t, results[:1], topResults(results, 1))
+	assert.Equal(t, results, topResults(results, 0))
+	assert.Equal(t, results, topResults(results, 5))
+}
+
+func fetchTestObjects(t *testing.T) []liveObject {
+	fetcher := createTestObjectFetcher(
+		&corev1.Pod{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"}, ObjectMeta: metav1.ObjectMeta{Name: "blargle", Namespace: "flargle"}},
+		&corev1.Pod{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"}, ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "flargle"}},
+	)
+
+	objects, err := fetcher.fetch(context.Background(), []api.ResultV2{
+		{APIVersion: "v1", Kind: "Pod", Name: "blargle", Namespace: "flargle"},
+		{APIVersion: "v1", Kind: "Pod", Name: "foo", Namespace: "flargle"},
+	}, &bytes.Buffer{})
+	require.NoError(t, err)
+
+	return objects
+}
+
+// createTestObjectFetcher returns an objectFetcher of the given
+// objects, and it maps only Pods and Nodes.
+func createTestObjectFetcher(objects ...runtime.Object) *objectFetcher {
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{corev1.SchemeGroupVersion})
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("Pod"), meta.RESTScopeNamespace)
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("Node"), meta.RESTScopeRoot)
+
+	byPath := make(map[string]runtime.Object)
+
+	for _, o := range objects {
+		object, _ := meta.Accessor(o)
+		mapping, _ := mapper.RESTMapping(o.GetObjectKind().GroupVersionKind().GroupKind())
+		path := "/" + mapping.Resource.Resource + "/" + object.GetName()
+
+		if object.GetNamespace() != "" {
+			path = "/namespaces/" + object.GetNamespace() + path
+		}
+
+		byPath[path] = o
+	}
+
+	client := &restfake.RESTClient{
+		NegotiatedSerializer: resource.UnstructuredPlusDefaultContentConfig().NegotiatedSerializer,
+		Client: restfake.CreateHTTPClient(func(request *http.Request) (*http.Response, error) {
+			object, ok := byPath[request.URL.Path]
+
+			if !ok {
+				return jsonResponse(http.StatusNotFound, &metav1.Status{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Status"}, Status: metav1.StatusFailure, Reason: metav1.StatusReasonNotFound, Code: http.StatusNotFound})
+			}
+
+			if request.Method == http.MethodDelete {
+				delete(byPath, request.URL.Path)
+				return jsonResponse(http.StatusOK, &metav1.Status{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Status"}, Status: metav1.StatusSuccess})
+			}
+
+			return jsonResponse(http.StatusOK, object)
+		}),
+	}
+
+	return &objectFetcher{builder: func() *resource.Builder {
+		return resource.NewFakeBuilder(
+			func(schema.GroupVersion) (resource.RESTClient, error) { return client, nil },
+			func() (meta.RESTMapper, error) { return mapper, nil },
+			func() (restmapper.CategoryExpander, error) { return restmapper.SimpleCategoryExpander{}, nil },
+		)
+	}}
+}
+
+func jsonResponse(code int, object runtime.Object) (*http.Response, error) {
+	body, err := json.Marshal(object)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Response{
+		StatusCode: code,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader(body)),
+	}, nil
+}
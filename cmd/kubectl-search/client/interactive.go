@@ -0,0 +1,524 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/kubideh/kubesearch/search/api"
+	"github.com/muesli/reflow/truncate"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	finderLimit    = 50                     // finderLimit is the number of results shown by the finder
+	finderDebounce = 200 * time.Millisecond // finderDebounce is how long to wait for typing to stop before searching
+)
+
+// Actions on the selected result that are run once the finder has
+// released the terminal. Get is run in-process, and the others are
+// run by kubectl.
+const (
+	actionGet      = "get"
+	actionDescribe = "describe"
+	actionLogs     = "logs"
+	actionEdit     = "edit"
+)
+
+const finderHelp = "↑/↓ select · enter get · ctrl+d describe · ctrl+l logs · ctrl+e edit · ctrl+x delete · esc quit"
+
+// finderSearchFunc returns the results of the given query.
+type finderSearchFunc func(query string) ([]api.ResultV2, error)
+
+// finderPreviewFunc returns the YAML of the live object of the given
+// result.
+type finderPreviewFunc func(result api.ResultV2) (string, error)
+
+// finderDeleteFunc deletes the live object of the given result, and
+// it returns a message that says so.
+type finderDeleteFunc func(result api.ResultV2) (string, error)
+
+// finder is an interactive fuzzy finder. It searches again as the
+// query is typed, and it shows the results ranked, with the matched
+// terms highlighted, followed by a preview of the selected object.
+type finder struct {
+	search  finderSearchFunc
+	preview finderPreviewFunc
+	delete  finderDeleteFunc
+
+	query      string
+	generation int // generation is incremented each time the query changes
+	results    []api.ResultV2
+	selected   int
+	previews   map[string]string // previews are keyed by the command of each result
+	err        error
+	status     string
+	confirm    *api.ResultV2 // confirm is the result whose delete is waiting to be confirmed, if any
+
+	action string // action is run on the selected result when the finder quits
+	width  int
+	height int
+}
+
+type queryChangedMsg struct{ generation int }
+
+type resultsMsg struct {
+	generation int
+	results    []api.ResultV2
+	err        error
+}
+
+type previewMsg struct {
+	key  string
+	text string
+}
+
+type deletedMsg struct {
+	output string
+	err    error
+}
+
+func createFinder(query string, search finderSearchFunc, preview finderPreviewFunc, deleteResult finderDeleteFunc) finder {
+	return finder{
+		search:   search,
+		preview:  preview,
+		delete:   deleteResult,
+		query:    query,
+		previews: make(map[string]string),
+	}
+}
+
+// Init searches for the current query, if any.
+func (m finder) Init() tea.Cmd {
+	if strings.TrimSpace(m.query) == "" {
+		return nil
+	}
+	return m.searchCmd()
+}
+
+// Update handles key presses and the results of searches, previews
+// and deletes.
+func (m finder) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+	case tea.KeyMsg:
+		return m.handleKey(msg)
+	case queryChangedMsg:
+		if msg.generation == m.generation {
+			return m, m.searchCmd()
+		}
+	case resultsMsg:
+		if msg.generation == m.generation {
+			m.results, m.err = msg.results, msg.err
+			m.selected = m.indexOf(m.confirm)
+			return m, m.previewCmd()
+		}
+	case previewMsg:
+		m.previews[msg.key] = msg.text
+	case deletedMsg:
+		m.status = strings.TrimSpace(msg.output)
+		if msg.err != nil {
+			m.status = strings.TrimSuffix(msg.err.Error()+": "+m.status, ": ")
+		}
+		m.generation++
+		return m, m.searchCmd()
+	}
+
+	return m, nil
+}
+
+func (m finder) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if target := m.confirm; target != nil {
+		m.confirm = nil
+
+		if msg.String() == "y" {
+			return m, m.deleteCmd(*target)
+		}
+
+		m.status = "delete canceled"
+		return m, nil
+	}
+
+	m.status = ""
+
+	switch msg.String() {
+	case "ctrl+c", "esc":
+		m.action = ""
+		return m, tea.Quit
+	case "up", "ctrl+p":
+		if m.selected > 0 {
+			m.selected--
+		}
+		return m, m.previewCmd()
+	case "down", "ctrl+n":
+		if m.selected < len(m.results)-1 {
+			m.selected++
+		}
+		return m, m.previewCmd()
+	case "enter":
+		return m.quitWithAction(actionGet)
+	case "ctrl+d":
+		return m.quitWithAction(actionDescribe)
+	case "ctrl+l":
+		return m.quitWithAction(actionLogs)
+	case "ctrl+e":
+		return m.quitWithAction(actionEdit)
+	case "ctrl+x":
+		if r, ok := m.selectedResult(); ok {
+			m.confirm = &r
+			m.status = fmt.Sprintf("delete %s %s? (y/n)", strings.ToLower(r.Kind), r.Name)
+		}
+		return m, nil
+	}
+
+	switch msg.Type {
+	case tea.KeyBackspace, tea.KeyCtrlH:
+		if runes := []rune(m.query); len(runes) > 0 {
+			return m.setQuery(string(runes[:len(runes)-1]))
+		}
+	case tea.KeySpace:
+		return m.setQuery(m.query + " ")
+	case tea.KeyRunes:
+		return m.setQuery(m.query + string(msg.Runes))
+	}
+
+	return m, nil
+}
+
+// setQuery changes the query, and it searches again unless the query
+// changes again before finderDebounce.
+func (m finder) setQuery(query string) (tea.Model, tea.Cmd) {
+	m.query = query
+	m.generation++
+
+	generation := m.generation
+
+	return m, tea.Tick(finderDebounce, func(time.Time) tea.Msg {
+		return queryChangedMsg{generation: generation}
+	})
+}
+
+func (m finder) quitWithAction(action string) (tea.Model, tea.Cmd) {
+	if _, ok := m.selectedResult(); !ok {
+		return m, nil
+	}
+
+	m.action = action
+
+	return m, tea.Quit
+}
+
+func (m finder) selectedResult() (api.ResultV2, bool) {
+	if m.selected < 0 || m.selected >= len(m.results) {
+		return api.ResultV2{}, false
+	}
+	return m.results[m.selected], true
+}
+
+// indexOf returns the index of the given result among the results,
+// so that a result waiting to be confirmed stays selected when the
+// results change. It returns 0 otherwise.
+func (m finder) indexOf(result *api.ResultV2) int {
+	if result == nil {
+		return 0
+	}
+
+	for i, r := range m.results {
+		if r.Command == result.Command {
+			return i
+		}
+	}
+
+	return 0
+}
+
+func (m finder) searchCmd() tea.Cmd {
+	query, generation, search := m.query, m.generation, m.search
+
+	if strings.TrimSpace(query) == "" {
+		return func() tea.Msg {
+			return resultsMsg{generation: generation}
+		}
+	}
+
+	return func() tea.Msg {
+		results, err := search(query)
+		return resultsMsg{generation: generation, results: results, err: err}
+	}
+}
+
+// previewCmd fetches the preview of the selected result, unless it
+// was fetched before.
+func (m finder) previewCmd() tea.Cmd {
+	r, ok := m.selectedResult()
+
+	if !ok {
+		return nil
+	}
+
+	if _, ok := m.previews[r.Command]; ok {
+		return nil
+	}
+
+	preview := m.preview
+
+	return func() tea.Msg {
+		text, err := preview(r)
+
+		if err != nil {
+			text = err.Error()
+		}
+
+		return previewMsg{key: r.Command, text: text}
+	}
+}
+
+// deleteCmd deletes the given result, which is the one whose delete
+// was confirmed rather than whichever is selected now.
+func (m finder) deleteCmd(r api.ResultV2) tea.Cmd {
+	deleteResult := m.delete
+
+	return func() tea.Msg {
+		output, err := deleteResult(r)
+		return deletedMsg{output: output, err: err}
+	}
+}
+
+// View renders the query, the results, the preview of the selected
+// result and a line of help or status.
+func (m finder) View() string {
+	var lines []string
+
+	lines = append(lines, "> "+m.query)
+
+	listHeight := m.height / 2
+
+	if listHeight < 3 {
+		listHeight = 3
+	}
+
+	switch {
+	case m.err != nil:
+		lines = append(lines, "error: "+m.err.Error())
+	case len(m.results) == 0 && strings.TrimSpace(m.query) != "":
+		lines = append(lines, "No results found.")
+	default:
+		lines = append(lines, m.renderResults(listHeight-1)...)
+	}
+
+	if r, ok := m.selectedResult(); ok {
+		lines = append(lines, strings.Repeat("─", m.lineWidth()))
+
+		previewHeight := m.height - len(lines) - 1
+
+		for i, line := range strings.Split(m.previews[r.Command], "\n") {
+			if i >= previewHeight {
+				break
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	for len(lines) < m.height-1 {
+		lines = append(lines, "")
+	}
+
+	if m.status != "" {
+		lines = append(lines, m.status)
+	} else {
+		lines = append(lines, finderHelp)
+	}
+
+	for i := range lines {
+		lines[i] = truncate.String(lines[i], uint(m.lineWidth()))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// renderResults returns at most the given number of lines of results,
+// and it scrolls so that the selected result is shown.
+func (m finder) renderResults(height int) []string {
+	var builder strings.Builder
+
+	writer := tabwriter.NewWriter(&builder, 0, 4, 2, ' ', 0)
+
+	for i, r := range m.results {
+		cursor := " "
+
+		if i == m.selected {
+			cursor = ">"
+		}
+
+		fmt.Fprintf(writer, "%s %s\t%s\t%s\t%d\t%s\n", cursor, r.Kind, r.Namespace, r.Name, r.Rank, renderHighlights(r.Highlights, terminalMarkers))
+	}
+
+	writer.Flush()
+
+	lines := strings.Split(strings.TrimSuffix(builder.String(), "\n"), "\n")
+	start := 0
+
+	if m.selected >= height {
+		start = m.selected - height + 1
+	}
+
+	end := start + height
+
+	if end > len(lines) {
+		end = len(lines)
+	}
+
+	return lines[start:end]
+}
+
+func (m finder) lineWidth() int {
+	if m.width <= 0 {
+		return 80
+	}
+	return m.width
+}
+
+// kubectlArgs returns the arguments of kubectl that run the given
+// verb on the object of the given result, e.g., `describe
+// deployment.apps/nginx --namespace default`.
+func kubectlArgs(verb string, result api.ResultV2, extra ...string) []string {
+	args := append([]string{verb, resourceName(result)}, extra...)
+
+	if result.Namespace != "" {
+		args = append(args, "--namespace", result.Namespace)
+	}
+
+	return args
+}
+
+// resourceName returns the object of the given result in the form
+// `<kind>[.<group>]/<name>` used by kubectl.
+func resourceName(result api.ResultV2) string {
+	gvk := schema.FromAPIVersionAndKind(result.APIVersion, result.Kind)
+	kind := strings.ToLower(gvk.Kind)
+
+	if gvk.Group != "" {
+		kind += "." + gvk.Group
+	}
+
+	return kind + "/" + result.Name
+}
+
+// runInteractive runs the finder until it quits without an action.
+// Actions are run with the terminal released by the finder, and then
+// the finder is started again where it left off. The live objects of
+// results are fetched using a single objectFetcher.
+func (c Client) runInteractive(search finderSearchFunc) error {
+	fetcher, err := c.createObjectFetcher()
+	objects := liveObjects{fetcher: fetcher, err: err}
+	model := createFinder(queryString(), search, objects.preview, objects.delete)
+
+	for {
+		result, err := tea.NewProgram(model, tea.WithAltScreen()).StartReturningModel()
+
+		if err != nil {
+			return err
+		}
+
+		model = result.(finder)
+
+		if model.action == "" {
+			return nil
+		}
+
+		r, _ := model.selectedResult()
+
+		if err := c.runAction(objects, model.action, r); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+
+		if model.action != actionEdit {
+			waitForEnter()
+		}
+
+		model.action = ""
+		model.previews = make(map[string]string)
+		model.generation++
+	}
+}
+
+func (c Client) runAction(objects liveObjects, action string, result api.ResultV2) error {
+	var args []string
+
+	switch action {
+	case actionGet:
+		return objects.print(os.Stdout, result)
+	case actionLogs:
+		args = kubectlArgs(actionLogs, result, "--all-containers")
+	default:
+		args = kubectlArgs(action, result)
+	}
+
+	command := exec.Command("kubectl", kubectlFlags(c.flags, args)...)
+	command.Stdin = os.Stdin
+	command.Stdout = os.Stdout
+	command.Stderr = os.Stderr
+
+	return command.Run()
+}
+
+// liveObjects previews, prints and deletes the live objects of the
+// results of the finder. Its objectFetcher is created once when the
+// finder starts, and err is why it couldn't be, e.g., because there's
+// no kubeconfig file.
+type liveObjects struct {
+	fetcher *objectFetcher
+	err     error
+}
+
+// preview returns the live object of the given result as YAML.
+func (l liveObjects) preview(result api.ResultV2) (string, error) {
+	var builder strings.Builder
+	err := l.print(&builder, result)
+	return builder.String(), err
+}
+
+// print prints the live object of the given result as YAML.
+func (l liveObjects) print(out io.Writer, result api.ResultV2) error {
+	if l.err != nil {
+		return l.err
+	}
+
+	objects, err := l.fetcher.fetch(context.Background(), []api.ResultV2{result}, io.Discard)
+
+	if err != nil {
+		return err
+	}
+
+	if len(objects) == 0 {
+		return fmt.Errorf("%s %q was not found", strings.ToLower(result.Kind), result.Name)
+	}
+
+	return printObjects(out, OutputYAML, objects)
+}
+
+// delete deletes the live object of the given result.
+func (l liveObjects) delete(result api.ResultV2) (string, error) {
+	if l.err != nil {
+		return "", l.err
+	}
+
+	if err := l.fetcher.delete(context.Background(), result); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s %q deleted", strings.ToLower(result.Kind), result.Name), nil
+}
+
+func waitForEnter() {
+	fmt.Fprint(os.Stderr, "\nPress Enter to return to kubectl search.")
+	bufio.NewReader(os.Stdin).ReadString('\n')
+}
@@ -0,0 +1,275 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/kubideh/kubesearch/search/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestFinder_search(t *testing.T) {
+	var queries []string
+	m := createTestFinder(func(query string) ([]api.ResultV2, error) {
+		queries = append(queries, query)
+		return createFinderResults("blargle", "foo"), nil
+	})
+
+	m, cmd := update(t, m, keyRunes("fl"))
+	m, _ = update(t, m, tea.KeyMsg{Type: tea.KeyBackspace})
+	assert.Equal(t, "f", m.query)
+	assert.Equal(t, 2, m.generation)
+	require.NotNil(t, cmd)
+
+	// A search that was debounced by a later change is dropped.
+	m, cmd = update(t, m, queryChangedMsg{generation: 1})
+	assert.Nil(t, cmd)
+
+	m, cmd = update(t, m, queryChangedMsg{generation: 2})
+	require.NotNil(t, cmd)
+
+	m, cmd = update(t, m, cmd())
+	assert.Equal(t, []string{"f"}, queries)
+	assert.Len(t, m.results, 2)
+	assert.Equal(t, 0, m.selected)
+
+	// The selected result is previewed.
+	m, _ = update(t, m, cmd())
+	assert.Equal(t, "preview of blargle", m.previews["kubectl get pod blargle"])
+}
+
+func TestFinder_staleResults(t *testing.T) {
+	m := createTestFinder(nil)
+	m.generation = 2
+
+	m, cmd := update(t, m, resultsMsg{generation: 1, results: createFinderResults("blargle")})
+
+	assert.Empty(t, m.results)
+	assert.Nil(t, cmd)
+}
+
+func TestFinder_select(t *testing.T) {
+	m := createTestFinder(nil)
+	m.results = createFinderResults("blargle", "foo")
+
+	m, _ = update(t, m, tea.KeyMsg{Type: tea.KeyUp})
+	assert.Equal(t, 0, m.selected)
+
+	m, cmd := update(t, m, tea.KeyMsg{Type: tea.KeyDown})
+	assert.Equal(t, 1, m.selected)
+	require.NotNil(t, cmd)
+	assert.Equal(t, previewMsg{key: "kubectl get pod foo", text: "preview of foo"}, cmd())
+
+	m, _ = update(t, m, tea.KeyMsg{Type: tea.KeyDown})
+	assert.Equal(t, 1, m.selected, "the selection stops at the last result")
+
+	// Previews aren't fetched again.
+	m.previews["kubectl get pod blargle"] = "preview of blargle"
+	_, cmd = update(t, m, tea.KeyMsg{Type: tea.KeyUp})
+	assert.Nil(t, cmd)
+}
+
+func TestFinder_actions(t *testing.T) {
+	cases := []struct {
+		key    tea.KeyMsg
+		action string
+	}{
+		{key: tea.KeyMsg{Type: tea.KeyEnter}, action: actionGet},
+		{key: tea.KeyMsg{Type: tea.KeyCtrlD}, action: actionDescribe},
+		{key: tea.KeyMsg{Type: tea.KeyCtrlL}, action: actionLogs},
+		{key: tea.KeyMsg{Type: tea.KeyCtrlE}, action: actionEdit},
+		{key: tea.KeyMsg{Type: tea.KeyEsc}, action: ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.key.String(), func(t *testing.T) {
+			m := createTestFinder(nil)
+			m.results = createFinderResults("blargle")
+
+			m, cmd := update(t, m, c.key)
+
+			assert.Equal(t, c.action, m.action)
+			require.NotNil(t, cmd)
+			assert.Equal(t, tea.Quit(), cmd())
+		})
+	}
+}
+
+func TestFinder_actionWithoutResults(t *testing.T) {
+	m, cmd := update(t, createTestFinder(nil), tea.KeyMsg{Type: tea.KeyEnter})
+
+	assert.Equal(t, "", m.action)
+	assert.Nil(t, cmd)
+}
+
+func TestFinder_delete(t *testing.T) {
+	var deleted []string
+	m := createTestFinder(nil)
+	m.delete = func(result api.ResultV2) (string, error) {
+		deleted = append(deleted, result.Name)
+		return "pod \"blargle\" deleted", nil
+	}
+	m.results = createFinderResults("blargle")
+
+	m, _ = update(t, m, tea.KeyMsg{Type: tea.KeyCtrlX})
+	assert.NotNil(t, m.confirm)
+	assert.Equal(t, "delete pod blargle? (y/n)", m.status)
+
+	m, cmd := update(t, m, keyRunes("y"))
+	require.NotNil(t, cmd)
+
+	m, cmd = update(t, m, cmd())
+	assert.Equal(t, []string{"blargle"}, deleted)
+	assert.Equal(t, "pod \"blargle\" deleted", m.status)
+	assert.NotNil(t, cmd, "the query is searched again")
+}
+
+func TestFinder_deleteAfterResultsChanged(t *testing.T) {
+	var deleted []string
+	m := createTestFinder(nil)
+	m.delete = func(result api.ResultV2) (string, error) {
+		deleted = append(deleted, result.Name)
+		return "", nil
+	}
+	m.results = createFinderResults("blargle", "foo")
+	m.selected = 1
+
+	m, _ = update(t, m, tea.KeyMsg{Type: tea.KeyCtrlX})
+	assert.Equal(t, "delete pod foo? (y/n)", m.status)
+
+	// The results are refreshed while the delete waits to be
+	// confirmed.
+	m, _ = update(t, m, resultsMsg{generation: m.generation, results: createFinderResults("bobble", "blargle", "foo")})
+	assert.Equal(t, 2, m.selected, "the result waiting to be confirmed stays selected")
+
+	m, cmd := update(t, m, keyRunes("y"))
+	require.NotNil(t, cmd)
+
+	_, _ = update(t, m, cmd())
+	assert.Equal(t, []string{"foo"}, deleted)
+}
+
+func TestFinder_deleteCanceled(t *testing.T) {
+	m := createTestFinder(nil)
+	m.results = createFinderResults("blargle")
+
+	m, _ = update(t, m, tea.KeyMsg{Type: tea.KeyCtrlX})
+	m, cmd := update(t, m, keyRunes("n"))
+
+	assert.Nil(t, m.confirm)
+	assert.Equal(t, "delete canceled", m.status)
+	assert.Nil(t, cmd)
+}
+
+func TestFinder_deleteFailed(t *testing.T) {
+	m, _ := update(t, createTestFinder(nil), deletedMsg{err: errors.New("forbidden")})
+
+	assert.Equal(t, "forbidden", m.status)
+}
+
+func TestFinder_renderResults(t *testing.T) {
+	m := createTestFinder(nil)
+	m.results = createFinderResults("a", "b", "c", "d")
+	m.results[2].Highlights = []api.Highlight{{Field: "metadata.name", Value: "c", Matches: []api.Match{{Start: 0, End: 1}}}}
+	m.selected = 2
+
+	assert.Equal(t, []string{
+		"  Pod  flargle  b  1  ",
+		"> Pod  flargle  c  1  metadata.name=\x1b[1mc\x1b[0m",
+	}, m.renderResults(2), "the list scrolls to the selected result")
+
+	m.selected = 0
+	assert.Equal(t, "> Pod  flargle  a  1  ", m.renderResults(2)[0])
+}
+
+func TestFinder_view(t *testing.T) {
+	m := createTestFinder(nil)
+	m.query = "flargle"
+	m.width, m.height = 40, 6
+
+	assert.Equal(t, "> flargle\nNo results found.\n\n\n\n"+truncateLine(finderHelp, 40), m.View())
+
+	m.err = errors.New("bobble")
+	assert.Contains(t, m.View(), "error: bobble")
+}
+
+func TestKubectlArgs(t *testing.T) {
+	result := api.ResultV2{APIVersion: "apps/v1", Kind: "Deployment", Name: "blargle", Namespace: "flargle"}
+
+	assert.Equal(t, []string{"logs", "deployment.apps/blargle", "--all-containers", "--namespace", "flargle"}, kubectlArgs(actionLogs, result, "--all-containers"))
+	assert.Equal(t, []string{"describe", "node/bobble"}, kubectlArgs(actionDescribe, api.ResultV2{APIVersion: "v1", Kind: "Node", Name: "bobble"}))
+}
+
+func TestLiveObjects(t *testing.T) {
+	fetcher := createTestObjectFetcher(&corev1.Pod{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"}, ObjectMeta: metav1.ObjectMeta{Name: "blargle", Namespace: "flargle"}})
+	objects := liveObjects{fetcher: fetcher}
+	result := api.ResultV2{APIVersion: "v1", Kind: "Pod", Name: "blargle", Namespace: "flargle"}
+
+	preview, err := objects.preview(result)
+	require.NoError(t, err)
+	assert.Contains(t, preview, "name: blargle")
+
+	output, err := objects.delete(result)
+	require.NoError(t, err)
+	assert.Equal(t, "pod \"blargle\" deleted", output)
+
+	fetched, err := fetcher.fetch(context.Background(), []api.ResultV2{result}, &bytes.Buffer{})
+	require.NoError(t, err)
+	assert.Empty(t, fetched)
+
+	_, err = objects.preview(result)
+	assert.EqualError(t, err, "pod \"blargle\" was not found")
+}
+
+func TestLiveObjects_withoutFetcher(t *testing.T) {
+	objects := liveObjects{err: errors.New("no kubeconfig")}
+
+	_, err := objects.preview(api.ResultV2{})
+	assert.EqualError(t, err, "no kubeconfig")
+
+	_, err = objects.delete(api.ResultV2{})
+	assert.EqualError(t, err, "no kubeconfig")
+}
+
+func createTestFinder(search finderSearchFunc) finder {
+	preview := func(result api.ResultV2) (string, error) {
+		return "preview of " + result.Name, nil
+	}
+
+	deleteResult := func(result api.ResultV2) (string, error) {
+		return "", errors.New("unexpected delete")
+	}
+
+	return createFinder("", search, preview, deleteResult)
+}
+
+func createFinderResults(names ...string) (results []api.ResultV2) {
+	for _, name := range names {
+		results = append(results, api.ResultV2{APIVersion: "v1", Kind: "Pod", Namespace: "flargle", Name: name, Rank: 1, Command: "kubectl get pod " + name})
+	}
+	return
+}
+
+func update(t *testing.T, m finder, msg tea.Msg) (finder, tea.Cmd) {
+	model, cmd := m.Update(msg)
+	require.IsType(t, finder{}, model)
+	return model.(finder), cmd
+}
+
+func keyRunes(s string) tea.KeyMsg {
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+}
+
+func truncateLine(line string, width int) string {
+	if runes := []rune(line); len(runes) > width {
+		return string(runes[:width])
+	}
+	return strings.TrimSpace(line)
+}
@@ -0,0 +1,42 @@
+package client
+
+import (
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// kubeConfigLoader loads the kubeconfig given by the kubeconfig
+// flags, or else the defaults of kubectl.
+func kubeConfigLoader(flags ImmutableClientFlags) clientcmd.ClientConfig {
+	return flags.KubeConfigFlags().ToRawKubeConfigLoader()
+}
+
+// createKubernetesConfig returns the configuration from the kubeconfig
+// given by flags, the same as kubectl.
+func createKubernetesConfig(flags ImmutableClientFlags) (*rest.Config, error) {
+	return flags.KubeConfigFlags().ToRESTConfig()
+}
+
+// currentContext returns the name of the kubeconfig context given by
+// flags, or else the current context of the kubeconfig file. It's
+// empty if there's no kubeconfig file.
+func currentContext(flags ImmutableClientFlags) string {
+	if flags.Context() != "" {
+		return flags.Context()
+	}
+
+	raw, err := kubeConfigLoader(flags).RawConfig()
+
+	if err != nil {
+		return ""
+	}
+
+	return raw.CurrentContext
+}
+
+// currentNamespace returns the namespace of the kubeconfig context
+// given by flags, and it's `default` if the context has none.
+func currentNamespace(flags ImmutableClientFlags) (string, error) {
+	namespace, _, err := kubeConfigLoader(flags).Namespace()
+	return namespace, err
+}
@@ -0,0 +1,141 @@
+package client
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+const kubeConfigWithContexts = `apiVersion: v1
+kind: Config
+clusters:
+- name: flargle
+  cluster:
+    server: https://flargle.example.com
+- name: blargle
+  cluster:
+    server: https://blargle.example.com
+users:
+- name: bobble
+  user:
+    token: bobble
+contexts:
+- name: flargle
+  context:
+    cluster: flargle
+    user: bobble
+    namespace: payment
+- name: blargle
+  context:
+    cluster: blargle
+    user: bobble
+current-context: flargle
+`
+
+func TestCreateKubernetesConfig(t *testing.T) {
+	config, err := createKubernetesConfig(createKubeConfigFlags(t, ""))
+	require.NoError(t, err)
+	assert.Equal(t, "https://flargle.example.com", config.Host)
+	assert.Equal(t, "bobble", config.BearerToken)
+
+	config, err = createKubernetesConfig(createKubeConfigFlags(t, "blargle"))
+	require.NoError(t, err)
+	assert.Equal(t, "https://blargle.example.com", config.Host)
+
+	_, err = createKubernetesConfig(createKubeConfigFlags(t, "foo"))
+	assert.Error(t, err)
+}
+
+func TestCurrentContext(t *testing.T) {
+	assert.Equal(t, "flargle", currentContext(createKubeConfigFlags(t, "")))
+	assert.Equal(t, "blargle", currentContext(createKubeConfigFlags(t, "blargle")))
+
+	missing := createKubeConfigFlags(t, "")
+	*missing.kubeConfigFlags.KubeConfig = filepath.Join(t.TempDir(), "config")
+	assert.Equal(t, "", currentContext(missing))
+}
+
+func TestCurrentNamespace(t *testing.T) {
+	namespace, err := currentNamespace(createKubeConfigFlags(t, ""))
+	require.NoError(t, err)
+	assert.Equal(t, "payment", namespace)
+
+	namespace, err = currentNamespace(createKubeConfigFlags(t, "blargle"))
+	require.NoError(t, err)
+	assert.Equal(t, "default", namespace, "a context without a namespace uses the default namespace")
+}
+
+func TestClientScope(t *testing.T) {
+	cases := []struct {
+		name          string
+		namespace     string
+		allNamespaces bool
+		local         bool
+		config        Config
+		scope         string
+	}{
+		{name: "kubeconfig context", scope: "payment"},
+		{name: "-namespace", namespace: "flargle", scope: "flargle"},
+		{name: "-all-namespaces", allNamespaces: true, scope: ""},
+		{name: "namespaceScope all", config: Config{NamespaceScope: NamespaceScopeAll}, scope: ""},
+		{name: "-namespace with namespaceScope all", namespace: "flargle", config: Config{NamespaceScope: NamespaceScopeAll}, scope: "flargle"},
+		{name: "-local", local: true, scope: ""},
+		{name: "-local with -namespace", local: true, namespace: "flargle", scope: "flargle"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			flags := createKubeConfigFlags(t, "")
+			flags.kubeConfigFlags.Namespace = &c.namespace
+			flags.allNamespaces = &c.allNamespaces
+			flags.local = &c.local
+
+			client := Create(flags)
+			client.config = c.config
+
+			assert.Equal(t, c.scope, client.scope())
+		})
+	}
+}
+
+func TestBindConfigFlags(t *testing.T) {
+	kubeConfigFlags := genericclioptions.NewConfigFlags(true)
+	kubeConfigFlags.APIServer = nil
+
+	flags := flag.NewFlagSet("kubectl-search", flag.ContinueOnError)
+	bindConfigFlags(flags, kubeConfigFlags)
+
+	require.NoError(t, flags.Parse([]string{"-n", "flargle", "--context", "blargle", "-user", "bobble", "-kubeconfig=/flargle/config"}))
+
+	assert.Equal(t, "flargle", *kubeConfigFlags.Namespace)
+	assert.Equal(t, "blargle", *kubeConfigFlags.Context)
+	assert.Equal(t, "bobble", *kubeConfigFlags.AuthInfoName)
+	assert.Equal(t, "/flargle/config", *kubeConfigFlags.KubeConfig)
+	assert.Nil(t, flags.Lookup("server"), "flags that are nil aren't bound")
+}
+
+// createKubeConfigFlags returns flags that load a kubeconfig with the
+// contexts flargle, which is current, and blargle, and that select
+// the given context.
+func createKubeConfigFlags(t *testing.T, kubeContext string) ImmutableClientFlags {
+	kubeConfig := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, os.WriteFile(kubeConfig, []byte(kubeConfigWithContexts), 0o600))
+
+	return ImmutableClientFlags{kubeConfigFlags: createConfigFlags(kubeConfig, kubeContext)}
+}
+
+// createConfigFlags returns kubeconfig flags that load the given
+// kubeconfig file and context, and that don't cache what's loaded.
+func createConfigFlags(kubeConfig, kubeContext string) *genericclioptions.ConfigFlags {
+	namespace := ""
+	flags := genericclioptions.NewConfigFlags(false)
+	flags.KubeConfig = &kubeConfig
+	flags.Context = &kubeContext
+	flags.Namespace = &namespace
+	return flags
+}
@@ -0,0 +1,33 @@
+package client
+
+import (
+	"os"
+
+	"github.com/kubideh/kubesearch/search/api"
+	objectfinder "github.com/kubideh/kubesearch/search/finder"
+	"github.com/kubideh/kubesearch/search/local"
+	"github.com/kubideh/kubesearch/search/searcher"
+	"github.com/kubideh/kubesearch/search/tokenizer"
+)
+
+// connectLocal indexes the objects of the given manifests, and it
+// returns a connection that searches them in this process, so that
+// they're searched without a server or cluster.
+func connectLocal(filenames []string) (connection, error) {
+	objects, err := local.Load(filenames, os.Stdin)
+
+	if err != nil {
+		return connection{}, err
+	}
+
+	aLocal := local.Create(objects)
+	aTokenizer := tokenizer.Tokenizer()
+	aSearcher := searcher.Create(aLocal.Index(), aTokenizer)
+	anExplainer := searcher.CreateExplainer(aLocal.Index(), aTokenizer)
+	sortKeys := searcher.CreateSortKeys(aLocal.Index())
+
+	return connection{
+		close: func() {},
+		local: api.CreateSearchV2Func(aSearcher, objectfinder.Create(aLocal.Store()), anExplainer, sortKeys),
+	}, nil
+}
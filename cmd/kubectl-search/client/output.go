@@ -0,0 +1,207 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kubideh/kubesearch/search/api"
+	"github.com/kubideh/kubesearch/search/federation"
+	"github.com/kubideh/kubesearch/search/searcher"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/printers"
+)
+
+// Output formats given by `-output`.
+const (
+	OutputTable = "table"
+	OutputWide  = "wide"
+	OutputJSON  = "json"
+	OutputYAML  = "yaml"
+	OutputName  = "name"
+
+	outputJSONPathPrefix      = "jsonpath="
+	outputCustomColumnsPrefix = "custom-columns="
+)
+
+// isTableOutput returns true if the given output format is meant to
+// be read by people rather than by scripts.
+func isTableOutput(output string) bool {
+	return output == OutputTable || output == OutputWide
+}
+
+// createPrinter returns a printer of the given output format. Table
+// formats print a metav1.Table, and every other format prints the
+// list returned by createList or createFederatedList.
+func createPrinter(output string) (printers.ResourcePrinter, error) {
+	switch {
+	case isTableOutput(output):
+		return printers.NewTablePrinter(printers.PrintOptions{Wide: output == OutputWide}), nil
+	case output == OutputJSON:
+		return &printers.JSONPrinter{}, nil
+	case output == OutputYAML:
+		return &printers.YAMLPrinter{}, nil
+	case output == OutputName:
+		return &printers.NamePrinter{}, nil
+	case strings.HasPrefix(output, outputJSONPathPrefix):
+		printer, err := printers.NewJSONPathPrinter(strings.TrimPrefix(output, outputJSONPathPrefix))
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid jsonpath template: %w", err)
+		}
+
+		printer.AllowMissingKeys(true)
+
+		return printer, nil
+	case strings.HasPrefix(output, outputCustomColumnsPrefix):
+		return createCustomColumnsPrinter(strings.TrimPrefix(output, outputCustomColumnsPrefix))
+	}
+
+	return nil, fmt.Errorf("unsupported output format %q; use one of table, wide, json, yaml, name, jsonpath=<template> or custom-columns=<spec>", output)
+}
+
+// object is a result as it's printed by structured output formats.
+// It has the shape of a Kubernetes object, so it's addressed by the
+// same paths as the output of `kubectl get`, e.g., `.metadata.name`,
+// and how it matched the query is under `search`.
+type object struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Search            match `json:"search"`
+}
+
+// match describes how a result matched the query.
+type match struct {
+	Rank          int                   `json:"rank,omitempty"`
+	Score         float64               `json:"score,omitempty"`
+	Cluster       string                `json:"cluster,omitempty"`
+	MatchedFields []string              `json:"matchedFields,omitempty"`
+	Highlights    []api.Highlight       `json:"highlights,omitempty"`
+	Command       string                `json:"command,omitempty"`
+	Explanation   *searcher.Explanation `json:"explanation,omitempty"`
+	Event         string                `json:"event,omitempty"` // Event is the type of change streamed by -watch
+}
+
+func createObject(result api.ResultV2) object {
+	return object{
+		TypeMeta: metav1.TypeMeta{APIVersion: result.APIVersion, Kind: result.Kind},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              result.Name,
+			Namespace:         result.Namespace,
+			UID:               result.UID,
+			Labels:            result.Labels,
+			CreationTimestamp: result.CreationTimestamp,
+			OwnerReferences:   result.OwnerReferences,
+		},
+		Search: match{
+			Rank:          result.Rank,
+			MatchedFields: result.MatchedFields,
+			Highlights:    result.Highlights,
+			Command:       result.Command,
+			Explanation:   result.Explanation,
+		},
+	}
+}
+
+func createFederatedObject(result federation.Result) object {
+	return object{
+		TypeMeta: metav1.TypeMeta{Kind: result.Kind},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      result.Name,
+			Namespace: result.Namespace,
+		},
+		Search: match{
+			Rank:        result.Rank,
+			Score:       result.Score,
+			Cluster:     result.Peer,
+			Explanation: result.Explanation,
+		},
+	}
+}
+
+// createList returns the given results as a List, i.e., in the same
+// shape as the output of `kubectl get -o json`.
+func createList(results []api.ResultV2) (*unstructured.UnstructuredList, error) {
+	objects := make([]object, 0, len(results))
+
+	for _, r := range results {
+		objects = append(objects, createObject(r))
+	}
+
+	return toList(objects)
+}
+
+// createFederatedList is the same as createList, but for the results
+// of a federated search.
+func createFederatedList(results []federation.Result) (*unstructured.UnstructuredList, error) {
+	objects := make([]object, 0, len(results))
+
+	for _, r := range results {
+		objects = append(objects, createFederatedObject(r))
+	}
+
+	return toList(objects)
+}
+
+func toList(objects []object) (*unstructured.UnstructuredList, error) {
+	result := &unstructured.UnstructuredList{}
+	result.SetAPIVersion("v1")
+	result.SetKind("List")
+
+	for i := range objects {
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&objects[i])
+
+		if err != nil {
+			return nil, err
+		}
+
+		result.Items = append(result.Items, unstructured.Unstructured{Object: content})
+	}
+
+	return result, nil
+}
+
+// printResults prints the given results in the given output format.
+func printResults(out io.Writer, output string, results []api.ResultV2, markers [2]string) error {
+	printer, err := createPrinter(output)
+
+	if err != nil {
+		return err
+	}
+
+	if isTableOutput(output) {
+		return printer.PrintObj(createTable(results, markers), out)
+	}
+
+	list, err := createList(results)
+
+	if err != nil {
+		return err
+	}
+
+	return printer.PrintObj(list, out)
+}
+
+// printFederatedResults prints the given results of a federated
+// search in the given output format.
+func printFederatedResults(out io.Writer, output string, results []federation.Result) error {
+	printer, err := createPrinter(output)
+
+	if err != nil {
+		return err
+	}
+
+	if isTableOutput(output) {
+		return printer.PrintObj(createFederatedTable(results), out)
+	}
+
+	list, err := createFederatedList(results)
+
+	if err != nil {
+		return err
+	}
+
+	return printer.PrintObj(list, out)
+}
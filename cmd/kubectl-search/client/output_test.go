@@ -0,0 +1,211 @@
+package client
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/kubideh/kubesearch/search/api"
+	"github.com/kubideh/kubesearch/search/federation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrintResults(t *testing.T) {
+	cases := []struct {
+		output   string
+		expected string
+	}{
+		{
+			output: OutputTable,
+			expected: "KIND         NAMESPACE   NAME      AGE         SCORE\n" +
+				"Pod          flargle     blargle   <unknown>   2\n" +
+				"Deployment   flargle     foo       <unknown>   1\n",
+		},
+		{
+			output: OutputWide,
+			expected: "KIND         NAMESPACE   NAME      AGE         SCORE   MATCHED                   COMMAND\n" +
+				"Pod          flargle     blargle   <unknown>   2       metadata.name=[blargle]   kubectl get pod blargle -n flargle\n" +
+				"Deployment   flargle     foo       <unknown>   1                                 \n",
+		},
+		{
+			output: OutputYAML,
+			expected: `apiVersion: v1
+items:
+- apiVersion: v1
+  kind: Pod
+  metadata:
+    creationTimestamp: null
+    labels:
+      app: blargle
+    name: blargle
+    namespace: flargle
+  search:
+    command: kubectl get pod blargle -n flargle
+    highlights:
+    - field: metadata.name
+      matches:
+      - end: 7
+        start: 0
+      snippet: ""
+      value: blargle
+    matchedFields:
+    - metadata.name
+    rank: 2
+- apiVersion: apps/v1
+  kind: Deployment
+  metadata:
+    creationTimestamp: null
+    name: foo
+    namespace: flargle
+  search:
+    rank: 1
+kind: List
+`,
+		},
+		{
+			output:   OutputName,
+			expected: "pod/blargle\ndeployment.apps/foo\n",
+		},
+		{
+			output:   "jsonpath={.items[*].metadata.name}",
+			expected: "blargle foo",
+		},
+		{
+			output:   "jsonpath={.items[*].metadata.flargle}",
+			expected: "",
+		},
+		{
+			output:   "custom-columns=NAME:.metadata.name,RANK:search.rank,APP:{.metadata.labels.app}",
+			expected: "NAME      RANK   APP\nblargle   2      blargle\nfoo       1      <none>\n",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.output, func(t *testing.T) {
+			var out bytes.Buffer
+
+			require.NoError(t, printResults(&out, c.output, createTestResults(), plainMarkers))
+			assert.Equal(t, c.expected, out.String())
+		})
+	}
+}
+
+func TestPrintResults_json(t *testing.T) {
+	var out bytes.Buffer
+
+	require.NoError(t, printResults(&out, OutputJSON, createTestResults(), plainMarkers))
+
+	assert.JSONEq(t, `{
+		"apiVersion": "v1",
+		"kind": "List",
+		"items": [
+			{
+				"apiVersion": "v1",
+				"kind": "Pod",
+				"metadata": {"creationTimestamp": null, "labels": {"app": "blargle"}, "name": "blargle", "namespace": "flargle"},
+				"search": {
+					"command": "kubectl get pod blargle -n flargle",
+					"highlights": [{"field": "metadata.name", "matches": [{"end": 7, "start": 0}], "snippet": "", "value": "blargle"}],
+					"matchedFields": ["metadata.name"],
+					"rank": 2
+				}
+			},
+			{
+				"apiVersion": "apps/v1",
+				"kind": "Deployment",
+				"metadata": {"creationTimestamp": null, "name": "foo", "namespace": "flargle"},
+				"search": {"rank": 1}
+			}
+		]
+	}`, out.String())
+}
+
+func TestPrintFederatedResults(t *testing.T) {
+	results := []federation.Result{
+		{Result: api.Result{Kind: "Pod", Name: "blargle", Namespace: "flargle"}, Peer: "cluster-a", Score: 0.5},
+	}
+
+	cases := []struct {
+		output   string
+		expected string
+	}{
+		{
+			output:   OutputTable,
+			expected: "KIND   NAMESPACE   NAME      CLUSTER     SCORE\nPod    flargle     blargle   cluster-a   0.50\n",
+		},
+		{
+			output:   OutputName,
+			expected: "pod/blargle\n",
+		},
+		{
+			output:   "custom-columns=NAME:.metadata.name,CLUSTER:.search.cluster",
+			expected: "NAME      CLUSTER\nblargle   cluster-a\n",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.output, func(t *testing.T) {
+			var out bytes.Buffer
+
+			require.NoError(t, printFederatedResults(&out, c.output, results))
+			assert.Equal(t, c.expected, out.String())
+		})
+	}
+}
+
+func TestCreatePrinter_invalid(t *testing.T) {
+	cases := []struct {
+		output string
+		err    string
+	}{
+		{output: "jsonpath={.items[", err: "invalid jsonpath template: unterminated array"},
+		{output: "custom-columns=NAME", err: `invalid custom column "NAME"; expected <header>:<path>`},
+		{output: "custom-columns=:.metadata.name", err: `invalid custom column ":.metadata.name"; expected <header>:<path>`},
+		{output: "custom-columns=NAME:.metadata.name,", err: `invalid custom column ""; expected <header>:<path>`},
+		{output: "custom-columns=NAME:.items[", err: `invalid path of custom column "NAME": unterminated array`},
+		{output: "flargle", err: `unsupported output format "flargle"; use one of table, wide, json, yaml, name, jsonpath=<template> or custom-columns=<spec>`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.output, func(t *testing.T) {
+			_, err := createPrinter(c.output)
+
+			assert.EqualError(t, err, c.err)
+		})
+	}
+}
+
+func TestCreateList(t *testing.T) {
+	list, err := createList(createTestResults())
+
+	require.NoError(t, err)
+	assert.Equal(t, "List", list.GetKind())
+	require.Len(t, list.Items, 2)
+	assert.Equal(t, "Pod", list.Items[0].GetKind())
+	assert.Equal(t, "blargle", list.Items[0].GetName())
+	assert.Equal(t, map[string]string{"app": "blargle"}, list.Items[0].GetLabels())
+	assert.Equal(t, "apps/v1", list.Items[1].GetAPIVersion())
+}
+
+func TestRelaxedPath(t *testing.T) {
+	assert.Equal(t, "{.metadata.name}", relaxedPath("metadata.name"))
+	assert.Equal(t, "{.metadata.name}", relaxedPath(".metadata.name"))
+	assert.Equal(t, "{.metadata.name}", relaxedPath("{.metadata.name}"))
+}
+
+func createTestResults() []api.ResultV2 {
+	return []api.ResultV2{
+		{
+			APIVersion:    "v1",
+			Kind:          "Pod",
+			Name:          "blargle",
+			Namespace:     "flargle",
+			Rank:          2,
+			Labels:        map[string]string{"app": "blargle"},
+			MatchedFields: []string{"metadata.name"},
+			Highlights:    []api.Highlight{{Field: "metadata.name", Value: "blargle", Matches: []api.Match{{Start: 0, End: 7}}}},
+			Command:       "kubectl get pod blargle -n flargle",
+		},
+		{APIVersion: "apps/v1", Kind: "Deployment", Name: "foo", Namespace: "flargle", Rank: 1},
+	}
+}
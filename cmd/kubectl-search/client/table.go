@@ -0,0 +1,132 @@
+package client
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kubideh/kubesearch/search/api"
+	"github.com/kubideh/kubesearch/search/federation"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+// Markers that surround each matched term when printing highlights.
+var (
+	terminalMarkers = [2]string{"\x1b[1m", "\x1b[0m"} // bold
+	plainMarkers    = [2]string{"[", "]"}
+)
+
+// createTable returns a table of the given results, with the same
+// columns as `kubectl get` followed by the rank of each result. The
+// wide table also has each matched field with the matched terms
+// highlighted, and a command that can be copied and executed.
+func createTable(results []api.ResultV2, markers [2]string) *metav1.Table {
+	table := &metav1.Table{
+		ColumnDefinitions: []metav1.TableColumnDefinition{
+			{Name: "Kind", Type: "string"},
+			{Name: "Namespace", Type: "string"},
+			{Name: "Name", Type: "string", Format: "name"},
+			{Name: "Age", Type: "string"},
+			{Name: "Score", Type: "integer"},
+			{Name: "Matched", Type: "string", Priority: 1},
+			{Name: "Command", Type: "string", Priority: 1},
+		},
+	}
+
+	for _, r := range results {
+		table.Rows = append(table.Rows, metav1.TableRow{
+			Cells: []interface{}{r.Kind, r.Namespace, r.Name, age(r.CreationTimestamp), r.Rank, renderHighlights(r.Highlights, markers), r.Command},
+		})
+	}
+
+	return table
+}
+
+// createWatchTable returns a table of the result of the given watch
+// event, with the type of the event in the first column, the same as
+// `kubectl get --watch --output-watch-events`.
+func createWatchTable(event api.WatchEventV2, markers [2]string) *metav1.Table {
+	table := createTable([]api.ResultV2{event.Object}, markers)
+	table.ColumnDefinitions = append([]metav1.TableColumnDefinition{{Name: "Event", Type: "string"}}, table.ColumnDefinitions...)
+	table.Rows[0].Cells = append([]interface{}{event.Type}, table.Rows[0].Cells...)
+
+	return table
+}
+
+// createFederatedTable returns a table of the given results of a
+// federated search, and each row has the cluster of the result and
+// its normalized score instead of an age and a rank.
+func createFederatedTable(results []federation.Result) *metav1.Table {
+	table := &metav1.Table{
+		ColumnDefinitions: []metav1.TableColumnDefinition{
+			{Name: "Kind", Type: "string"},
+			{Name: "Namespace", Type: "string"},
+			{Name: "Name", Type: "string", Format: "name"},
+			{Name: "Cluster", Type: "string"},
+			{Name: "Score", Type: "number"},
+		},
+	}
+
+	for _, r := range results {
+		table.Rows = append(table.Rows, metav1.TableRow{
+			Cells: []interface{}{r.Kind, r.Namespace, r.Name, r.Peer, fmt.Sprintf("%.2f", r.Score)},
+		})
+	}
+
+	return table
+}
+
+// age returns the time since the given timestamp in the same format
+// as `kubectl get`, e.g., 5m or 3d.
+func age(timestamp metav1.Time) string {
+	if timestamp.IsZero() {
+		return "<unknown>"
+	}
+	return duration.HumanDuration(time.Since(timestamp.Time))
+}
+
+func renderHighlights(highlights []api.Highlight, markers [2]string) string {
+	rendered := make([]string, 0, len(highlights))
+
+	for _, h := range highlights {
+		rendered = append(rendered, h.Field+"="+renderHighlight(h, markers))
+	}
+
+	return strings.Join(rendered, " ")
+}
+
+func renderHighlight(highlight api.Highlight, markers [2]string) string {
+	var builder strings.Builder
+
+	last := 0
+
+	for _, m := range highlight.Matches {
+		if m.Start < last || m.End > len(highlight.Value) {
+			return highlight.Value
+		}
+
+		builder.WriteString(highlight.Value[last:m.Start])
+		builder.WriteString(markers[0])
+		builder.WriteString(highlight.Value[m.Start:m.End])
+		builder.WriteString(markers[1])
+		last = m.End
+	}
+
+	builder.WriteString(highlight.Value[last:])
+
+	return builder.String()
+}
+
+// markersFor returns terminal markers if the given file is a
+// terminal, and plain markers otherwise.
+func markersFor(file *os.File) [2]string {
+	info, err := file.Stat()
+
+	if err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		return terminalMarkers
+	}
+
+	return plainMarkers
+}
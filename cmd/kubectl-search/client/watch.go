@@ -0,0 +1,52 @@
+package client
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/signal"
+
+	"github.com/kubideh/kubesearch/search/api"
+	"k8s.io/cli-runtime/pkg/printers"
+)
+
+// runWatch prints the current results of the query, and then it
+// prints each result that's added, modified or deleted as the server
+// indexes changes, until it's interrupted.
+func (c Client) runWatch(conn connection) error {
+	printer, err := createPrinter(c.output())
+
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	options := api.Options{Namespaces: c.namespaces()}
+
+	return api.WatchV2WithClient(ctx, conn.client, conn.endpoint, c.query(queryString()), options, func(event api.WatchEventV2) error {
+		return printWatchEvent(os.Stdout, printer, c.output(), event, markersFor(os.Stdout))
+	})
+}
+
+// printWatchEvent prints the given watch event using the given
+// printer, which prints table headers only once. Structured output
+// formats print the result of the event, and its type is under
+// `.search.event`.
+func printWatchEvent(out io.Writer, printer printers.ResourcePrinter, output string, event api.WatchEventV2, markers [2]string) error {
+	if isTableOutput(output) {
+		return printer.PrintObj(createWatchTable(event, markers), out)
+	}
+
+	o := createObject(event.Object)
+	o.Search.Event = event.Type
+
+	list, err := toList([]object{o})
+
+	if err != nil {
+		return err
+	}
+
+	return printer.PrintObj(&list.Items[0], out)
+}
@@ -1,62 +1,278 @@
 package app
 
 import (
+	"context"
 	"net/http"
+	"os"
 
+	"github.com/kubideh/kubesearch/search/aggregation"
+	"github.com/kubideh/kubesearch/search/auth"
+	"github.com/kubideh/kubesearch/search/federation"
 	"github.com/kubideh/kubesearch/search/finder"
+	"github.com/kubideh/kubesearch/search/local"
+	"github.com/kubideh/kubesearch/search/metrics"
+	"github.com/kubideh/kubesearch/search/savedsearch"
 	"github.com/kubideh/kubesearch/search/searcher"
 	"github.com/kubideh/kubesearch/search/tokenizer"
+	"github.com/kubideh/kubesearch/search/watch"
+	"github.com/kubideh/kubesearch/search/webhook"
 
 	"github.com/kubideh/kubesearch/search/api"
 	"github.com/kubideh/kubesearch/search/controller"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/klog/v2"
 )
 
-// ConfigureDefault configures and returns a new App.
+// ConfigureDefault configures and returns a new App. If any peers
+// are given on the command-line, then the App federates queries to
+// those peers instead of indexing a cluster, and if manifests are
+// given, e.g., by `kubesearch index -from-dir ./manifests`, then the
+// App indexes those instead.
 func ConfigureDefault() App {
 	flags := CreateImmutableServerFlags()
 	flags.Parse()
 
+	if err := flags.Validate(); err != nil {
+		klog.Fatalln(err)
+	}
+
+	if len(flags.Peers()) > 0 {
+		return CreateFederated(flags)
+	}
+
+	if flags.FromDir() != "" {
+		objects, err := local.Load([]string{flags.FromDir()}, os.Stdin)
+
+		if err != nil {
+			klog.Fatalln(err)
+		}
+
+		klog.Infof("Indexed %d objects from %s", len(objects), flags.FromDir())
+
+		return CreateLocal(flags, local.Create(objects))
+	}
+
 	client := createKubernetesClientset(flags)
 
 	aController := controller.Create(client)
 
-	return Create(flags, aController)
+	var dynamicClient dynamic.Interface
+
+	if flags.SavedSearches() {
+		dynamicClient = createDynamicClient(flags)
+	}
+
+	return Create(flags, client, dynamicClient, aController)
 }
 
-// Create returns server App objects.
-func Create(flags ImmutableServerFlags, aController *controller.Controller) App {
+// Create returns server App objects. The given client is used for
+// leader election and for authenticating and authorizing callers,
+// and the given dynamicClient is used for SavedSearches, if enabled.
+func Create(flags ImmutableServerFlags, client kubernetes.Interface, dynamicClient dynamic.Interface, aController *controller.Controller) App {
 	aTokenizer := tokenizer.Tokenizer()
 	aSearcher := searcher.Create(aController.Index(), aTokenizer)
 	aFinder := finder.Create(aController.Store())
-	aHandler := api.CreateSearchHandler(aSearcher, aFinder)
+	anExplainer := searcher.CreateExplainer(aController.Index(), aTokenizer)
+	sortKeys := searcher.CreateSortKeys(aController.Index())
+	aHandler := api.CreateSearchHandler(aSearcher, aFinder, anExplainer)
+	aHandlerV2 := api.CreateSearchHandlerV2(aSearcher, aFinder, anExplainer, sortKeys)
 	aMux := http.NewServeMux()
 
+	registry := watch.CreateRegistry()
+	aController.OnChange(registry.Notify)
+	aWatchHandler := api.CreateWatchHandlerV2(aSearcher, aFinder, registry)
+
+	var duties []LeaderFunc
+
+	if flags.StandingQueries() != "" || flags.SavedSearches() {
+		notifier := webhook.Create(loadStandingQueries(flags), aSearcher, aFinder, registry, aController.Ready)
+		duties = append(duties, notifier.Run)
+
+		if flags.SavedSearches() {
+			savedSearches := savedsearch.Create(dynamicClient, aSearcher, notifier, aController.Ready, flags.SavedSearchesAdminNamespace(), createSavedSearchAuthorizer(flags, client), flags.SavedSearchesNotificationHosts())
+			duties = append(duties, savedSearches.Run)
+		}
+	}
+
+	var admissionHandler http.HandlerFunc
+
+	if flags.SavedSearches() && flags.SavedSearchesAdmission() {
+		admissionHandler = savedsearch.CreateAdmissionHandler()
+	}
+
+	var authenticate auth.AuthenticateFunc
+	var aggregatedHandler http.HandlerFunc
+
+	// The aggregated API always delegates authentication and
+	// authorization to the Kubernetes API server.
+	if flags.Auth() || flags.AggregatedAPI() {
+		authenticate = createAuthenticator(flags, client)
+		filter := createFilter(flags, client)
+		aHandler = auth.Authenticate(api.CreateFilteredSearchHandler(aSearcher, aFinder, anExplainer, filter), authenticate)
+		aHandlerV2 = auth.Authenticate(api.CreateFilteredSearchHandlerV2(aSearcher, aFinder, anExplainer, sortKeys, filter), authenticate)
+		aWatchHandler = auth.Authenticate(api.CreateFilteredWatchHandlerV2(aSearcher, aFinder, registry, filter), authenticate)
+
+		if flags.AggregatedAPI() {
+			results := api.CreateResultsFunc(aSearcher, aFinder, filter)
+			aggregatedHandler = auth.Authenticate(aggregation.CreateSearchHandler(results), authenticate)
+		}
+	}
+
+	if err := metrics.RegisterIndex(aController.Index()); err != nil {
+		klog.Warningln("error registering index metrics: ", err)
+	}
+
 	return App{
-		controller: aController,
-		flags:      flags,
-		handler:    aHandler,
-		mux:        aMux,
+		admissionHandler:  admissionHandler,
+		aggregatedHandler: aggregatedHandler,
+		authenticate:      authenticate,
+		client:            client,
+		controller:        aController,
+		flags:             flags,
+		handler:           aHandler,
+		handlerV2:         aHandlerV2,
+		leaderDuties:      duties,
+		mux:               aMux,
+		registerHandler:   api.RegisterSearchHandler,
+		watchHandler:      aWatchHandler,
+	}
+}
+
+// loadStandingQueries returns the standing queries in the file given
+// by `-standing-queries`, if any.
+func loadStandingQueries(flags ImmutableServerFlags) []webhook.StandingQuery {
+	if flags.StandingQueries() == "" {
+		return nil
+	}
+
+	config, err := webhook.LoadConfig(flags.StandingQueries())
+
+	if err != nil {
+		klog.Fatalln(err)
+	}
+
+	return config.StandingQueries
+}
+
+// CreateFederated returns server App objects that fan each query
+// out to the peers given by flags.
+func CreateFederated(flags ImmutableServerFlags) App {
+	aSearch := federation.Create(flags.Peers(), flags.PeerTimeout())
+	aHandler := federation.CreateSearchHandler(aSearch)
+	aMux := http.NewServeMux()
+
+	return App{
+		flags:           flags,
+		handler:         aHandler,
+		mux:             aMux,
+		registerHandler: federation.RegisterSearchHandler,
+	}
+}
+
+// CreateLocal returns server App objects that search the objects of
+// the given Local instead of a cluster.
+func CreateLocal(flags ImmutableServerFlags, aLocal *local.Local) App {
+	aTokenizer := tokenizer.Tokenizer()
+	aSearcher := searcher.Create(aLocal.Index(), aTokenizer)
+	aFinder := finder.Create(aLocal.Store())
+	anExplainer := searcher.CreateExplainer(aLocal.Index(), aTokenizer)
+	sortKeys := searcher.CreateSortKeys(aLocal.Index())
+	aMux := http.NewServeMux()
+
+	return App{
+		flags:           flags,
+		handler:         api.CreateSearchHandler(aSearcher, aFinder, anExplainer),
+		handlerV2:       api.CreateSearchHandlerV2(aSearcher, aFinder, anExplainer, sortKeys),
+		mux:             aMux,
+		registerHandler: api.RegisterSearchHandler,
 	}
 }
 
 // App provides everything needed to run KubeSearch.
 type App struct {
-	controller *controller.Controller
-	flags      ImmutableServerFlags
-	handler    http.HandlerFunc
-	mux        *http.ServeMux
+	admissionHandler  http.HandlerFunc       // admissionHandler is nil unless serving the admission webhook of SavedSearches
+	aggregatedHandler http.HandlerFunc       // aggregatedHandler is nil unless serving the aggregated API
+	authenticate      auth.AuthenticateFunc  // authenticate is nil unless callers must be authenticated
+	client            kubernetes.Interface   // client is used for leader election, and it may be nil
+	controller        *controller.Controller // controller is nil when federating queries to peers or searching manifests
+	flags             ImmutableServerFlags
+	handler           http.HandlerFunc
+	handlerV2         http.HandlerFunc // handlerV2 is nil when federating queries to peers
+	leaderDuties      []LeaderFunc     // leaderDuties run only on the elected leader, or always without -leader-elect
+	mux               *http.ServeMux
+	registerHandler   func(mux *http.ServeMux, handler http.HandlerFunc)
+	watchHandler      http.HandlerFunc // watchHandler is nil when federating queries to peers or searching manifests
 }
 
-// Run starts the given Controller and registers the Search API
-// handler.
+// Run registers the Search API handler, starts listening, and then
+// starts the given Controller, if any. Every replica serves search
+// requests, and it reports being ready once its own informer caches
+// are synced. If leader election is enabled, then the singleton
+// duties run only on the elected leader.
 func (a App) Run() error {
-	// create the Controller to be used by the search API handler
-	cancel := a.controller.Start()
+	a.registerHandler(a.mux, a.handler)
+
+	if a.handlerV2 != nil {
+		api.RegisterSearchHandlerV2(a.mux, a.handlerV2)
+	}
+
+	if a.watchHandler != nil {
+		api.RegisterWatchHandlerV2(a.mux, a.watchHandler)
+	}
+
+	registerHealthHandlers(a.mux, a.controller)
+	metrics.RegisterHandler(a.mux)
+
+	if a.controller != nil {
+		api.RegisterStatusHandler(a.mux, a.authenticated(api.CreateStatusHandler(a.controller.Status)))
+	}
+
+	if a.aggregatedHandler != nil {
+		aggregation.RegisterHandlers(a.mux, a.aggregatedHandler)
+	}
+
+	if a.admissionHandler != nil {
+		savedsearch.RegisterAdmissionHandler(a.mux, a.admissionHandler)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	api.RegisterSearchHandler(a.mux, a.handler)
+	tlsConfig, err := createTLSConfig(ctx, a.flags)
+
+	if err != nil {
+		return err
+	}
+
+	serverErrors := make(chan error, 1)
+
+	go func() {
+		klog.Infoln("Listening on " + a.flags.BindAddress())
+		serverErrors <- listenAndServe(a.flags.BindAddress(), a.mux, tlsConfig)
+	}()
 
-	klog.Infoln("Listening on " + a.flags.BindAddress())
-	return http.ListenAndServe(a.flags.BindAddress(), a.mux)
+	// create the Controller to be used by the search API handler
+	if a.controller != nil {
+		cancel := a.controller.Start()
+		defer cancel()
+	}
+
+	if a.flags.LeaderElect() && a.client != nil {
+		go runLeaderElection(ctx, a.flags, a.client, a.leaderDuties)
+	} else {
+		// Without leader election, this replica is the only leader.
+		go runDuties(ctx, a.leaderDuties)
+	}
+
+	return <-serverErrors
+}
+
+// authenticated wraps the given handler so that callers must be
+// authenticated, unless authentication is disabled.
+func (a App) authenticated(handler http.HandlerFunc) http.HandlerFunc {
+	if a.authenticate == nil {
+		return handler
+	}
+	return auth.Authenticate(handler, a.authenticate)
 }
@@ -0,0 +1,83 @@
+package app
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+
+	"github.com/kubideh/kubesearch/search/auth"
+	"github.com/kubideh/kubesearch/search/controller"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+// createAuthenticator returns an authenticator that accepts either
+// verified client certificates or bearer tokens checked with a
+// TokenReview. When serving the aggregated API, the users given by
+// request headers are trusted if the request comes from the
+// Kubernetes API aggregator.
+func createAuthenticator(flags ImmutableServerFlags, client kubernetes.Interface) auth.AuthenticateFunc {
+	authenticators := []auth.AuthenticateFunc{
+		auth.CreateClientCertAuthenticator(),
+		auth.CreateTokenAuthenticator(client, flags.AuthCacheTTL()),
+	}
+
+	if flags.AggregatedAPI() {
+		config, err := auth.LoadRequestHeaderConfig(context.Background(), client)
+
+		if err != nil {
+			klog.Errorln("error loading the request header configuration; requests from the aggregator will not be authenticated: ", err)
+		} else {
+			if err := checkRequestHeaderClientCAs(flags.ClientCAFile(), config); err != nil {
+				klog.Fatalln(err)
+			}
+
+			authenticators = append([]auth.AuthenticateFunc{auth.CreateRequestHeaderAuthenticator(config)}, authenticators...)
+		}
+	}
+
+	return auth.Union(authenticators...)
+}
+
+// checkRequestHeaderClientCAs returns an error unless the CAs of the
+// given client CA file, if any, also verify the client certificate
+// of the Kubernetes API aggregator. Given a client CA file, client
+// certificates are verified against it during the TLS handshake, so
+// the aggregator couldn't connect otherwise.
+func checkRequestHeaderClientCAs(clientCAFile string, config auth.RequestHeaderConfig) error {
+	clientCAs, err := loadClientCAs(clientCAFile)
+
+	if err != nil || clientCAs == nil {
+		return err
+	}
+
+	for _, certificate := range config.ClientCACerts {
+		_, err := certificate.Verify(x509.VerifyOptions{
+			Roots:     clientCAs,
+			KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		})
+
+		if err != nil {
+			return fmt.Errorf("the request header client CA %q isn't in -client-ca-file %s, so the Kubernetes API aggregator can't connect; add it to -client-ca-file: %w", certificate.Subject.CommonName, clientCAFile, err)
+		}
+	}
+
+	return nil
+}
+
+// createFilter returns a filter that keeps only the results that
+// the caller could `get`, as decided by SubjectAccessReviews.
+func createFilter(flags ImmutableServerFlags, client kubernetes.Interface) auth.FilterFunc {
+	authorize := auth.CreateSubjectAccessReviewAuthorizer(client, flags.AuthCacheTTL())
+	return auth.CreateFilter(authorize, controller.IndexedResources())
+}
+
+// createSavedSearchAuthorizer returns the authorizer of the creators
+// of SavedSearches, as decided by SubjectAccessReviews. It's nil
+// unless the admission webhook records those creators.
+func createSavedSearchAuthorizer(flags ImmutableServerFlags, client kubernetes.Interface) auth.AuthorizeFunc {
+	if !flags.SavedSearchesAdmission() {
+		return nil
+	}
+	return auth.CreateSubjectAccessReviewAuthorizer(client, flags.AuthCacheTTL())
+}
@@ -1,17 +1,50 @@
 package app
 
 import (
+	"errors"
 	"flag"
+	"fmt"
+	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/kubideh/kubesearch/search/aggregation"
 	"k8s.io/client-go/util/homedir"
 )
 
 // CreateImmutableServerFlags returns the ImmutableServerFlags for
-// App. A list of the flags and their defaults are now given.
+// App. A list of the flags and their defaults are now given. The
+// flags may follow the subcommand `index`, which requires -from-dir,
+// e.g., `kubesearch index -from-dir ./manifests`.
 //
 // -bind-address (default: :8080)
 // -kubeconfig (default $HOME/.kube/config if $HOME is set; empty string otherwise)
+// -context (default: empty string)
+// -master (default: empty string)
+// -kube-api-qps (default: 5)
+// -kube-api-burst (default: 10)
+// -leader-elect (default: false)
+// -leader-elect-namespace (default: kubesearch)
+// -leader-elect-lease-name (default: kubesearch)
+// -leader-elect-lease-duration (default: 15s)
+// -leader-elect-renew-deadline (default: 10s)
+// -leader-elect-retry-period (default: 2s)
+// -auth (default: false)
+// -auth-cache-ttl (default: 10s)
+// -aggregated-api (default: false)
+// -tls-cert-file (default: empty string)
+// -tls-private-key-file (default: empty string)
+// -client-ca-file (default: empty string)
+// -peers (default: empty string)
+// -peer-timeout (default: 5s)
+// -standing-queries (default: empty string)
+// -saved-searches (default: false)
+// -saved-searches-admin-namespace (default: kubesearch)
+// -saved-searches-admission (default: false)
+// -saved-searches-notification-hosts (default: empty string)
+// -from-dir (default: empty string)
 func CreateImmutableServerFlags() ImmutableServerFlags {
 	return CreateImmutableServerFlagsWithBindAddress(":8080")
 }
@@ -21,27 +54,79 @@ func CreateImmutableServerFlags() ImmutableServerFlags {
 // as a default value for the flag `-bind-address`.
 func CreateImmutableServerFlagsWithBindAddress(bindAddress string) ImmutableServerFlags {
 	return ImmutableServerFlags{
-		bindAddress: flag.String("bind-address", bindAddress, "IP address and port on which to listen"),
-		kubeConfig:  kubeConfigFlag(),
+		bindAddress:                    flag.String("bind-address", bindAddress, "IP address and port on which to listen"),
+		kubeConfig:                     kubeConfigFlag(),
+		context:                        flag.String("context", "", "(optional) the kubeconfig context to use; the current context is used otherwise"),
+		master:                         flag.String("master", "", "(optional) the address of the Kubernetes API server; overrides any value in the kubeconfig file"),
+		kubeAPIQPS:                     flag.Float64("kube-api-qps", 5, "QPS to use while talking with the Kubernetes API server"),
+		kubeAPIBurst:                   flag.Int("kube-api-burst", 10, "burst to use while talking with the Kubernetes API server"),
+		leaderElect:                    flag.Bool("leader-elect", false, "elect a leader among replicas using a Lease; only the leader runs singleton duties"),
+		leaderElectNamespace:           flag.String("leader-elect-namespace", "kubesearch", "the namespace of the Lease used for leader election"),
+		leaderElectLeaseName:           flag.String("leader-elect-lease-name", "kubesearch", "the name of the Lease used for leader election"),
+		leaderElectLeaseDuration:       flag.Duration("leader-elect-lease-duration", 15*time.Second, "how long non-leaders wait before trying to acquire the Lease"),
+		leaderElectRenewDeadline:       flag.Duration("leader-elect-renew-deadline", 10*time.Second, "how long the leader retries renewing the Lease before giving it up"),
+		leaderElectRetryPeriod:         flag.Duration("leader-elect-retry-period", 2*time.Second, "how long to wait between attempts to acquire or renew the Lease"),
+		auth:                           flag.Bool("auth", false, "authenticate callers using TokenReviews or client certificates, and return only the objects each caller could get"),
+		authCacheTTL:                   flag.Duration("auth-cache-ttl", 10*time.Second, "how long to cache TokenReview and SubjectAccessReview responses"),
+		aggregatedAPI:                  flag.Bool("aggregated-api", false, "serve the search API as the aggregated Kubernetes API "+aggregation.SchemeGroupVersion.String()+"; implies -auth"),
+		tlsCertFile:                    flag.String("tls-cert-file", "", "(optional) path to the x509 certificate used to serve HTTPS, which is reloaded when changed; a self-signed certificate is generated for -aggregated-api otherwise"),
+		tlsPrivateKeyFile:              flag.String("tls-private-key-file", "", "(optional) path to the x509 private key matching -tls-cert-file"),
+		clientCAFile:                   flag.String("client-ca-file", "", "(optional) path to a bundle of CAs used to verify client certificates; verified clients are authenticated by the common name and organizations of their certificate; with -aggregated-api, it must also include the request header client CA of the Kubernetes API aggregator"),
+		peers:                          flag.String("peers", "", "(optional) comma-separated list of peer KubeSearch endpoints; if set, queries are federated to the peers instead of indexing a cluster"),
+		peerTimeout:                    flag.Duration("peer-timeout", 5*time.Second, "how long to wait for each peer to respond to a federated query"),
+		standingQueries:                flag.String("standing-queries", "", "(optional) path to a file of standing queries whose webhooks are called when objects start matching them; with -leader-elect, only the leader calls webhooks"),
+		savedSearches:                  flag.Bool("saved-searches", false, "run SavedSearches on their schedules and report their results in their status; requires the SavedSearch CustomResourceDefinition"),
+		savedSearchesAdminNamespace:    flag.String("saved-searches-admin-namespace", "kubesearch", "the namespace whose SavedSearches may search any namespace; all others search only their own namespace"),
+		savedSearchesAdmission:         flag.Bool("saved-searches-admission", false, "serve the admission webhook that records who created or last changed each SavedSearch; SavedSearches outside the admin namespace report only the hits their creator could get, and none without it; requires HTTPS and the MutatingWebhookConfiguration in deploy/savedsearch.yaml"),
+		savedSearchesNotificationHosts: flag.String("saved-searches-notification-hosts", "", "(optional) comma-separated list of hosts to which SavedSearches outside the admin namespace may send notifications; they may send none otherwise"),
+		index:                          new(bool),
+		fromDir:                        flag.String("from-dir", "", "(optional) path to a directory or file of YAML or JSON manifests, e.g., the output of helm template; if set, the objects of the manifests are indexed and searched instead of a cluster"),
 	}
 }
 
-func kubeConfigFlag() (kubeConfig *string) {
+// indexCommand is the subcommand that searches the manifests given
+// by -from-dir instead of a cluster.
+const indexCommand = "index"
+
+// kubeConfigFlag is empty unless `-kubeconfig` is given, so that a
+// missing default kubeconfig file can be told apart from a missing
+// kubeconfig file that was asked for.
+func kubeConfigFlag() *string {
 	// It's convention to use `kubeconfig` as the flag name.
-	if home := homedir.HomeDir(); home != "" {
-		kubeConfig = flag.String("kubeconfig", filepath.Join(home, ".kube", "config"), "(optional) absolute path to the kubeconfig file")
-	} else {
-		kubeConfig = flag.String("kubeconfig", "", "absolute path to the kubeconfig file")
-	}
-	return
+	return flag.String("kubeconfig", "", "(optional) absolute path to the kubeconfig file (default $HOME/.kube/config if $HOME is set)")
 }
 
 // ImmutableServerFlags is a collection of flags used to configure
 // the App. Each flag will be populated with values from the
 // command-line after calling Parse().
 type ImmutableServerFlags struct {
-	bindAddress *string // bindAddress is an address that can be used by `http.ListenAndServe`
-	kubeConfig  *string // kubeConfig is a path string that can be used to create Kubernetes clients
+	bindAddress                    *string        // bindAddress is an address that can be used by `http.ListenAndServe`
+	kubeConfig                     *string        // kubeConfig is a path string that can be used to create Kubernetes clients
+	context                        *string        // context is the name of the kubeconfig context to use
+	master                         *string        // master is the address of the Kubernetes API server
+	kubeAPIQPS                     *float64       // kubeAPIQPS is the QPS used by Kubernetes clients
+	kubeAPIBurst                   *int           // kubeAPIBurst is the burst used by Kubernetes clients
+	leaderElect                    *bool          // leaderElect is whether to elect a leader among replicas
+	leaderElectNamespace           *string        // leaderElectNamespace is the namespace of the Lease
+	leaderElectLeaseName           *string        // leaderElectLeaseName is the name of the Lease
+	leaderElectLeaseDuration       *time.Duration // leaderElectLeaseDuration is how long non-leaders wait before acquiring the Lease
+	leaderElectRenewDeadline       *time.Duration // leaderElectRenewDeadline is how long the leader retries renewing the Lease
+	leaderElectRetryPeriod         *time.Duration // leaderElectRetryPeriod is how long to wait between attempts
+	auth                           *bool          // auth is whether to authenticate and authorize callers
+	authCacheTTL                   *time.Duration // authCacheTTL is how long to cache reviews
+	aggregatedAPI                  *bool          // aggregatedAPI is whether to serve the aggregated Kubernetes API
+	tlsCertFile                    *string        // tlsCertFile is the path to the serving certificate
+	tlsPrivateKeyFile              *string        // tlsPrivateKeyFile is the path to the serving private key
+	clientCAFile                   *string        // clientCAFile is the path to the client CA bundle
+	peers                          *string        // peers is a comma-separated list of peer KubeSearch endpoints
+	peerTimeout                    *time.Duration // peerTimeout is how long to wait for each peer
+	standingQueries                *string        // standingQueries is the path to a file of standing queries
+	savedSearches                  *bool          // savedSearches is whether to run SavedSearches
+	savedSearchesAdminNamespace    *string        // savedSearchesAdminNamespace is the namespace whose SavedSearches may search any namespace
+	savedSearchesAdmission         *bool          // savedSearchesAdmission is whether to serve the admission webhook of SavedSearches
+	savedSearchesNotificationHosts *string        // savedSearchesNotificationHosts is a comma-separated list of hosts
+	index                          *bool          // index is whether the subcommand `index` was given
+	fromDir                        *string        // fromDir is the path to manifests that are indexed instead of a cluster
 }
 
 // BindAddress returns an address that can be used by
@@ -55,11 +140,271 @@ func (f ImmutableServerFlags) BindAddress() string {
 // Kubernetes clients, and it's populated by a value from the
 // command-line.
 func (f ImmutableServerFlags) KubeConfig() string {
-	return *f.kubeConfig
+	if *f.kubeConfig != "" {
+		return *f.kubeConfig
+	}
+
+	if home := homedir.HomeDir(); home != "" {
+		return filepath.Join(home, ".kube", "config")
+	}
+
+	return ""
+}
+
+// KubeConfigGiven returns true if `-kubeconfig` was given on the
+// command-line instead of defaulting to $HOME/.kube/config.
+func (f ImmutableServerFlags) KubeConfigGiven() bool {
+	return *f.kubeConfig != ""
+}
+
+// Context returns the name of the kubeconfig context used to create
+// Kubernetes clients, and it's populated by a value from the
+// command-line.
+func (f ImmutableServerFlags) Context() string {
+	return *f.context
+}
+
+// Master returns the address of the Kubernetes API server used to
+// create Kubernetes clients, and it's populated by a value from the
+// command-line.
+func (f ImmutableServerFlags) Master() string {
+	return *f.master
+}
+
+// KubeAPIQPS returns the QPS used by Kubernetes clients, and it's
+// populated by a value from the command-line.
+func (f ImmutableServerFlags) KubeAPIQPS() float32 {
+	return float32(*f.kubeAPIQPS)
+}
+
+// KubeAPIBurst returns the burst used by Kubernetes clients, and
+// it's populated by a value from the command-line.
+func (f ImmutableServerFlags) KubeAPIBurst() int {
+	return *f.kubeAPIBurst
+}
+
+// LeaderElect returns whether to elect a leader among replicas, and
+// it's populated by a value from the command-line.
+func (f ImmutableServerFlags) LeaderElect() bool {
+	return *f.leaderElect
+}
+
+// LeaderElectNamespace returns the namespace of the Lease used for
+// leader election, and it's populated by a value from the
+// command-line.
+func (f ImmutableServerFlags) LeaderElectNamespace() string {
+	return *f.leaderElectNamespace
+}
+
+// LeaderElectLeaseName returns the name of the Lease used for leader
+// election, and it's populated by a value from the command-line.
+func (f ImmutableServerFlags) LeaderElectLeaseName() string {
+	return *f.leaderElectLeaseName
+}
+
+// LeaderElectLeaseDuration returns how long non-leaders wait before
+// trying to acquire the Lease, and it's populated by a value from
+// the command-line.
+func (f ImmutableServerFlags) LeaderElectLeaseDuration() time.Duration {
+	return *f.leaderElectLeaseDuration
+}
+
+// LeaderElectRenewDeadline returns how long the leader retries
+// renewing the Lease, and it's populated by a value from the
+// command-line.
+func (f ImmutableServerFlags) LeaderElectRenewDeadline() time.Duration {
+	return *f.leaderElectRenewDeadline
+}
+
+// LeaderElectRetryPeriod returns how long to wait between attempts
+// to acquire or renew the Lease, and it's populated by a value from
+// the command-line.
+func (f ImmutableServerFlags) LeaderElectRetryPeriod() time.Duration {
+	return *f.leaderElectRetryPeriod
+}
+
+// Auth returns whether callers are authenticated and whether
+// results are filtered by what each caller is authorized to see,
+// and it's populated by a value from the command-line.
+func (f ImmutableServerFlags) Auth() bool {
+	return *f.auth
+}
+
+// AuthCacheTTL returns how long to cache TokenReview and
+// SubjectAccessReview responses, and it's populated by a value from
+// the command-line.
+func (f ImmutableServerFlags) AuthCacheTTL() time.Duration {
+	return *f.authCacheTTL
+}
+
+// AggregatedAPI returns whether to serve the search API as an
+// aggregated Kubernetes API, and it's populated by a value from the
+// command-line.
+func (f ImmutableServerFlags) AggregatedAPI() bool {
+	return *f.aggregatedAPI
+}
+
+// TLSCertFile returns the path to the x509 certificate used to serve
+// HTTPS, and it's populated by a value from the command-line.
+func (f ImmutableServerFlags) TLSCertFile() string {
+	return *f.tlsCertFile
+}
+
+// TLSPrivateKeyFile returns the path to the x509 private key used to
+// serve HTTPS, and it's populated by a value from the command-line.
+func (f ImmutableServerFlags) TLSPrivateKeyFile() string {
+	return *f.tlsPrivateKeyFile
+}
+
+// ClientCAFile returns the path to the bundle of CAs used to verify
+// client certificates, and it's populated by a value from the
+// command-line.
+func (f ImmutableServerFlags) ClientCAFile() string {
+	return *f.clientCAFile
+}
+
+// Peers returns the list of peer KubeSearch endpoints to which
+// queries are federated, and it's populated by a value from the
+// command-line.
+func (f ImmutableServerFlags) Peers() (result []string) {
+	for _, p := range strings.Split(*f.peers, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			result = append(result, p)
+		}
+	}
+	return
+}
+
+// PeerTimeout returns how long to wait for each peer to respond to
+// a federated query, and it's populated by a value from the
+// command-line.
+func (f ImmutableServerFlags) PeerTimeout() time.Duration {
+	return *f.peerTimeout
+}
+
+// StandingQueries returns the path to the file of standing queries,
+// and it's populated by a value from the command-line.
+func (f ImmutableServerFlags) StandingQueries() string {
+	return *f.standingQueries
+}
+
+// SavedSearches returns whether to run SavedSearches, and it's
+// populated by a value from the command-line.
+func (f ImmutableServerFlags) SavedSearches() bool {
+	return *f.savedSearches
+}
+
+// SavedSearchesAdminNamespace returns the namespace whose
+// SavedSearches may search any namespace, and it's populated by a
+// value from the command-line.
+func (f ImmutableServerFlags) SavedSearchesAdminNamespace() string {
+	return *f.savedSearchesAdminNamespace
+}
+
+// SavedSearchesAdmission returns whether to serve the admission
+// webhook that records the creators of SavedSearches, and it's
+// populated by a value from the command-line.
+func (f ImmutableServerFlags) SavedSearchesAdmission() bool {
+	return *f.savedSearchesAdmission
+}
+
+// SavedSearchesNotificationHosts returns the hosts to which
+// SavedSearches outside the admin namespace may send notifications,
+// and it's populated by a value from the command-line.
+func (f ImmutableServerFlags) SavedSearchesNotificationHosts() (result []string) {
+	for _, h := range strings.Split(*f.savedSearchesNotificationHosts, ",") {
+		if h = strings.TrimSpace(h); h != "" {
+			result = append(result, h)
+		}
+	}
+	return
+}
+
+// FromDir returns the path to the manifests that are indexed instead
+// of a cluster, and it's populated by a value from the command-line.
+func (f ImmutableServerFlags) FromDir() string {
+	return *f.fromDir
+}
+
+// Index returns whether the subcommand `index` was given, and it's
+// populated by a value from the command-line.
+func (f ImmutableServerFlags) Index() bool {
+	return *f.index
 }
 
 // Parse populates this collection of ImmutableServerFlags with values from the
 // command-line.
 func (f ImmutableServerFlags) Parse() {
-	flag.Parse()
+	f.parse(os.Args[1:])
+}
+
+func (f ImmutableServerFlags) parse(args []string) {
+	if len(args) > 0 && args[0] == indexCommand {
+		*f.index = true
+		args = args[1:]
+	}
+
+	// The flag set exits on errors.
+	_ = flag.CommandLine.Parse(args)
+}
+
+// Validate returns an error if flags given on the command-line can't
+// be used together.
+func (f ImmutableServerFlags) Validate() error {
+	if flag.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments %q; the only subcommand is %s, and it must come first", flag.Args(), indexCommand)
+	}
+
+	if f.Index() && f.FromDir() == "" {
+		return errors.New(indexCommand + " requires -from-dir")
+	}
+
+	if conflicts := f.fromDirConflicts(); f.FromDir() != "" && len(conflicts) > 0 {
+		return fmt.Errorf("-from-dir can't be used with %s, because manifests are searched without a cluster", strings.Join(conflicts, ", "))
+	}
+
+	// Federated queries are neither authenticated nor filtered, and
+	// the credentials of callers aren't forwarded to peers.
+	if len(f.Peers()) > 0 && (f.Auth() || f.AggregatedAPI()) {
+		return errors.New("-peers can't be used with -auth or -aggregated-api, because federated queries aren't authorized; give -auth to each peer instead")
+	}
+
+	if f.SavedSearchesAdmission() && !f.SavedSearches() {
+		return errors.New("-saved-searches-admission requires -saved-searches")
+	}
+
+	// The Kubernetes API server calls admission webhooks only over
+	// HTTPS with a certificate that it can verify.
+	if f.SavedSearchesAdmission() && f.TLSCertFile() == "" {
+		return errors.New("-saved-searches-admission requires -tls-cert-file and -tls-private-key-file")
+	}
+
+	return nil
+}
+
+// fromDirConflicts returns the given flags that need a cluster, or
+// that aren't used when searching manifests.
+func (f ImmutableServerFlags) fromDirConflicts() (result []string) {
+	given := map[string]bool{
+		"-peers":                             len(f.Peers()) > 0,
+		"-standing-queries":                  f.StandingQueries() != "",
+		"-saved-searches":                    f.SavedSearches(),
+		"-saved-searches-admission":          f.SavedSearchesAdmission(),
+		"-saved-searches-notification-hosts": len(f.SavedSearchesNotificationHosts()) > 0,
+		"-aggregated-api":                    f.AggregatedAPI(),
+		"-auth":                              f.Auth(),
+		"-tls-cert-file":                     f.TLSCertFile() != "",
+		"-tls-private-key-file":              f.TLSPrivateKeyFile() != "",
+		"-client-ca-file":                    f.ClientCAFile() != "",
+	}
+
+	for name, ok := range given {
+		if ok {
+			result = append(result, name)
+		}
+	}
+
+	sort.Strings(result)
+
+	return
 }
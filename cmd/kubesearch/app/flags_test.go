@@ -0,0 +1,74 @@
+package app
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		name string
+		args []string
+		err  string
+	}{
+		{name: "no flags"},
+		{name: "saved searches", args: []string{"-saved-searches"}},
+		{name: "admission", args: []string{"-saved-searches", "-saved-searches-admission", "-tls-cert-file", "tls.crt", "-tls-private-key-file", "tls.key"}},
+		{name: "admission without saved searches", args: []string{"-saved-searches-admission", "-tls-cert-file", "tls.crt"}, err: "-saved-searches-admission requires -saved-searches"},
+		{name: "admission without TLS", args: []string{"-saved-searches", "-saved-searches-admission"}, err: "-saved-searches-admission requires -tls-cert-file and -tls-private-key-file"},
+		{name: "from dir", args: []string{"-from-dir", "manifests", "-bind-address", ":9090"}},
+		{name: "index", args: []string{"index", "-from-dir", "manifests"}},
+		{name: "index with two dashes", args: []string{"index", "--from-dir", "manifests"}},
+		{name: "index without from dir", args: []string{"index"}, err: "index requires -from-dir"},
+		{name: "index after flags", args: []string{"-from-dir", "manifests", "index"}, err: `unexpected arguments ["index"]; the only subcommand is index, and it must come first`},
+		{name: "from dir with standing queries", args: []string{"-from-dir", "manifests", "-standing-queries", "queries.yaml"}, err: "-from-dir can't be used with -standing-queries, because manifests are searched without a cluster"},
+		{name: "from dir with saved searches", args: []string{"index", "-from-dir", "manifests", "-saved-searches"}, err: "-from-dir can't be used with -saved-searches, because manifests are searched without a cluster"},
+		{name: "from dir with the aggregated API", args: []string{"-from-dir", "manifests", "-aggregated-api"}, err: "-from-dir can't be used with -aggregated-api, because manifests are searched without a cluster"},
+		{name: "from dir with auth and TLS", args: []string{"-from-dir", "manifests", "-auth", "-tls-cert-file", "tls.crt", "-tls-private-key-file", "tls.key", "-client-ca-file", "ca.crt"}, err: "-from-dir can't be used with -auth, -client-ca-file, -tls-cert-file, -tls-private-key-file, because manifests are searched without a cluster"},
+		{name: "peers", args: []string{"-peers", "http://flargle:8080"}},
+		{name: "peers with auth", args: []string{"-peers", "http://flargle:8080", "-auth"}, err: "-peers can't be used with -auth or -aggregated-api, because federated queries aren't authorized; give -auth to each peer instead"},
+		{name: "peers with the aggregated API", args: []string{"-peers", "http://flargle:8080", "-aggregated-api"}, err: "-peers can't be used with -auth or -aggregated-api, because federated queries aren't authorized; give -auth to each peer instead"},
+		{name: "from dir with peers", args: []string{"-from-dir", "manifests", "-peers", "http://flargle:8080"}, err: "-from-dir can't be used with -peers, because manifests are searched without a cluster"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := createTestFlags(t, c.args...).Validate()
+
+			if c.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, c.err)
+			}
+		})
+	}
+}
+
+func TestParse_index(t *testing.T) {
+	flags := createTestFlags(t, "index", "-from-dir", "manifests")
+	assert.True(t, flags.Index())
+	assert.Equal(t, "manifests", flags.FromDir())
+
+	flags = createTestFlags(t, "-from-dir", "manifests")
+	assert.False(t, flags.Index())
+}
+
+func TestSavedSearchesNotificationHosts(t *testing.T) {
+	assert.Empty(t, createTestFlags(t).SavedSearchesNotificationHosts())
+	assert.Equal(t, []string{"hooks.example.com", "chat.example.com"}, createTestFlags(t, "-saved-searches-notification-hosts", " hooks.example.com,,chat.example.com ").SavedSearchesNotificationHosts())
+}
+
+// createTestFlags returns ImmutableServerFlags populated with the
+// given arguments instead of those of the command-line.
+func createTestFlags(t *testing.T, args ...string) ImmutableServerFlags {
+	commandLine := flag.CommandLine
+	t.Cleanup(func() { flag.CommandLine = commandLine })
+
+	flag.CommandLine = flag.NewFlagSet(t.Name(), flag.ContinueOnError)
+	flags := CreateImmutableServerFlags()
+	flags.parse(args)
+
+	return flags
+}
@@ -0,0 +1,42 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/kubideh/kubesearch/search/controller"
+)
+
+const (
+	healthzPath = "/healthz"
+	readyzPath  = "/readyz"
+)
+
+// registerHealthHandlers registers the liveness and readiness
+// handlers with the given mux.
+func registerHealthHandlers(mux *http.ServeMux, aController *controller.Controller) {
+	mux.HandleFunc(healthzPath, healthz)
+	mux.HandleFunc(readyzPath, createReadyzHandler(aController))
+}
+
+// healthz responds with `200 OK` for as long as the server is able
+// to handle requests.
+func healthz(writer http.ResponseWriter, request *http.Request) {
+	fmt.Fprintln(writer, "ok")
+}
+
+// createReadyzHandler returns a handler that responds with `200 OK`
+// once the informer caches of the given Controller are synced and
+// its indexers have drained their workqueues, and with `503 Service
+// Unavailable` otherwise. A nil Controller, e.g., when federating
+// queries, is always ready.
+func createReadyzHandler(aController *controller.Controller) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		if aController != nil && !aController.Ready() {
+			http.Error(writer, "informer caches are not synced or indexers are not drained", http.StatusServiceUnavailable)
+			return
+		}
+
+		fmt.Fprintln(writer, "ok")
+	}
+}
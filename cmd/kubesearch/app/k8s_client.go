@@ -1,21 +1,29 @@
 package app
 
 import (
+	"fmt"
+	"os"
+
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 	"k8s.io/klog/v2"
 )
 
 // createKubernetesClientset returns Kubernetes client objects
 // (`kubernetes.Clientset`) from the configuration given by `flags`.
 func createKubernetesClientset(flags ImmutableServerFlags) *kubernetes.Clientset {
-	// use the current context in kubeConfig
-	config, err := clientcmd.BuildConfigFromFlags("", flags.KubeConfig())
+	config, err := createKubernetesConfig(flags)
 
 	if err != nil {
 		klog.Fatalln(err)
 	}
 
+	config.QPS = flags.KubeAPIQPS()
+	config.Burst = flags.KubeAPIBurst()
+
 	// create the clientset
 	client, err := kubernetes.NewForConfig(config)
 
@@ -25,3 +33,72 @@ func createKubernetesClientset(flags ImmutableServerFlags) *kubernetes.Clientset
 
 	return client
 }
+
+// createDynamicClient returns dynamic Kubernetes client objects from
+// the configuration given by `flags`.
+func createDynamicClient(flags ImmutableServerFlags) dynamic.Interface {
+	config, err := createKubernetesConfig(flags)
+
+	if err != nil {
+		klog.Fatalln(err)
+	}
+
+	config.QPS = flags.KubeAPIQPS()
+	config.Burst = flags.KubeAPIBurst()
+
+	client, err := dynamic.NewForConfig(config)
+
+	if err != nil {
+		klog.Fatalln(err)
+	}
+
+	return client
+}
+
+// createKubernetesConfig returns the in-cluster configuration when
+// running in a Pod without a kubeconfig file or master URL;
+// otherwise, it uses the kubeconfig file, context and master URL
+// given by `flags`.
+func createKubernetesConfig(flags ImmutableServerFlags) (*rest.Config, error) {
+	inCluster, err := useInClusterConfig(flags)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if inCluster {
+		klog.Infoln("Using the in-cluster configuration")
+		return rest.InClusterConfig()
+	}
+
+	loadingRules := &clientcmd.ClientConfigLoadingRules{ExplicitPath: flags.KubeConfig()}
+	overrides := &clientcmd.ConfigOverrides{
+		ClusterInfo:    clientcmdapi.Cluster{Server: flags.Master()},
+		CurrentContext: flags.Context(),
+	}
+
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides).ClientConfig()
+}
+
+// useInClusterConfig returns true if there's neither a master URL,
+// a context nor a kubeconfig file. The default kubeconfig file may be
+// missing, e.g., in a Pod, but one given by `-kubeconfig` must exist,
+// so that kubesearch never indexes a cluster other than the one asked
+// for.
+func useInClusterConfig(flags ImmutableServerFlags) (bool, error) {
+	if flags.Master() != "" || flags.Context() != "" {
+		return false, nil
+	}
+
+	if flags.KubeConfig() == "" {
+		return true, nil
+	}
+
+	_, err := os.Stat(flags.KubeConfig())
+
+	if os.IsNotExist(err) && flags.KubeConfigGiven() {
+		return false, fmt.Errorf("the kubeconfig file %s given by -kubeconfig doesn't exist", flags.KubeConfig())
+	}
+
+	return os.IsNotExist(err), nil
+}
@@ -0,0 +1,49 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUseInClusterConfig(t *testing.T) {
+	flags := createFlags(filepath.Join(t.TempDir(), "missing"), "")
+
+	_, err := useInClusterConfig(flags)
+
+	assert.EqualError(t, err, "the kubeconfig file "+flags.KubeConfig()+" given by -kubeconfig doesn't exist")
+}
+
+func TestUseInClusterConfig_existingKubeConfig(t *testing.T) {
+	kubeConfig := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, os.WriteFile(kubeConfig, nil, 0o600))
+
+	inCluster, err := useInClusterConfig(createFlags(kubeConfig, ""))
+
+	require.NoError(t, err)
+	assert.False(t, inCluster)
+}
+
+func TestUseInClusterConfig_missingDefaultKubeConfig(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	inCluster, err := useInClusterConfig(createFlags("", ""))
+
+	require.NoError(t, err)
+	assert.True(t, inCluster)
+}
+
+func TestUseInClusterConfig_master(t *testing.T) {
+	inCluster, err := useInClusterConfig(createFlags(filepath.Join(t.TempDir(), "missing"), "https://flargle:6443"))
+
+	require.NoError(t, err)
+	assert.False(t, inCluster)
+}
+
+func createFlags(kubeConfig, master string) ImmutableServerFlags {
+	context := ""
+	return ImmutableServerFlags{kubeConfig: &kubeConfig, master: &master, context: &context}
+}
@@ -0,0 +1,98 @@
+package app
+
+import (
+	"context"
+	"os"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/klog/v2"
+)
+
+// LeaderFunc is a singleton duty, e.g., snapshotting or alert
+// evaluation, that runs only on the replica holding the lease. It
+// must return once the given context is done.
+type LeaderFunc func(ctx context.Context)
+
+// runLeaderElection campaigns for the Lease given by flags until
+// ctx is done. Every duty is started whenever this replica becomes
+// the leader, and it's cancelled whenever leadership is lost, after
+// which this replica campaigns again. Search requests are served
+// by every replica regardless of leadership.
+func runLeaderElection(ctx context.Context, flags ImmutableServerFlags, client kubernetes.Interface, duties []LeaderFunc) {
+	lock, err := createLeaseLock(flags, client)
+
+	if err != nil {
+		klog.Errorln(err)
+		return
+	}
+
+	config := leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   flags.LeaderElectLeaseDuration(),
+		RenewDeadline:   flags.LeaderElectRenewDeadline(),
+		RetryPeriod:     flags.LeaderElectRetryPeriod(),
+		ReleaseOnCancel: true,
+		Name:            flags.LeaderElectLeaseName(),
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				klog.Infof("Became the leader as %s", lock.Identity())
+				runDuties(ctx, duties)
+			},
+			OnStoppedLeading: func() {
+				klog.Infof("Stopped leading as %s", lock.Identity())
+			},
+			OnNewLeader: func(identity string) {
+				klog.Infof("The leader is %s", identity)
+			},
+		},
+	}
+
+	for ctx.Err() == nil {
+		leaderelection.RunOrDie(ctx, config)
+	}
+}
+
+func createLeaseLock(flags ImmutableServerFlags, client kubernetes.Interface) (resourcelock.Interface, error) {
+	return resourcelock.New(
+		resourcelock.LeasesResourceLock,
+		flags.LeaderElectNamespace(),
+		flags.LeaderElectLeaseName(),
+		client.CoreV1(),
+		client.CoordinationV1(),
+		resourcelock.ResourceLockConfig{Identity: identity()},
+	)
+}
+
+// identity returns a unique name for this replica. The hostname is
+// the name of the Pod when running in a cluster.
+func identity() string {
+	hostname, err := os.Hostname()
+
+	if err != nil {
+		klog.Warningln("error getting hostname: ", err)
+		return string(uuid.NewUUID())
+	}
+
+	return hostname + "_" + string(uuid.NewUUID())
+}
+
+// runDuties runs every duty concurrently, and it returns once all
+// of them have returned.
+func runDuties(ctx context.Context, duties []LeaderFunc) {
+	var wg sync.WaitGroup
+
+	for _, d := range duties {
+		wg.Add(1)
+
+		go func(duty LeaderFunc) {
+			defer wg.Done()
+			duty(ctx)
+		}(d)
+	}
+
+	wg.Wait()
+}
@@ -0,0 +1,117 @@
+package app
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/pointer"
+)
+
+func TestRunLeaderElection(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	flags := createLeaderElectionFlags()
+
+	// Another replica holds the Lease.
+	holdLease(t, client, "flargle", 1)
+
+	var running int32
+	duty := func(ctx context.Context) {
+		atomic.StoreInt32(&running, 1)
+		<-ctx.Done()
+		atomic.StoreInt32(&running, 0)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go runLeaderElection(ctx, flags, client, []LeaderFunc{duty})
+
+	// Duties don't run while another replica holds the Lease.
+	time.Sleep(500 * time.Millisecond)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&running))
+
+	// Duties run once the Lease of the other replica expires.
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&running) == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	// Duties stop once the Lease is lost to another replica.
+	holdLease(t, client, "blargle", 60)
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&running) == 0 }, 5*time.Second, 10*time.Millisecond)
+
+	// Duties don't run again while another replica holds the Lease.
+	time.Sleep(500 * time.Millisecond)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&running))
+}
+
+func TestRunDuties(t *testing.T) {
+	var count int32
+	duty := func(ctx context.Context) {
+		atomic.AddInt32(&count, 1)
+		<-ctx.Done()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		runDuties(ctx, []LeaderFunc{duty, duty})
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&count) == 2 }, time.Second, time.Millisecond)
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("runDuties didn't return after its context was done")
+	}
+}
+
+// holdLease makes the replica with the given identity hold the Lease
+// given by createLeaderElectionFlags for the given number of seconds.
+func holdLease(t *testing.T, client kubernetes.Interface, identity string, seconds int32) {
+	now := metav1.NewMicroTime(time.Now())
+	lease := &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{Name: "kubesearch", Namespace: "kubesearch"},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       pointer.String(identity),
+			LeaseDurationSeconds: pointer.Int32(seconds),
+			AcquireTime:          &now,
+			RenewTime:            &now,
+		},
+	}
+
+	leases := client.CoordinationV1().Leases("kubesearch")
+
+	if existing, err := leases.Get(context.Background(), lease.Name, metav1.GetOptions{}); err == nil {
+		lease.ResourceVersion = existing.ResourceVersion
+		_, err = leases.Update(context.Background(), lease, metav1.UpdateOptions{})
+		require.NoError(t, err)
+		return
+	}
+
+	_, err := leases.Create(context.Background(), lease, metav1.CreateOptions{})
+	require.NoError(t, err)
+}
+
+func createLeaderElectionFlags() ImmutableServerFlags {
+	namespace, name := "kubesearch", "kubesearch"
+	leaseDuration, renewDeadline, retryPeriod := time.Second, 500*time.Millisecond, 100*time.Millisecond
+
+	return ImmutableServerFlags{
+		leaderElectNamespace:     &namespace,
+		leaderElectLeaseName:     &name,
+		leaderElectLeaseDuration: &leaseDuration,
+		leaderElectRenewDeadline: &renewDeadline,
+		leaderElectRetryPeriod:   &retryPeriod,
+	}
+}
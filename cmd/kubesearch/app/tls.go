@@ -0,0 +1,262 @@
+package app
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	certutil "k8s.io/client-go/util/cert"
+	"k8s.io/klog/v2"
+)
+
+// reloadPeriod is how often the certificate files are checked for
+// changes.
+const reloadPeriod = 10 * time.Second
+
+// createTLSConfig returns the TLS configuration of the server, or
+// nil if the server should not use TLS. The aggregated API must be
+// served over TLS, so a self-signed certificate is generated if no
+// certificate is given. Certificates given by files are reloaded
+// whenever the files change until ctx is done.
+//
+// If a client CA is given, then client certificates are verified
+// against it. They're required (mTLS) unless callers are
+// authenticated, because callers may then use bearer tokens
+// instead. Without a client CA, client certificates are requested,
+// but not verified during the handshake, so that the Kubernetes API
+// aggregator can identify itself.
+func createTLSConfig(ctx context.Context, flags ImmutableServerFlags) (*tls.Config, error) {
+	switch {
+	case flags.TLSCertFile() != "" || flags.TLSPrivateKeyFile() != "":
+		return createReloadingTLSConfig(ctx, flags)
+	case flags.AggregatedAPI():
+		return createSelfSignedTLSConfig(flags)
+	default:
+		return nil, nil
+	}
+}
+
+func createReloadingTLSConfig(ctx context.Context, flags ImmutableServerFlags) (*tls.Config, error) {
+	reloader := &certificateReloader{
+		certFile:          flags.TLSCertFile(),
+		keyFile:           flags.TLSPrivateKeyFile(),
+		clientCAFile:      flags.ClientCAFile(),
+		requireClientCert: requireClientCert(flags),
+	}
+
+	if err := reloader.load(); err != nil {
+		return nil, err
+	}
+
+	go wait.Until(reloader.reloadIfChanged, reloadPeriod, ctx.Done())
+
+	return &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		GetConfigForClient: reloader.configForClient,
+	}, nil
+}
+
+func createSelfSignedTLSConfig(flags ImmutableServerFlags) (*tls.Config, error) {
+	certificate, err := createSelfSignedCertificate()
+
+	if err != nil {
+		return nil, err
+	}
+
+	clientCAs, err := loadClientCAs(flags.ClientCAFile())
+
+	if err != nil {
+		return nil, err
+	}
+
+	return createServerTLSConfig(certificate, clientCAs, requireClientCert(flags)), nil
+}
+
+func requireClientCert(flags ImmutableServerFlags) bool {
+	return !flags.Auth() && !flags.AggregatedAPI()
+}
+
+func createServerTLSConfig(certificate tls.Certificate, clientCAs *x509.CertPool, requireClientCert bool) *tls.Config {
+	config := &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		ClientAuth:   tls.RequestClientCert,
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if clientCAs != nil {
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+		config.ClientCAs = clientCAs
+
+		if requireClientCert {
+			config.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+
+	return config
+}
+
+func createSelfSignedCertificate() (tls.Certificate, error) {
+	host, err := os.Hostname()
+
+	if err != nil {
+		host = "localhost"
+	}
+
+	klog.Infof("Generating a self-signed certificate for %s", host)
+
+	certificatePEM, keyPEM, err := certutil.GenerateSelfSignedCertKey(host, nil, []string{"localhost"})
+
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	return tls.X509KeyPair(certificatePEM, keyPEM)
+}
+
+// loadClientCAs returns nil if no file is given.
+func loadClientCAs(file string) (*x509.CertPool, error) {
+	if file == "" {
+		return nil, nil
+	}
+
+	data, err := ioutil.ReadFile(file)
+
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in %s", file)
+	}
+
+	return pool, nil
+}
+
+// certificateReloader holds the serving certificate and client CAs
+// loaded from files, and it reloads them whenever any of the files
+// are modified.
+type certificateReloader struct {
+	certFile          string
+	keyFile           string
+	clientCAFile      string
+	requireClientCert bool
+
+	config   *tls.Config
+	modTimes map[string]time.Time
+	mutex    sync.RWMutex
+}
+
+func (r *certificateReloader) load() error {
+	modTimes, err := r.readModTimes()
+
+	if err != nil {
+		return err
+	}
+
+	certificate, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+
+	if err != nil {
+		return err
+	}
+
+	clientCAs, err := loadClientCAs(r.clientCAFile)
+
+	if err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.config = createServerTLSConfig(certificate, clientCAs, r.requireClientCert)
+	r.modTimes = modTimes
+
+	return nil
+}
+
+func (r *certificateReloader) reloadIfChanged() {
+	modTimes, err := r.readModTimes()
+
+	if err != nil {
+		klog.Warningln("error checking certificate files: ", err)
+		return
+	}
+
+	if !r.changed(modTimes) {
+		return
+	}
+
+	// Keep serving the previous certificate if the new files are
+	// invalid, e.g., because they are only partially written.
+	if err := r.load(); err != nil {
+		klog.Warningln("error reloading certificate files: ", err)
+		return
+	}
+
+	klog.Infoln("Reloaded the serving certificate")
+}
+
+func (r *certificateReloader) changed(modTimes map[string]time.Time) bool {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for file, modTime := range modTimes {
+		if !modTime.Equal(r.modTimes[file]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *certificateReloader) readModTimes() (map[string]time.Time, error) {
+	result := make(map[string]time.Time)
+
+	for _, file := range []string{r.certFile, r.keyFile, r.clientCAFile} {
+		if file == "" {
+			continue
+		}
+
+		info, err := os.Stat(file)
+
+		if err != nil {
+			return nil, err
+		}
+
+		result[file] = info.ModTime()
+	}
+
+	return result, nil
+}
+
+func (r *certificateReloader) configForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.config, nil
+}
+
+// listenAndServe serves the given handler over TLS if tlsConfig is
+// not nil, and over plain HTTP otherwise.
+func listenAndServe(address string, handler http.Handler, tlsConfig *tls.Config) error {
+	if tlsConfig == nil {
+		return http.ListenAndServe(address, handler)
+	}
+
+	server := &http.Server{
+		Addr:      address,
+		Handler:   handler,
+		TLSConfig: tlsConfig,
+	}
+
+	return server.ListenAndServeTLS("", "")
+}
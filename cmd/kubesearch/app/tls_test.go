@@ -0,0 +1,231 @@
+package app
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kubideh/kubesearch/search/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateServerTLSConfig(t *testing.T) {
+	clientCAs := x509.NewCertPool()
+
+	assert.Equal(t, tls.RequestClientCert, createServerTLSConfig(tls.Certificate{}, nil, true).ClientAuth)
+	assert.Equal(t, tls.VerifyClientCertIfGiven, createServerTLSConfig(tls.Certificate{}, clientCAs, false).ClientAuth)
+	assert.Equal(t, tls.RequireAndVerifyClientCert, createServerTLSConfig(tls.Certificate{}, clientCAs, true).ClientAuth)
+}
+
+func TestCertificateReloader(t *testing.T) {
+	dir := t.TempDir()
+	ca := createCA(t, "flargle-ca")
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+
+	ca.writeCertificate(t, certFile, keyFile, "flargle", x509.ExtKeyUsageServerAuth)
+
+	reloader := &certificateReloader{certFile: certFile, keyFile: keyFile}
+	require.NoError(t, reloader.load())
+	assert.Equal(t, "flargle", servedCommonName(t, reloader))
+
+	// A changed certificate is served after it's reloaded.
+	ca.writeCertificate(t, certFile, keyFile, "blargle", x509.ExtKeyUsageServerAuth)
+	touch(t, time.Now().Add(time.Minute), certFile, keyFile)
+
+	reloader.reloadIfChanged()
+	assert.Equal(t, "blargle", servedCommonName(t, reloader))
+
+	// The previous certificate is served if the files are invalid.
+	require.NoError(t, os.WriteFile(keyFile, []byte("bobble"), 0o600))
+	touch(t, time.Now().Add(2*time.Minute), keyFile)
+
+	reloader.reloadIfChanged()
+	assert.Equal(t, "blargle", servedCommonName(t, reloader))
+}
+
+func TestCreateTLSConfig_mutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := createCA(t, "flargle-ca")
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	clientCertFile, clientKeyFile := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+	caFile := filepath.Join(dir, "ca.crt")
+
+	ca.writeCertificate(t, certFile, keyFile, "flargle", x509.ExtKeyUsageServerAuth)
+	ca.writeCertificate(t, clientCertFile, clientKeyFile, "blargle", x509.ExtKeyUsageClientAuth)
+	ca.writeCA(t, caFile)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tlsConfig, err := createTLSConfig(ctx, createTLSFlags(certFile, keyFile, caFile, false))
+	require.NoError(t, err)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("X-User", request.TLS.VerifiedChains[0][0].Subject.CommonName)
+	}))
+	server.TLS = tlsConfig
+	server.StartTLS()
+	defer server.Close()
+
+	// Client certificates are required.
+	_, err = createTLSClient(ca, nil).Get(server.URL)
+	assert.Error(t, err)
+
+	clientCert, err := tls.LoadX509KeyPair(clientCertFile, clientKeyFile)
+	require.NoError(t, err)
+
+	response, err := createTLSClient(ca, &clientCert).Get(server.URL)
+	require.NoError(t, err)
+	defer response.Body.Close()
+
+	assert.Equal(t, "blargle", response.Header.Get("X-User"))
+}
+
+func TestCreateTLSConfig_optionalClientCertificatesWithAuth(t *testing.T) {
+	dir := t.TempDir()
+	ca := createCA(t, "flargle-ca")
+	certFile, keyFile, caFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), filepath.Join(dir, "ca.crt")
+
+	ca.writeCertificate(t, certFile, keyFile, "flargle", x509.ExtKeyUsageServerAuth)
+	ca.writeCA(t, caFile)
+
+	tlsConfig, err := createTLSConfig(context.Background(), createTLSFlags(certFile, keyFile, caFile, true))
+	require.NoError(t, err)
+
+	config, err := tlsConfig.GetConfigForClient(nil)
+	require.NoError(t, err)
+	assert.Equal(t, tls.VerifyClientCertIfGiven, config.ClientAuth)
+}
+
+func TestCheckRequestHeaderClientCAs(t *testing.T) {
+	dir := t.TempDir()
+	ca, other := createCA(t, "flargle-ca"), createCA(t, "front-proxy-ca")
+	caFile := filepath.Join(dir, "ca.crt")
+	ca.writeCA(t, caFile)
+
+	assert.NoError(t, checkRequestHeaderClientCAs("", auth.RequestHeaderConfig{ClientCACerts: []*x509.Certificate{other.certificate}}))
+	assert.NoError(t, checkRequestHeaderClientCAs(caFile, auth.RequestHeaderConfig{ClientCACerts: []*x509.Certificate{ca.certificate}}))
+
+	err := checkRequestHeaderClientCAs(caFile, auth.RequestHeaderConfig{ClientCACerts: []*x509.Certificate{other.certificate}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `the request header client CA "front-proxy-ca" isn't in -client-ca-file`)
+}
+
+type testCA struct {
+	certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+}
+
+func createCA(t *testing.T, name string) testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	certificate, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return testCA{certificate: certificate, key: key}
+}
+
+func (ca testCA) writeCA(t *testing.T, file string) {
+	require.NoError(t, os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.certificate.Raw}), 0o600))
+}
+
+// writeCertificate writes a certificate for 127.0.0.1 with the given
+// common name and usage, signed by the CA, and its key.
+func (ca testCA) writeCertificate(t *testing.T, certFile, keyFile, name string, usage x509.ExtKeyUsage) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.certificate, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+}
+
+func createTLSClient(ca testCA, certificate *tls.Certificate) *http.Client {
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.certificate)
+
+	config := &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12}
+
+	if certificate != nil {
+		config.Certificates = []tls.Certificate{*certificate}
+	}
+
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+}
+
+func createTLSFlags(certFile, keyFile, clientCAFile string, authenticate bool) ImmutableServerFlags {
+	aggregatedAPI := false
+
+	return ImmutableServerFlags{
+		tlsCertFile:       &certFile,
+		tlsPrivateKeyFile: &keyFile,
+		clientCAFile:      &clientCAFile,
+		auth:              &authenticate,
+		aggregatedAPI:     &aggregatedAPI,
+	}
+}
+
+func servedCommonName(t *testing.T, reloader *certificateReloader) string {
+	config, err := reloader.configForClient(nil)
+	require.NoError(t, err)
+
+	certificate, err := x509.ParseCertificate(config.Certificates[0].Certificate[0])
+	require.NoError(t, err)
+
+	return certificate.Subject.CommonName
+}
+
+// touch sets the modification time of the given files, because they
+// may be written more than once within the resolution of the clock
+// of the file system.
+func touch(t *testing.T, modTime time.Time, files ...string) {
+	for _, file := range files {
+		require.NoError(t, os.Chtimes(file, modTime, modTime))
+	}
+}
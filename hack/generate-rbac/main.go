@@ -0,0 +1,232 @@
+// Package main generates the RBAC manifests needed to run kubesearch
+// in a cluster. The ClusterRole is derived from the resources
+// indexed by the controller, and it grants only list and watch, plus
+// reading SavedSearches and updating their status. The
+// Role grants access to the Lease used for leader election, and
+// the ServiceAccount is bound to `system:auth-delegator` so that it
+// can create TokenReviews and SubjectAccessReviews. The ServiceAccount
+// may also read the request header configuration of the Kubernetes
+// API aggregator from kube-system.
+//
+// Usage: go run ./hack/generate-rbac > deploy/rbac.yaml
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/kubideh/kubesearch/search/controller"
+	"github.com/kubideh/kubesearch/search/savedsearch"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+func main() {
+	namespace := flag.String("namespace", "kubesearch", "the namespace of the kubesearch ServiceAccount")
+	name := flag.String("name", "kubesearch", "the name of the kubesearch ServiceAccount, ClusterRole and ClusterRoleBinding")
+	flag.Parse()
+
+	manifests := []interface{}{
+		createClusterRole(*name),
+		createClusterRoleBinding(*namespace, *name),
+		createAuthDelegatorClusterRoleBinding(*namespace, *name),
+		createAuthenticationReaderRoleBinding(*namespace, *name),
+		createLeaderElectionRole(*namespace, *name),
+		createLeaderElectionRoleBinding(*namespace, *name),
+	}
+
+	if err := printManifests(manifests...); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func printManifests(manifests ...interface{}) error {
+	fmt.Println("# Code generated by hack/generate-rbac; DO NOT EDIT.")
+
+	for _, m := range manifests {
+		data, err := yaml.Marshal(m)
+
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("---")
+		fmt.Print(string(data))
+	}
+
+	return nil
+}
+
+func createClusterRole(name string) *rbacv1.ClusterRole {
+	return &rbacv1.ClusterRole{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: rbacv1.SchemeGroupVersion.String(),
+			Kind:       "ClusterRole",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Rules: append(createPolicyRules(controller.IndexedResources()), createSavedSearchPolicyRules()...),
+	}
+}
+
+// createPolicyRules returns one rule per API group that grants
+// list and watch on each indexed resource in that group.
+func createPolicyRules(resources []controller.Resource) (rules []rbacv1.PolicyRule) {
+	resourcesByGroup := make(map[string][]string)
+
+	for _, r := range resources {
+		group := r.GroupVersionResource.Group
+		resourcesByGroup[group] = append(resourcesByGroup[group], r.GroupVersionResource.Resource)
+	}
+
+	groups := make([]string, 0, len(resourcesByGroup))
+
+	for g := range resourcesByGroup {
+		groups = append(groups, g)
+	}
+
+	sort.Strings(groups)
+
+	for _, g := range groups {
+		sort.Strings(resourcesByGroup[g])
+
+		rules = append(rules, rbacv1.PolicyRule{
+			APIGroups: []string{g},
+			Resources: resourcesByGroup[g],
+			Verbs:     []string{"list", "watch"},
+		})
+	}
+
+	return
+}
+
+// createSavedSearchPolicyRules returns rules that grant watching
+// SavedSearches and updating their status.
+func createSavedSearchPolicyRules() []rbacv1.PolicyRule {
+	return []rbacv1.PolicyRule{
+		{
+			APIGroups: []string{savedsearch.GroupName},
+			Resources: []string{savedsearch.Resource.Resource},
+			Verbs:     []string{"get", "list", "watch"},
+		},
+		{
+			APIGroups: []string{savedsearch.GroupName},
+			Resources: []string{savedsearch.Resource.Resource + "/status"},
+			Verbs:     []string{"update"},
+		},
+	}
+}
+
+func createClusterRoleBinding(namespace, name string) *rbacv1.ClusterRoleBinding {
+	return createClusterRoleBindingTo(namespace, name, name, name)
+}
+
+// createAuthDelegatorClusterRoleBinding binds the built-in
+// ClusterRole used to delegate authentication and authorization
+// decisions to the Kubernetes API server.
+func createAuthDelegatorClusterRoleBinding(namespace, name string) *rbacv1.ClusterRoleBinding {
+	return createClusterRoleBindingTo(namespace, name, name+":auth-delegator", "system:auth-delegator")
+}
+
+func createClusterRoleBindingTo(namespace, name, bindingName, clusterRoleName string) *rbacv1.ClusterRoleBinding {
+	return &rbacv1.ClusterRoleBinding{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: rbacv1.SchemeGroupVersion.String(),
+			Kind:       "ClusterRoleBinding",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: bindingName,
+		},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "ClusterRole",
+			Name:     clusterRoleName,
+		},
+		Subjects: []rbacv1.Subject{
+			{
+				Kind:      rbacv1.ServiceAccountKind,
+				Name:      name,
+				Namespace: namespace,
+			},
+		},
+	}
+}
+
+func createLeaderElectionRole(namespace, name string) *rbacv1.Role {
+	return &rbacv1.Role{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: rbacv1.SchemeGroupVersion.String(),
+			Kind:       "Role",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name + "-leader-election",
+			Namespace: namespace,
+		},
+		Rules: []rbacv1.PolicyRule{
+			{
+				APIGroups: []string{coordinationv1.GroupName},
+				Resources: []string{"leases"},
+				Verbs:     []string{"get", "create", "update"},
+			},
+		},
+	}
+}
+
+func createLeaderElectionRoleBinding(namespace, name string) *rbacv1.RoleBinding {
+	return &rbacv1.RoleBinding{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: rbacv1.SchemeGroupVersion.String(),
+			Kind:       "RoleBinding",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name + "-leader-election",
+			Namespace: namespace,
+		},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "Role",
+			Name:     name + "-leader-election",
+		},
+		Subjects: []rbacv1.Subject{
+			{
+				Kind:      rbacv1.ServiceAccountKind,
+				Name:      name,
+				Namespace: namespace,
+			},
+		},
+	}
+}
+
+// createAuthenticationReaderRoleBinding binds the built-in Role that
+// grants access to the ConfigMap
+// `kube-system/extension-apiserver-authentication`.
+func createAuthenticationReaderRoleBinding(namespace, name string) *rbacv1.RoleBinding {
+	return &rbacv1.RoleBinding{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: rbacv1.SchemeGroupVersion.String(),
+			Kind:       "RoleBinding",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name + ":extension-apiserver-authentication-reader",
+			Namespace: "kube-system",
+		},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "Role",
+			Name:     "extension-apiserver-authentication-reader",
+		},
+		Subjects: []rbacv1.Subject{
+			{
+				Kind:      rbacv1.ServiceAccountKind,
+				Name:      name,
+				Namespace: namespace,
+			},
+		},
+	}
+}
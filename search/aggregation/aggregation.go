@@ -0,0 +1,97 @@
+// Package aggregation serves the search API as an aggregated
+// Kubernetes API, so that it can be registered using an APIService
+// and reached through the Kubernetes API server, e.g.,
+//
+//	kubectl get --raw '/apis/search.kubideh.io/v1alpha1/search?q=nginx'
+package aggregation
+
+import (
+	"encoding/json"
+	"net/http"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// GroupName is the name of the API group served by KubeSearch.
+	GroupName = "search.kubideh.io"
+
+	// Version is the version of the API group served by KubeSearch.
+	Version = "v1alpha1"
+
+	searchResource = "search"
+	queryParamName = "q"
+)
+
+// SchemeGroupVersion is the group and version served by KubeSearch.
+var SchemeGroupVersion = schema.GroupVersion{Group: GroupName, Version: Version}
+
+var (
+	apisPath         = "/apis"
+	groupPath        = apisPath + "/" + GroupName
+	groupVersionPath = groupPath + "/" + Version
+	searchPath       = groupVersionPath + "/" + searchResource
+)
+
+// RegisterHandlers registers the discovery handlers and the given
+// search handler with the given mux at the paths expected by the
+// Kubernetes API aggregator.
+func RegisterHandlers(mux *http.ServeMux, search http.HandlerFunc) {
+	mux.HandleFunc(apisPath, writeAPIGroupList)
+	mux.HandleFunc(groupPath, writeAPIGroup)
+	mux.HandleFunc(groupVersionPath, writeAPIResourceList)
+	mux.HandleFunc(searchPath, search)
+}
+
+func writeAPIGroupList(writer http.ResponseWriter, request *http.Request) {
+	writeObject(writer, &metav1.APIGroupList{
+		TypeMeta: metav1.TypeMeta{Kind: "APIGroupList", APIVersion: "v1"},
+		Groups:   []metav1.APIGroup{createAPIGroup()},
+	})
+}
+
+func writeAPIGroup(writer http.ResponseWriter, request *http.Request) {
+	group := createAPIGroup()
+	group.TypeMeta = metav1.TypeMeta{Kind: "APIGroup", APIVersion: "v1"}
+	writeObject(writer, &group)
+}
+
+func writeAPIResourceList(writer http.ResponseWriter, request *http.Request) {
+	writeObject(writer, &metav1.APIResourceList{
+		TypeMeta:     metav1.TypeMeta{Kind: "APIResourceList", APIVersion: "v1"},
+		GroupVersion: SchemeGroupVersion.String(),
+		APIResources: []metav1.APIResource{
+			{
+				Name:       searchResource,
+				Namespaced: false,
+				Kind:       searchResultListKind,
+				Verbs:      metav1.Verbs{"get"},
+			},
+		},
+	})
+}
+
+func createAPIGroup() metav1.APIGroup {
+	version := metav1.GroupVersionForDiscovery{
+		GroupVersion: SchemeGroupVersion.String(),
+		Version:      Version,
+	}
+
+	return metav1.APIGroup{
+		Name:             GroupName,
+		Versions:         []metav1.GroupVersionForDiscovery{version},
+		PreferredVersion: version,
+	}
+}
+
+func writeObject(writer http.ResponseWriter, object interface{}) {
+	writer.Header().Set("Content-Type", "application/json")
+
+	encoder := json.NewEncoder(writer)
+
+	if err := encoder.Encode(object); err != nil {
+		klog.Warningln("error marshaling object: ", err)
+	}
+}
@@ -0,0 +1,85 @@
+package aggregation
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kubideh/kubesearch/search/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDiscovery(t *testing.T) {
+	server := setup()
+	defer server.Close()
+
+	var groups metav1.APIGroupList
+	get(t, server.URL+"/apis", &groups)
+
+	require.Len(t, groups.Groups, 1)
+	assert.Equal(t, "search.kubideh.io", groups.Groups[0].Name)
+	assert.Equal(t, "search.kubideh.io/v1alpha1", groups.Groups[0].PreferredVersion.GroupVersion)
+
+	var group metav1.APIGroup
+	get(t, server.URL+"/apis/search.kubideh.io", &group)
+
+	assert.Equal(t, "APIGroup", group.Kind)
+	assert.Equal(t, "search.kubideh.io", group.Name)
+
+	var resources metav1.APIResourceList
+	get(t, server.URL+"/apis/search.kubideh.io/v1alpha1", &resources)
+
+	assert.Equal(t, "search.kubideh.io/v1alpha1", resources.GroupVersion)
+	require.Len(t, resources.APIResources, 1)
+	assert.Equal(t, "search", resources.APIResources[0].Name)
+}
+
+func TestSearch(t *testing.T) {
+	server := setup()
+	defer server.Close()
+
+	var list SearchResultList
+	get(t, server.URL+"/apis/search.kubideh.io/v1alpha1/search?q=blargle", &list)
+
+	assert.Equal(t, "SearchResultList", list.Kind)
+	assert.Equal(t, "search.kubideh.io/v1alpha1", list.APIVersion)
+	assert.Equal(t, []api.Result{{Kind: "Pod", Name: "blargle", Namespace: "flargle", Rank: 1}}, list.Items)
+}
+
+func TestSearch_noResults(t *testing.T) {
+	server := setup()
+	defer server.Close()
+
+	var list SearchResultList
+	get(t, server.URL+"/apis/search.kubideh.io/v1alpha1/search?q=whatever", &list)
+
+	assert.NotNil(t, list.Items)
+	assert.Empty(t, list.Items)
+}
+
+func setup() *httptest.Server {
+	results := func(ctx context.Context, query string) []api.Result {
+		if query != "blargle" {
+			return nil
+		}
+		return []api.Result{{Kind: "Pod", Name: "blargle", Namespace: "flargle", Rank: 1}}
+	}
+
+	mux := http.NewServeMux()
+	RegisterHandlers(mux, CreateSearchHandler(results))
+
+	return httptest.NewServer(mux)
+}
+
+func get(t *testing.T, url string, object interface{}) {
+	response, err := http.Get(url)
+	require.NoError(t, err)
+	defer response.Body.Close()
+
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	require.NoError(t, json.NewDecoder(response.Body).Decode(object))
+}
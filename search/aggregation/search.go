@@ -0,0 +1,39 @@
+package aggregation
+
+import (
+	"net/http"
+
+	"github.com/kubideh/kubesearch/search/api"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const searchResultListKind = "SearchResultList"
+
+// SearchResultList is the response of the aggregated search API.
+type SearchResultList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []api.Result `json:"items"`
+}
+
+// CreateSearchHandler is a `http.HandlerFunc` that responds with a
+// JSON-encoded SearchResultList based on the query given by the
+// parameter `q`.
+func CreateSearchHandler(results api.ResultsFunc) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		items := results(request.Context(), request.URL.Query().Get(queryParamName))
+
+		if items == nil {
+			items = []api.Result{}
+		}
+
+		writeObject(writer, &SearchResultList{
+			TypeMeta: metav1.TypeMeta{
+				Kind:       searchResultListKind,
+				APIVersion: SchemeGroupVersion.String(),
+			},
+			Items: items,
+		})
+	}
+}
@@ -3,33 +3,114 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 )
 
 // Search is the API used to queryString for Kubernetes objects.
 func Search(endpoint, query string) (result []Result, err error) {
-	response, err := http.Get(searchURL(endpoint, query))
+	return SearchWithContext(context.Background(), endpoint, query)
+}
+
+// SearchWithContext is the same as Search, but the request is
+// bound to the given context so that callers can cancel it or
+// apply a deadline.
+func SearchWithContext(ctx context.Context, endpoint, query string) (result []Result, err error) {
+	return SearchWithClient(ctx, http.DefaultClient, endpoint, query)
+}
+
+// Options are the optional parameters of a search.
+type Options struct {
+	Explain    bool     // Explain asks for an explanation of each result
+	Limit      int      // Limit is the maximum number of results of a page, and zero means no limit
+	Continue   string   // Continue is the token that selects the next page
+	Facets     []string // Facets are the dimensions by which hits are counted
+	Sort       string   // Sort is a comma-separated list of doc values by which results are sorted
+	Namespaces []string // Namespaces are the only namespaces that are searched, and empty means every namespace
+}
+
+// SearchWithClient is the same as SearchWithContext, but the request
+// is sent using the given client, e.g., one that adds credentials.
+func SearchWithClient(ctx context.Context, client *http.Client, endpoint, query string) (result []Result, err error) {
+	return SearchWithOptions(ctx, client, endpoint, query, Options{})
+}
+
+// SearchWithOptions is the same as SearchWithClient, but it uses the
+// given options. Only Explain and Namespaces apply to the v1 API.
+func SearchWithOptions(ctx context.Context, client *http.Client, endpoint, query string, options Options) (result []Result, err error) {
+	err = get(ctx, client, searchURL(endpoint, endpointPath, query, options), &result)
+	return
+}
+
+// SearchV2WithClient is the same as SearchWithClient, but it uses the
+// v2 response schema and the given options.
+func SearchV2WithClient(ctx context.Context, client *http.Client, endpoint, query string, options Options) (result ResponseV2, err error) {
+	err = get(ctx, client, searchURL(endpoint, endpointPathV2, query, options), &result)
+	return
+}
+
+// get sends a request to the given URL, and it decodes the
+// JSON-encoded response into result.
+func get(ctx context.Context, client *http.Client, endpointURL string, result interface{}) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, endpointURL, nil)
 
 	if err != nil {
-		return
+		return err
+	}
+
+	response, err := client.Do(request)
+
+	if err != nil {
+		return err
 	}
 
 	body, err := ioutil.ReadAll(response.Body)
 	defer response.Body.Close()
 
 	if err != nil {
-		return
+		return err
 	}
 
-	err = json.Unmarshal(body, &result)
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status from %s: %s", request.URL.Host, response.Status)
+	}
 
-	return
+	return json.Unmarshal(body, result)
 }
 
-func searchURL(endpoint, query string) string {
-	return fmt.Sprintf("%s%s?%s=%s", endpoint, endpointPath, queryParamName, url.QueryEscape(query))
+func searchURL(endpoint, path, query string, options Options) string {
+	values := url.Values{}
+	values.Set(queryParamName, query)
+
+	if options.Explain {
+		values.Set(explainParamName, "true")
+	}
+
+	if options.Limit > 0 {
+		values.Set(limitParamName, strconv.Itoa(options.Limit))
+	}
+
+	if options.Continue != "" {
+		values.Set(continueParamName, options.Continue)
+	}
+
+	if options.Sort != "" {
+		values.Set(sortParamName, options.Sort)
+	}
+
+	for _, n := range options.Namespaces {
+		values.Add(namespaceParamName, n)
+	}
+
+	if len(options.Facets) > 0 {
+		values.Set(facetsParamName, strings.Join(options.Facets, ","))
+	}
+
+	return fmt.Sprintf("%s%s?%s", endpoint, path, values.Encode())
 }
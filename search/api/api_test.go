@@ -2,20 +2,25 @@ package api
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/kubideh/kubesearch/search/finder"
+	"github.com/kubideh/kubesearch/search/index"
 	"github.com/kubideh/kubesearch/search/searcher"
 	"github.com/kubideh/kubesearch/search/tokenizer"
 
 	"github.com/kubideh/kubesearch/search/controller"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
 )
 
 func TestSearch_emptyQuery(t *testing.T) {
@@ -93,6 +98,275 @@ func TestSearch_queryForAllPodsInNamespace(t *testing.T) {
 	}, result)
 }
 
+func TestSearchV2_emptyQuery(t *testing.T) {
+	server, cancel := setup(t)
+	defer server.Close()
+	defer cancel()
+
+	response, err := SearchV2WithClient(context.Background(), http.DefaultClient, server.URL, "", Options{})
+
+	assert.NoError(t, err)
+	assert.Empty(t, response.Results)
+}
+
+func TestSearchV2_queryForSinglePod(t *testing.T) {
+	server, cancel := setup(t)
+	defer server.Close()
+	defer cancel()
+
+	response, err := SearchV2WithClient(context.Background(), http.DefaultClient, server.URL, "blargle", Options{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []ResultV2{
+		{
+			APIVersion:    "v1",
+			Kind:          "Pod",
+			Name:          "blargle",
+			Namespace:     "flargle",
+			UID:           "e7b1c3f0-5a4d-4b0e-9a43-2d8f6c1e7a10",
+			Labels:        map[string]string{"app": "blargle"},
+			MatchedFields: []string{FieldName},
+			Highlights: []Highlight{
+				{
+					Field:   FieldName,
+					Value:   "blargle",
+					Snippet: "<em>blargle</em>",
+					Matches: []Match{{Start: 0, End: 7}},
+				},
+			},
+			Rank:    1,
+			Command: "kubectl get pod blargle -n flargle",
+		},
+	}, response.Results)
+}
+
+func TestSearchV2_queryMatchingNamespace(t *testing.T) {
+	server, cancel := setup(t)
+	defer server.Close()
+	defer cancel()
+
+	response, err := SearchV2WithClient(context.Background(), http.DefaultClient, server.URL, "flargle", Options{})
+
+	assert.NoError(t, err)
+	require.Len(t, response.Results, 2)
+
+	for _, r := range response.Results {
+		assert.Equal(t, []string{FieldNamespace}, r.MatchedFields)
+	}
+}
+
+func TestSearchV2_labelQuery(t *testing.T) {
+	server, cancel := setup(t)
+	defer server.Close()
+	defer cancel()
+
+	response, err := SearchV2WithClient(context.Background(), http.DefaultClient, server.URL, "label:app=blargle", Options{Explain: true})
+
+	assert.NoError(t, err)
+	require.Len(t, response.Results, 1)
+
+	result := response.Results[0]
+	assert.Equal(t, "blargle", result.Name)
+	assert.Equal(t, []string{FieldLabelPrefix + "app"}, result.MatchedFields)
+	assert.Equal(t, []Highlight{
+		{Field: FieldLabelPrefix + "app", Value: "blargle", Snippet: "<em>blargle</em>", Matches: []Match{{Start: 0, End: 7}}},
+	}, result.Highlights)
+
+	require.NotNil(t, result.Explanation)
+	assert.Equal(t, searcher.QueryNode{Operator: searcher.OperatorAnd, Children: []searcher.QueryNode{{Field: "label:app=blargle"}}}, result.Explanation.Query)
+}
+
+func TestFieldHighlights(t *testing.T) {
+	pod := testPodFlargleBlargle()
+	pod.Labels["tier"] = "web"
+	pod.Spec.Containers = []corev1.Container{{Name: "web", Image: "docker.io/library/nginx:1.21"}, {Name: "sidecar", Image: "envoy"}}
+
+	query, err := searcher.ParseQuery("ns:flargle label:tier image:nginx label:owner= blargle", tokenizer.Tokenizer())
+	require.NoError(t, err)
+
+	result := CreateResultV2(index.Posting{StoredObjectKey: "flargle/blargle", K8sResourceKind: "Pod"}, pod, query)
+
+	assert.Equal(t, []string{FieldName, FieldNamespace, FieldLabelPrefix + "tier", FieldImage}, result.MatchedFields)
+	assert.Equal(t, []Highlight{
+		{Field: FieldName, Value: "blargle", Snippet: "<em>blargle</em>", Matches: []Match{{Start: 0, End: 7}}},
+		{Field: FieldNamespace, Value: "flargle", Snippet: "<em>flargle</em>", Matches: []Match{{Start: 0, End: 7}}},
+		{Field: FieldLabelPrefix + "tier", Value: "web", Snippet: "<em>web</em>", Matches: []Match{{Start: 0, End: 3}}},
+		{Field: FieldImage, Value: "docker.io/library/nginx:1.21", Snippet: "<em>docker.io/library/nginx:1.21</em>", Matches: []Match{{Start: 0, End: 28}}},
+	}, result.Highlights)
+}
+
+func TestSearch_explain(t *testing.T) {
+	server, cancel := setup(t)
+	defer server.Close()
+	defer cancel()
+
+	response, err := http.Get(server.URL + "/v1/search?queryString=blargle&explain=true")
+	require.NoError(t, err)
+	defer response.Body.Close()
+
+	var result []Result
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&result))
+
+	require.Len(t, result, 1)
+	require.NotNil(t, result[0].Explanation)
+	assert.Equal(t, searcher.ScoreExplanation{Function: "max(termFrequency)", Term: "blargle", Value: 1}, result[0].Explanation.Score)
+}
+
+func TestSearchV2_explain(t *testing.T) {
+	server, cancel := setup(t)
+	defer server.Close()
+	defer cancel()
+
+	response, err := SearchV2WithClient(context.Background(), http.DefaultClient, server.URL, "flargle", Options{Explain: true})
+
+	assert.NoError(t, err)
+	require.Len(t, response.Results, 2)
+
+	for _, r := range response.Results {
+		require.NotNil(t, r.Explanation)
+		assert.Equal(t, 2, r.Explanation.Terms[0].DocumentFrequency)
+	}
+}
+
+func TestSearchV2_noExplanationUnlessAsked(t *testing.T) {
+	server, cancel := setup(t)
+	defer server.Close()
+	defer cancel()
+
+	response, err := SearchV2WithClient(context.Background(), http.DefaultClient, server.URL, "flargle", Options{})
+
+	assert.NoError(t, err)
+	require.Len(t, response.Results, 2)
+
+	for _, r := range response.Results {
+		assert.Nil(t, r.Explanation)
+	}
+}
+
+func TestSearchV2_sort(t *testing.T) {
+	server, cancel := setup(t)
+	defer server.Close()
+	defer cancel()
+
+	response, err := SearchV2WithClient(context.Background(), http.DefaultClient, server.URL, "flargle", Options{Sort: "-name"})
+
+	assert.NoError(t, err)
+	require.Len(t, response.Results, 2)
+	assert.Equal(t, "foo", response.Results[0].Name)
+	assert.Equal(t, "blargle", response.Results[1].Name)
+}
+
+func TestSnippet(t *testing.T) {
+	matches := []Match{{Start: 0, End: 5}, {Start: 10, End: 15}}
+
+	assert.Equal(t, "<em>nginx</em>-web-<em>nginx</em>", snippet("nginx-web-nginx", matches))
+}
+
+func TestCommand_clusterScoped(t *testing.T) {
+	assert.Equal(t, "kubectl get node flargle", command("Node", "flargle", ""))
+}
+
+func TestAPIVersion(t *testing.T) {
+	configMap := &corev1.ConfigMap{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"}}
+
+	assert.Equal(t, "apps/v1", apiVersion("Deployment", &appsv1.Deployment{}))
+	assert.Equal(t, "v1", apiVersion("ConfigMap", configMap))
+	assert.Equal(t, "", apiVersion("ConfigMap", &corev1.ConfigMap{}))
+}
+
+func TestSearchV2_deletedObject(t *testing.T) {
+	client := fake.NewSimpleClientset()
+
+	aController := controller.Create(client)
+	cancel := aController.Start()
+	defer cancel()
+
+	aSearcher := searcher.Create(aController.Index(), tokenizer.Tokenizer())
+	handler := CreateSearchHandlerV2(aSearcher, finder.Create(aController.Store()), searcher.CreateExplainer(aController.Index(), tokenizer.Tokenizer()), searcher.CreateSortKeys(aController.Index()))
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	for _, name := range []string{"a", "b", "c"} {
+		_, err := client.CoreV1().Pods("flargle").Create(context.TODO(), &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "flargle"}}, metav1.CreateOptions{})
+		require.NoError(t, err)
+	}
+
+	require.Eventually(t, func() bool { return len(aSearcher("flargle")) == 3 }, 5*time.Second, 10*time.Millisecond)
+
+	// The hit ranked in the middle is deleted.
+	require.NoError(t, client.CoreV1().Pods("flargle").Delete(context.TODO(), "b", metav1.DeleteOptions{}))
+	require.Eventually(t, func() bool { return len(aSearcher("flargle")) == 2 }, 5*time.Second, 10*time.Millisecond)
+
+	response, err := SearchV2WithClient(context.Background(), http.DefaultClient, server.URL, "flargle", Options{})
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, response.Metadata.TotalHits)
+	assert.Equal(t, []string{"a", "c"}, resultNames(response.Results))
+	assert.Empty(t, aSearcher("b"), "the postings of deleted objects are removed")
+}
+
+func TestCreateFindFunc_missingObject(t *testing.T) {
+	idx, stores := createIndexWithMissingObject(t)
+	find := createFindFunc(searcher.Create(idx, tokenizer.Tokenizer()), finder.Create(stores), unfiltered)
+
+	objects, postings := find(context.Background(), "flargle", nil, selectAll)
+
+	require.Len(t, objects, 3)
+	assert.Equal(t, "a", objects[0].Item.(*corev1.Pod).Name)
+	assert.Equal(t, "c", objects[1].Item.(*corev1.Pod).Name)
+	assert.Equal(t, "d", objects[2].Item.(*corev1.Pod).Name)
+	assert.Equal(t, []string{"flargle/a", "flargle/c", "flargle/d"}, []string{postings[0].StoredObjectKey, postings[1].StoredObjectKey, postings[2].StoredObjectKey})
+}
+
+func TestCreateSearchV2Func(t *testing.T) {
+	idx, stores := createIndexWithMissingObject(t)
+	search := CreateSearchV2Func(searcher.Create(idx, tokenizer.Tokenizer()), finder.Create(stores), searcher.CreateExplainer(idx, tokenizer.Tokenizer()), searcher.CreateSortKeys(idx))
+
+	first, err := search(context.Background(), "flargle", Options{Limit: 2, Namespaces: []string{"flargle,flargle"}, Explain: true})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "c"}, resultNames(first.Results))
+	assert.NotNil(t, first.Results[0].Explanation)
+	require.NotEmpty(t, first.Metadata.Continue)
+
+	second, err := search(context.Background(), "flargle", Options{Limit: 2, Namespaces: []string{"flargle"}, Continue: first.Metadata.Continue})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"d"}, resultNames(second.Results))
+	assert.Empty(t, second.Metadata.Continue)
+
+	_, err = search(context.Background(), "flargle", Options{Limit: -1})
+	assert.Error(t, err)
+
+	_, err = search(context.Background(), "flargle", Options{Facets: []string{"flargle"}})
+	assert.Error(t, err)
+}
+
+// createIndexWithMissingObject returns an index of the Pods a, b, c
+// and d, and their stores, but the Pod b is missing from the stores,
+// e.g., because it was deleted after it was searched.
+func createIndexWithMissingObject(t *testing.T) (*index.Index, map[string]cache.Store) {
+	idx := index.Create()
+	store := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	for _, name := range []string{"a", "b", "c", "d"} {
+		idx.Put([]string{"flargle"}, index.Posting{StoredObjectKey: "flargle/" + name, K8sResourceKind: "Pod"})
+
+		if name != "b" {
+			require.NoError(t, store.Add(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "flargle"}}))
+		}
+	}
+
+	return idx, map[string]cache.Store{"Pod": store}
+}
+
+func resultNames(results []ResultV2) (names []string) {
+	for _, r := range results {
+		names = append(names, r.Name)
+	}
+	return
+}
+
 func setup(t *testing.T) (*httptest.Server, context.CancelFunc) {
 	client := fake.NewSimpleClientset()
 
@@ -102,10 +376,12 @@ func setup(t *testing.T) (*httptest.Server, context.CancelFunc) {
 	aTokenizer := tokenizer.Tokenizer()
 	aSearcher := searcher.Create(aController.Index(), aTokenizer)
 	objectFinder := finder.Create(aController.Store())
-	handler := CreateSearchHandler(aSearcher, objectFinder)
+	anExplainer := searcher.CreateExplainer(aController.Index(), aTokenizer)
+	handler := CreateSearchHandler(aSearcher, objectFinder, anExplainer)
 	mux := http.NewServeMux()
 
 	RegisterSearchHandler(mux, handler)
+	RegisterSearchHandlerV2(mux, CreateSearchHandlerV2(aSearcher, objectFinder, anExplainer, searcher.CreateSortKeys(aController.Index())))
 
 	server := httptest.NewServer(mux)
 
@@ -129,6 +405,8 @@ func testPodFlargleBlargle() *corev1.Pod {
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      "blargle",
 			Namespace: "flargle",
+			UID:       "e7b1c3f0-5a4d-4b0e-9a43-2d8f6c1e7a10",
+			Labels:    map[string]string{"app": "blargle"},
 		},
 	}
 }
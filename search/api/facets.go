@@ -0,0 +1,162 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kubideh/kubesearch/search/finder"
+	"github.com/kubideh/kubesearch/search/index"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/klog/v2"
+)
+
+const facetsParamName = "facets"
+
+// Dimensions that hits may be counted by. A dimension of the form
+// `label:<key>` counts the values of the label with that key.
+const (
+	DimensionKind      = "kind"
+	DimensionNamespace = "namespace"
+	DimensionLabel     = "label"
+	DimensionOwnerKind = "ownerKind"
+	DimensionNode      = "node"
+
+	labelDimensionPrefix = DimensionLabel + ":"
+)
+
+// Facet is the number of hits for each value of a dimension.
+type Facet struct {
+	Dimension string       `json:"dimension"`
+	Counts    []FacetCount `json:"counts"`
+}
+
+// FacetCount is the number of hits for a single value.
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// CreateFacet returns a Facet of the given dimension from the given
+// count of each value. Counts are ordered from largest to smallest.
+func CreateFacet(dimension string, counts map[string]int) Facet {
+	result := Facet{
+		Dimension: dimension,
+		Counts:    make([]FacetCount, 0, len(counts)),
+	}
+
+	for value, count := range counts {
+		result.Counts = append(result.Counts, FacetCount{Value: value, Count: count})
+	}
+
+	sort.Slice(result.Counts, func(i, j int) bool {
+		if result.Counts[i].Count == result.Counts[j].Count {
+			return result.Counts[i].Value < result.Counts[j].Value
+		}
+		return result.Counts[j].Count < result.Counts[i].Count
+	})
+
+	return result
+}
+
+// ParseDimensions returns the comma-separated dimensions of the given
+// parameter, and it returns an error for any dimension that isn't
+// one of the given valid dimensions. Label dimensions are valid only
+// if DimensionLabel is.
+func ParseDimensions(param string, valid ...string) ([]string, error) {
+	var dimensions []string
+
+	for _, d := range strings.Split(param, ",") {
+		d = strings.TrimSpace(d)
+
+		if d == "" {
+			continue
+		}
+
+		if !isValidDimension(d, valid) {
+			return nil, fmt.Errorf("invalid facet dimension %q", d)
+		}
+
+		dimensions = append(dimensions, d)
+	}
+
+	return dimensions, nil
+}
+
+func isValidDimension(dimension string, valid []string) bool {
+	for _, v := range valid {
+		if dimension == v || (v == DimensionLabel && strings.HasPrefix(dimension, labelDimensionPrefix) && len(dimension) > len(labelDimensionPrefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+// createFacets counts the given objects by each of the given
+// dimensions.
+func createFacets(dimensions []string, objects []finder.K8sObject) (results []Facet) {
+	for _, d := range dimensions {
+		counts := make(map[string]int)
+
+		for _, o := range objects {
+			for _, value := range dimensionValues(d, o) {
+				counts[value]++
+			}
+		}
+
+		results = append(results, CreateFacet(d, counts))
+	}
+	return
+}
+
+// dimensionValues returns every value of the given dimension of the
+// given object, and an object is counted once per value.
+func dimensionValues(dimension string, object finder.K8sObject) (values []string) {
+	if dimension == DimensionKind {
+		return []string{object.Key.K8sResourceKind}
+	}
+
+	accessor, err := meta.Accessor(object.Item)
+
+	if err != nil {
+		return
+	}
+
+	switch {
+	case dimension == DimensionNamespace:
+		if accessor.GetNamespace() != "" {
+			values = append(values, accessor.GetNamespace())
+		}
+	case dimension == DimensionLabel:
+		for key := range accessor.GetLabels() {
+			values = append(values, key)
+		}
+	case strings.HasPrefix(dimension, labelDimensionPrefix):
+		if value, ok := accessor.GetLabels()[strings.TrimPrefix(dimension, labelDimensionPrefix)]; ok {
+			values = append(values, value)
+		}
+	case dimension == DimensionOwnerKind:
+		for _, owner := range accessor.GetOwnerReferences() {
+			values = append(values, owner.Kind)
+		}
+	case dimension == DimensionNode:
+		if pod, ok := object.Item.(*corev1.Pod); ok && pod.Spec.NodeName != "" {
+			values = append(values, pod.Spec.NodeName)
+		}
+	}
+
+	return
+}
+
+// countFacets finds the objects of every given posting, i.e., the
+// full hit set, and then it counts them by each given dimension.
+func countFacets(findAll finder.FindAllFunc, dimensions []string, postings []index.Posting) []Facet {
+	objects, err := findAll(createKeysFromPostings(postings))
+
+	if err != nil {
+		klog.Errorln(err)
+	}
+
+	return createFacets(dimensions, objects)
+}
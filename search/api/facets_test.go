@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/kubideh/kubesearch/search/finder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCreateFacets(t *testing.T) {
+	objects := []finder.K8sObject{
+		testFacetPod("blargle", "flargle", "node-a", map[string]string{"app": "nginx", "tier": "web"}),
+		testFacetPod("foo", "flargle", "node-b", map[string]string{"app": "nginx"}),
+		testFacetPod("bar", "bobble", "", map[string]string{"app": "redis"}),
+	}
+
+	facets := createFacets([]string{DimensionKind, DimensionNamespace, DimensionLabel, "label:app", DimensionOwnerKind, DimensionNode}, objects)
+
+	assert.Equal(t, []Facet{
+		{Dimension: DimensionKind, Counts: []FacetCount{{Value: "Pod", Count: 3}}},
+		{Dimension: DimensionNamespace, Counts: []FacetCount{{Value: "flargle", Count: 2}, {Value: "bobble", Count: 1}}},
+		{Dimension: DimensionLabel, Counts: []FacetCount{{Value: "app", Count: 3}, {Value: "tier", Count: 1}}},
+		{Dimension: "label:app", Counts: []FacetCount{{Value: "nginx", Count: 2}, {Value: "redis", Count: 1}}},
+		{Dimension: DimensionOwnerKind, Counts: []FacetCount{{Value: "ReplicaSet", Count: 3}}},
+		{Dimension: DimensionNode, Counts: []FacetCount{{Value: "node-a", Count: 1}, {Value: "node-b", Count: 1}}},
+	}, facets)
+}
+
+func TestParseDimensions(t *testing.T) {
+	dimensions, err := ParseDimensions("kind, label:app,,namespace", DimensionKind, DimensionNamespace, DimensionLabel)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{DimensionKind, "label:app", DimensionNamespace}, dimensions)
+
+	for _, invalid := range []string{"flargle", "label:", "node"} {
+		_, err := ParseDimensions(invalid, DimensionKind, DimensionLabel)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestSearchV2_facetsCountEveryHit(t *testing.T) {
+	server, cancel := setup(t)
+	defer server.Close()
+	defer cancel()
+
+	response, err := SearchV2WithClient(context.Background(), http.DefaultClient, server.URL, "flargle", Options{Limit: 1, Facets: []string{DimensionNamespace}})
+
+	require.NoError(t, err)
+	assert.Len(t, response.Results, 1)
+	assert.Equal(t, []Facet{
+		{Dimension: DimensionNamespace, Counts: []FacetCount{{Value: "flargle", Count: 2}}},
+	}, response.Facets)
+}
+
+func testFacetPod(name, namespace, node string, labels map[string]string) finder.K8sObject {
+	return finder.K8sObject{
+		Key: finder.Key{StoredObjectKey: namespace + "/" + name, K8sResourceKind: "Pod"},
+		Item: &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name,
+				Namespace:       namespace,
+				Labels:          labels,
+				OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "flargle"}},
+			},
+			Spec: corev1.PodSpec{NodeName: node},
+		},
+	}
+}
@@ -3,18 +3,23 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
+	"time"
 
+	"github.com/kubideh/kubesearch/search/auth"
 	"github.com/kubideh/kubesearch/search/finder"
 	"github.com/kubideh/kubesearch/search/index"
+	"github.com/kubideh/kubesearch/search/metrics"
 	"github.com/kubideh/kubesearch/search/searcher"
 	"k8s.io/klog/v2"
 )
 
 const (
-	endpointPath   = "/v1/search"
-	queryParamName = "queryString"
+	endpointPath     = "/v1/search"
+	queryParamName   = "queryString"
+	explainParamName = "explain"
 )
 
 // RegisterSearchHandler registers the search API handler with the given mux
@@ -24,11 +29,68 @@ func RegisterSearchHandler(mux *http.ServeMux, handler http.HandlerFunc) {
 }
 
 // CreateSearchHandler is a `http.HandlerFunc` that responds with a
-// list of JSON-encoded results based on the given query string.
-func CreateSearchHandler(search searcher.SearchFunc, findAll finder.FindAllFunc) http.HandlerFunc {
+// list of JSON-encoded results based on the given query string. If
+// the request has `explain=true`, then each result explains how it
+// matched the query using the given explain functor.
+func CreateSearchHandler(search searcher.SearchFunc, findAll finder.FindAllFunc, explain searcher.ExplainFunc) http.HandlerFunc {
+	return CreateFilteredSearchHandler(search, findAll, explain, unfiltered)
+}
+
+// CreateFilteredSearchHandler is the same as CreateSearchHandler,
+// but the given filter removes postings that the caller may not see
+// before any objects are found.
+func CreateFilteredSearchHandler(search searcher.SearchFunc, findAll finder.FindAllFunc, explain searcher.ExplainFunc, filter auth.FilterFunc) http.HandlerFunc {
+	find := createFindFunc(search, findAll, filter)
+
 	return func(writer http.ResponseWriter, request *http.Request) {
-		postings := search(queryString(request))
+		query := queryString(request)
+		objects, postings := find(request.Context(), query, ParseNamespaces(request), selectAll)
+		found := createResults(objects, postings)
 
+		for i, e := range explanations(request.URL.Query().Get(explainParamName) == "true", explain, query, postings[:len(found)]) {
+			found[i].Explanation = e
+		}
+
+		start := time.Now()
+		writeResults(writer, found)
+		metrics.ObserveQueryPhase(metrics.PhaseEncode, start)
+	}
+}
+
+// ResultsFunc returns the results of the given query that the caller
+// carried by ctx may see.
+type ResultsFunc func(ctx context.Context, query string) []Result
+
+// CreateResultsFunc returns the default ResultsFunc, which is shared
+// by every API that returns search results.
+func CreateResultsFunc(search searcher.SearchFunc, findAll finder.FindAllFunc, filter auth.FilterFunc) ResultsFunc {
+	find := createFindFunc(search, findAll, filter)
+
+	return func(ctx context.Context, query string) []Result {
+		objects, postings := find(ctx, query, nil, selectAll)
+		return createResults(objects, postings)
+	}
+}
+
+// findFunc returns the objects, and their postings, that match the
+// given query, that are in any of the given namespaces (or in any
+// namespace if none is given) and that the caller carried by ctx may
+// see. Only the postings chosen by the given selector are found.
+type findFunc func(ctx context.Context, query string, namespaces []string, selector selectFunc) ([]finder.K8sObject, []index.Posting)
+
+// selectFunc chooses which of the given postings to find, e.g., a
+// single page of them.
+type selectFunc func(postings []index.Posting) []index.Posting
+
+func selectAll(postings []index.Posting) []index.Posting {
+	return postings
+}
+
+func createFindFunc(search searcher.SearchFunc, findAll finder.FindAllFunc, filter auth.FilterFunc) findFunc {
+	return func(ctx context.Context, query string, namespaces []string, selector selectFunc) ([]finder.K8sObject, []index.Posting) {
+		postings := selector(filter(ctx, RetainNamespaces(search(query), namespaces)))
+
+		start := time.Now()
 		keys := createKeysFromPostings(postings)
 		objects, err := findAll(keys)
 
@@ -36,11 +98,47 @@ func CreateSearchHandler(search searcher.SearchFunc, findAll finder.FindAllFunc)
 			klog.Errorln(err)
 		}
 
-		results := createResults(objects, postings)
-		writeResults(writer, results)
+		metrics.ObserveQueryPhase(metrics.PhaseFind, start)
+		metrics.ObserveQueryResults(len(objects))
+
+		return objects, foundPostings(postings, objects)
 	}
 }
 
+// existing returns the given postings whose objects are found, so
+// that objects that are missing, e.g., because they were deleted
+// after they were searched, don't shorten a page.
+func existing(findAll finder.FindAllFunc, postings []index.Posting) []index.Posting {
+	objects, err := findAll(createKeysFromPostings(postings))
+
+	if err != nil {
+		klog.Errorln(err)
+	}
+
+	return foundPostings(postings, objects)
+}
+
+// foundPostings returns the postings of the given found objects, so
+// that the objects and the postings of the results line up after
+// the objects that are missing were skipped.
+func foundPostings(postings []index.Posting, objects []finder.K8sObject) []index.Posting {
+	result := make([]index.Posting, 0, len(objects))
+
+	for i := 0; i < len(postings) && len(result) < len(objects); i++ {
+		object := objects[len(result)]
+
+		if postings[i].StoredObjectKey == object.Key.StoredObjectKey && postings[i].K8sResourceKind == object.Key.K8sResourceKind {
+			result = append(result, postings[i])
+		}
+	}
+
+	return result
+}
+
+func unfiltered(_ context.Context, postings []index.Posting) []index.Posting {
+	return postings
+}
+
 func queryString(request *http.Request) string {
 	values, ok := request.URL.Query()[queryParamName]
 
@@ -51,6 +149,21 @@ func queryString(request *http.Request) string {
 	return values[0]
 }
 
+// explanations returns an explanation of each of the given postings
+// if enabled is true, and nil otherwise.
+func explanations(enabled bool, explain searcher.ExplainFunc, query string, postings []index.Posting) (results []*searcher.Explanation) {
+	if !enabled {
+		return
+	}
+
+	for _, p := range postings {
+		e := explain(query, p)
+		results = append(results, &e)
+	}
+
+	return
+}
+
 func createKeysFromPostings(postings []index.Posting) []finder.Key {
 	keys := make([]finder.Key, 0, len(postings))
 
@@ -74,12 +187,12 @@ func createKeyFromPosting(p index.Posting) finder.Key {
 	}
 }
 
-func writeResults(writer http.ResponseWriter, objects []Result) {
+func writeResults(writer http.ResponseWriter, results interface{}) {
 	writer.Header().Set("Content-Type", "application/json; charset=utf-8")
 
 	encoder := json.NewEncoder(writer)
 
-	if err := encoder.Encode(objects); err != nil {
+	if err := encoder.Encode(results); err != nil {
 		klog.Warningln("error marshaling result: ", err)
 	}
 }
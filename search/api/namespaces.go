@@ -0,0 +1,65 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/kubideh/kubesearch/search/index"
+)
+
+const namespaceParamName = "namespace"
+
+// ParseNamespaces returns the sorted namespaces given by the
+// parameter `namespace`, which may be repeated or comma-separated.
+// It's empty if every namespace is searched.
+func ParseNamespaces(request *http.Request) []string {
+	return normalizeNamespaces(request.URL.Query()[namespaceParamName])
+}
+
+// normalizeNamespaces returns the sorted, distinct namespaces of the
+// given values, each of which may be comma-separated.
+func normalizeNamespaces(values []string) (result []string) {
+	seen := make(map[string]bool)
+
+	for _, value := range values {
+		for _, namespace := range strings.Split(value, ",") {
+			namespace = strings.TrimSpace(namespace)
+
+			if namespace != "" && !seen[namespace] {
+				seen[namespace] = true
+				result = append(result, namespace)
+			}
+		}
+	}
+
+	sort.Strings(result)
+
+	return
+}
+
+// RetainNamespaces returns the given postings of objects in any of
+// the given namespaces, or every posting if no namespace is given.
+// Cluster-scoped objects are in no namespace, so they are removed
+// the same as `kubectl get --namespace` leaves them out.
+func RetainNamespaces(postings []index.Posting, namespaces []string) []index.Posting {
+	if len(namespaces) == 0 {
+		return postings
+	}
+
+	retained := make(map[string]bool, len(namespaces))
+
+	for _, n := range namespaces {
+		retained[n] = true
+	}
+
+	result := make([]index.Posting, 0, len(postings))
+
+	for _, p := range postings {
+		if retained[p.Namespace()] {
+			result = append(result, p)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kubideh/kubesearch/search/index"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNamespaces(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, endpointPathV2+"?namespace=flargle,bobble&namespace=flargle&namespace=", nil)
+
+	assert.Equal(t, []string{"bobble", "flargle"}, ParseNamespaces(request))
+	assert.Empty(t, ParseNamespaces(httptest.NewRequest(http.MethodGet, endpointPathV2, nil)))
+}
+
+func TestRetainNamespaces(t *testing.T) {
+	postings := []index.Posting{
+		{StoredObjectKey: "flargle/blargle", K8sResourceKind: "Pod"},
+		{StoredObjectKey: "bobble/foo", K8sResourceKind: "Pod"},
+		{StoredObjectKey: "blargle", K8sResourceKind: "Node"},
+	}
+
+	assert.Equal(t, postings, RetainNamespaces(postings, nil))
+	assert.Equal(t, []index.Posting{postings[0]}, RetainNamespaces(postings, []string{"flargle"}))
+	assert.Empty(t, RetainNamespaces(postings, []string{"default"}))
+}
+
+func TestSearchV2_namespaces(t *testing.T) {
+	server, cancel := setup(t)
+	defer server.Close()
+	defer cancel()
+
+	response, err := SearchV2WithClient(context.Background(), http.DefaultClient, server.URL, "blargle", Options{Namespaces: []string{"flargle"}, Facets: []string{DimensionNamespace}})
+
+	require.NoError(t, err)
+	require.Len(t, response.Results, 1)
+	assert.Equal(t, 1, response.Metadata.TotalHits)
+	assert.Equal(t, []Facet{{Dimension: DimensionNamespace, Counts: []FacetCount{{Value: "flargle", Count: 1}}}}, response.Facets)
+
+	response, err = SearchV2WithClient(context.Background(), http.DefaultClient, server.URL, "blargle", Options{Namespaces: []string{"bobble"}, Facets: []string{DimensionNamespace}})
+
+	require.NoError(t, err)
+	assert.Empty(t, response.Results)
+	assert.Equal(t, 0, response.Metadata.TotalHits)
+	assert.Empty(t, response.Facets[0].Counts)
+}
+
+func TestParsePageRequest_namespacesMustMatchTheContinueToken(t *testing.T) {
+	token := encodeCursor(&cursor{Query: hashQuery("flargle", nil, []string{"flargle"})})
+
+	_, err := parsePageRequest(httptest.NewRequest(http.MethodGet, endpointPathV2+"?namespace=flargle&continue="+token, nil), "flargle", nil)
+	assert.NoError(t, err)
+
+	_, err = parsePageRequest(httptest.NewRequest(http.MethodGet, endpointPathV2+"?namespace=bobble&continue="+token, nil), "flargle", nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,251 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/kubideh/kubesearch/search/index"
+	"github.com/kubideh/kubesearch/search/searcher"
+)
+
+const (
+	limitParamName    = "limit"
+	continueParamName = "continue"
+	sortParamName     = "sort"
+)
+
+// ListMeta describes a page of results, and it mirrors the list
+// metadata of the Kubernetes API. TotalHits is an estimate, because
+// objects may be deleted before they are found.
+type ListMeta struct {
+	Continue           string `json:"continue,omitempty"`
+	RemainingItemCount *int64 `json:"remainingItemCount,omitempty"`
+	TotalHits          int    `json:"totalHits"`
+}
+
+// cursor is the position after which the next page of results
+// starts. Results are ordered by the requested sort fields, then by
+// rank and then by document ID, and a cursor holds all of those of
+// the last result of a page, so pages stay stable while objects are
+// added to or deleted from the index.
+type cursor struct {
+	Query string        `json:"q"`
+	Key   []index.Value `json:"k,omitempty"`
+	Rank  int           `json:"r"`
+	DocID string        `json:"d"`
+}
+
+// pageRequest selects a single page of sorted postings, and it
+// records what's needed to describe that page.
+type pageRequest struct {
+	query   string
+	limit   int
+	after   *cursor
+	by      []searcher.SortField
+	sortKey searcher.SortKeyFunc
+
+	total     int
+	remaining int
+	next      *cursor
+}
+
+func parsePageRequest(request *http.Request, query string, sortKey searcher.SortKeyFunc) (*pageRequest, error) {
+	options, err := parseOptions(request)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return createPageRequest(query, options, sortKey)
+}
+
+// createPageRequest returns the page of the given query that's
+// selected by the given options.
+func createPageRequest(query string, options Options, sortKey searcher.SortKeyFunc) (*pageRequest, error) {
+	by, err := searcher.ParseSort(options.Sort)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if options.Limit < 0 {
+		return nil, fmt.Errorf("invalid %s: %d", limitParamName, options.Limit)
+	}
+
+	result := &pageRequest{query: hashQuery(query, by, normalizeNamespaces(options.Namespaces)), limit: options.Limit, by: by, sortKey: sortKey}
+
+	if token := options.Continue; token != "" {
+		after, err := decodeCursor(token)
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s token: %w", continueParamName, err)
+		}
+
+		if after.Query != result.query || len(after.Key) != len(by) {
+			return nil, errors.New("the continue token was issued for a different query, sort order or namespace")
+		}
+
+		result.after = after
+	}
+
+	return result, nil
+}
+
+// selectPage sorts the given postings, and then it returns the page
+// that follows the requested cursor, if any. A limit of zero selects
+// every remaining posting.
+func (p *pageRequest) selectPage(postings []index.Posting) []index.Posting {
+	return p.selectExistingPage(postings, selectAll)
+}
+
+// selectExistingPage is the same as selectPage, but only the postings
+// kept by the given selector, e.g., those whose objects still exist,
+// are returned. It's given only the postings of the page, and then
+// those that follow them until the page is full, so the cost of a
+// page doesn't grow with the number of hits.
+func (p *pageRequest) selectExistingPage(postings []index.Posting, existing selectFunc) []index.Posting {
+	sorted := make([]cursor, 0, len(postings))
+	byDocID := make(map[string]index.Posting, len(postings))
+
+	for _, posting := range postings {
+		sorted = append(sorted, p.position(posting))
+		byDocID[posting.DocID().String()] = posting
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		return p.compare(sorted[i], sorted[j]) < 0
+	})
+
+	p.total = len(sorted)
+
+	end := 0
+
+	if p.after != nil {
+		end = sort.Search(len(sorted), func(i int) bool {
+			return p.compare(*p.after, sorted[i]) < 0
+		})
+	}
+
+	result := make([]index.Posting, 0)
+
+	for end < len(sorted) && (p.limit == 0 || len(result) < p.limit) {
+		n := len(sorted) - end
+
+		if p.limit > 0 && p.limit-len(result) < n {
+			n = p.limit - len(result)
+		}
+
+		candidates := make([]index.Posting, 0, n)
+
+		for _, c := range sorted[end : end+n] {
+			candidates = append(candidates, byDocID[c.DocID])
+		}
+
+		kept := existing(candidates)
+		p.total -= len(candidates) - len(kept)
+		result = append(result, kept...)
+		end += n
+	}
+
+	p.remaining = len(sorted) - end
+
+	if p.limit > 0 && p.remaining > 0 {
+		last := p.position(result[len(result)-1])
+		p.next = &last
+	}
+
+	return result
+}
+
+// position returns the position of the given posting in the sort
+// order, i.e., a cursor that points at the posting.
+func (p *pageRequest) position(posting index.Posting) cursor {
+	result := cursor{
+		Query: p.query,
+		Rank:  posting.TermFrequency,
+		DocID: posting.DocID().String(),
+	}
+
+	if len(p.by) > 0 {
+		result.Key = p.sortKey(posting, p.by)
+	}
+
+	return result
+}
+
+// compare orders the given positions by sort key, and then in the
+// order of PostingsList, i.e., by largest rank and then by DocID.
+func (p *pageRequest) compare(a, b cursor) int {
+	if result := searcher.CompareSortKeys(a.Key, b.Key, p.by); result != 0 {
+		return result
+	}
+
+	if a.Rank != b.Rank {
+		if b.Rank < a.Rank {
+			return -1
+		}
+		return 1
+	}
+
+	return strings.Compare(a.DocID, b.DocID)
+}
+
+func (p *pageRequest) listMeta() ListMeta {
+	result := ListMeta{TotalHits: p.total}
+
+	if p.next != nil {
+		remaining := int64(p.remaining)
+		result.Continue = encodeCursor(p.next)
+		result.RemainingItemCount = &remaining
+	}
+
+	return result
+}
+
+// hashQuery returns a short hash of the given query, sort order and
+// namespaces, so that a continue token is used only with the same
+// query, sort order and namespaces.
+func hashQuery(query string, by []searcher.SortField, namespaces []string) string {
+	hash := sha256.New()
+	hash.Write([]byte(query))
+
+	for _, f := range by {
+		hash.Write([]byte{0})
+		hash.Write([]byte(f.String()))
+	}
+
+	for _, n := range namespaces {
+		hash.Write([]byte{1})
+		hash.Write([]byte(n))
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)[:8])
+}
+
+func encodeCursor(c *cursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(token string) (*cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := &cursor{}
+
+	if err := json.Unmarshal(data, result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
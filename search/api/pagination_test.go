@@ -0,0 +1,205 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kubideh/kubesearch/search/finder"
+	"github.com/kubideh/kubesearch/search/index"
+	"github.com/kubideh/kubesearch/search/searcher"
+	"github.com/kubideh/kubesearch/search/tokenizer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectPage(t *testing.T) {
+	postings := []index.Posting{
+		{StoredObjectKey: "flargle/c", K8sResourceKind: "Pod", TermFrequency: 1},
+		{StoredObjectKey: "flargle/a", K8sResourceKind: "Pod", TermFrequency: 1},
+		{StoredObjectKey: "flargle/b", K8sResourceKind: "Pod", TermFrequency: 2},
+	}
+
+	first := &pageRequest{query: hashQuery("flargle", nil, nil), limit: 2}
+
+	assert.Equal(t, []index.Posting{postings[2], postings[1]}, first.selectPage(postings))
+
+	meta := first.listMeta()
+	assert.Equal(t, 3, meta.TotalHits)
+	require.NotEmpty(t, meta.Continue)
+	assert.Equal(t, int64(1), *meta.RemainingItemCount)
+
+	second := pageRequestFromToken(t, meta.Continue, 2)
+
+	assert.Equal(t, []index.Posting{postings[0]}, second.selectPage(postings))
+	assert.Empty(t, second.listMeta().Continue)
+}
+
+func TestSelectPage_stableWhileTheIndexChanges(t *testing.T) {
+	postings := []index.Posting{
+		{StoredObjectKey: "flargle/a", K8sResourceKind: "Pod", TermFrequency: 1},
+		{StoredObjectKey: "flargle/b", K8sResourceKind: "Pod", TermFrequency: 1},
+		{StoredObjectKey: "flargle/c", K8sResourceKind: "Pod", TermFrequency: 1},
+	}
+
+	first := &pageRequest{query: hashQuery("flargle", nil, nil), limit: 2}
+	first.selectPage(postings)
+
+	// "a" was deleted, and "0" was added before the cursor.
+	changed := []index.Posting{
+		{StoredObjectKey: "flargle/0", K8sResourceKind: "Pod", TermFrequency: 1},
+		postings[1],
+		postings[2],
+	}
+
+	second := pageRequestFromToken(t, first.listMeta().Continue, 2)
+
+	assert.Equal(t, []index.Posting{postings[2]}, second.selectPage(changed))
+}
+
+func TestSelectExistingPage(t *testing.T) {
+	postings := []index.Posting{
+		{StoredObjectKey: "flargle/a", K8sResourceKind: "Pod", TermFrequency: 1},
+		{StoredObjectKey: "flargle/b", K8sResourceKind: "Pod", TermFrequency: 1},
+		{StoredObjectKey: "flargle/c", K8sResourceKind: "Pod", TermFrequency: 1},
+		{StoredObjectKey: "flargle/d", K8sResourceKind: "Pod", TermFrequency: 1},
+		{StoredObjectKey: "flargle/e", K8sResourceKind: "Pod", TermFrequency: 1},
+	}
+
+	var checked [][]index.Posting
+
+	// "b" was deleted after it was searched.
+	existing := func(candidates []index.Posting) (result []index.Posting) {
+		checked = append(checked, candidates)
+
+		for _, p := range candidates {
+			if p.StoredObjectKey != "flargle/b" {
+				result = append(result, p)
+			}
+		}
+		return
+	}
+
+	page := &pageRequest{query: hashQuery("flargle", nil, nil), limit: 2}
+
+	assert.Equal(t, []index.Posting{postings[0], postings[2]}, page.selectExistingPage(postings, existing))
+	assert.Equal(t, [][]index.Posting{postings[0:2], postings[2:3]}, checked, "only the page, and then the posting that tops it up, are checked")
+
+	meta := page.listMeta()
+	assert.Equal(t, 4, meta.TotalHits)
+	assert.Equal(t, int64(2), *meta.RemainingItemCount)
+
+	checked = nil
+	second := pageRequestFromToken(t, meta.Continue, 2)
+
+	assert.Equal(t, []index.Posting{postings[3], postings[4]}, second.selectExistingPage(postings, existing))
+	assert.Equal(t, [][]index.Posting{postings[3:5]}, checked)
+	assert.Empty(t, second.listMeta().Continue)
+}
+
+func TestSelectPage_noLimit(t *testing.T) {
+	postings := []index.Posting{
+		{StoredObjectKey: "flargle/a", K8sResourceKind: "Pod", TermFrequency: 1},
+	}
+
+	page := &pageRequest{query: hashQuery("flargle", nil, nil)}
+
+	assert.Equal(t, postings, page.selectPage(postings))
+	assert.Equal(t, ListMeta{TotalHits: 1}, page.listMeta())
+}
+
+func TestSelectPage_sortedByDocValues(t *testing.T) {
+	postings := []index.Posting{
+		{StoredObjectKey: "flargle/a", K8sResourceKind: "Pod", TermFrequency: 2},
+		{StoredObjectKey: "flargle/b", K8sResourceKind: "Pod", TermFrequency: 1},
+		{StoredObjectKey: "flargle/c", K8sResourceKind: "Pod", TermFrequency: 1},
+	}
+
+	created := map[string]int64{"Pod/flargle/a": 1, "Pod/flargle/b": 3, "Pod/flargle/c": 2}
+
+	sortKey := func(posting index.Posting, by []searcher.SortField) []index.Value {
+		return []index.Value{index.NumberValue(created[posting.DocID().String()])}
+	}
+
+	by := []searcher.SortField{{Field: index.DocValueCreationTimestamp, Descending: true}}
+	first := &pageRequest{query: hashQuery("flargle", by, nil), limit: 2, by: by, sortKey: sortKey}
+
+	assert.Equal(t, []index.Posting{postings[1], postings[2]}, first.selectPage(postings))
+
+	after, err := decodeCursor(first.listMeta().Continue)
+	require.NoError(t, err)
+
+	second := &pageRequest{query: after.Query, limit: 2, after: after, by: by, sortKey: sortKey}
+
+	assert.Equal(t, []index.Posting{postings[0]}, second.selectPage(postings))
+}
+
+func TestParsePageRequest_sortMustMatchTheContinueToken(t *testing.T) {
+	token := encodeCursor(&cursor{Query: hashQuery("flargle", nil, nil)})
+	request := httptest.NewRequest(http.MethodGet, endpointPathV2+"?sort=-name&continue="+token, nil)
+
+	_, err := parsePageRequest(request, "flargle", nil)
+
+	assert.Error(t, err)
+}
+
+func TestParsePageRequest_invalid(t *testing.T) {
+	token := encodeCursor(&cursor{Query: hashQuery("blargle", nil, nil)})
+
+	for _, rawQuery := range []string{"limit=-1", "limit=flargle", "continue=%25%25", "continue=" + token, "sort=flargle"} {
+		request := httptest.NewRequest(http.MethodGet, endpointPathV2+"?"+rawQuery, nil)
+
+		_, err := parsePageRequest(request, "flargle", nil)
+
+		assert.Error(t, err, rawQuery)
+	}
+}
+
+func TestSearchV2_pages(t *testing.T) {
+	server, cancel := setup(t)
+	defer server.Close()
+	defer cancel()
+
+	first, err := SearchV2WithClient(context.Background(), http.DefaultClient, server.URL, "flargle", Options{Limit: 1})
+
+	require.NoError(t, err)
+	require.Len(t, first.Results, 1)
+	assert.Equal(t, 2, first.Metadata.TotalHits)
+	require.NotEmpty(t, first.Metadata.Continue)
+
+	second, err := SearchV2WithClient(context.Background(), http.DefaultClient, server.URL, "flargle", Options{Limit: 1, Continue: first.Metadata.Continue})
+
+	require.NoError(t, err)
+	require.Len(t, second.Results, 1)
+	assert.Empty(t, second.Metadata.Continue)
+	assert.NotEqual(t, first.Results[0].Name, second.Results[0].Name)
+}
+
+func pageRequestFromToken(t *testing.T, token string, limit int) *pageRequest {
+	after, err := decodeCursor(token)
+	require.NoError(t, err)
+
+	return &pageRequest{query: after.Query, limit: limit, after: after}
+}
+
+func TestSearchV2_pageWithMissingObject(t *testing.T) {
+	idx, stores := createIndexWithMissingObject(t)
+	aTokenizer := tokenizer.Tokenizer()
+	handler := CreateSearchHandlerV2(searcher.Create(idx, aTokenizer), finder.Create(stores), searcher.CreateExplainer(idx, aTokenizer), searcher.CreateSortKeys(idx))
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	first, err := SearchV2WithClient(context.Background(), http.DefaultClient, server.URL, "flargle", Options{Limit: 2})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "c"}, resultNames(first.Results), "the missing object doesn't shorten the page")
+	assert.Equal(t, 3, first.Metadata.TotalHits)
+	require.NotEmpty(t, first.Metadata.Continue)
+
+	second, err := SearchV2WithClient(context.Background(), http.DefaultClient, server.URL, "flargle", Options{Limit: 2, Continue: first.Metadata.Continue})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"d"}, resultNames(second.Results))
+	assert.Empty(t, second.Metadata.Continue)
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"github.com/kubideh/kubesearch/search/finder"
 	"github.com/kubideh/kubesearch/search/index"
+	"github.com/kubideh/kubesearch/search/searcher"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 )
@@ -13,6 +14,8 @@ type Result struct {
 	Name      string `json:"name,omitempty"`
 	Namespace string `json:"namespaces,omitempty"`
 	Rank      int    `json:"rank,omitempty"`
+
+	Explanation *searcher.Explanation `json:"explanation,omitempty"`
 }
 
 func createResults(objects []finder.K8sObject, postings []index.Posting) (results []Result) {
@@ -0,0 +1,278 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kubideh/kubesearch/search/controller"
+	"github.com/kubideh/kubesearch/search/finder"
+	"github.com/kubideh/kubesearch/search/index"
+	"github.com/kubideh/kubesearch/search/searcher"
+	"github.com/kubideh/kubesearch/search/tokenizer"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+)
+
+// Fields of Kubernetes objects that may match a query. Labels match
+// as FieldLabelPrefix followed by their key, and FieldImage is the
+// image of any container.
+const (
+	FieldName        = "metadata.name"
+	FieldNamespace   = "metadata.namespace"
+	FieldLabelPrefix = "metadata.labels."
+	FieldImage       = "image"
+)
+
+// Tags that surround each matched term in highlighted snippets.
+const (
+	HighlightPreTag  = "<em>"
+	HighlightPostTag = "</em>"
+)
+
+// Highlight shows why a field matched a query. Snippet is the value
+// of the field with each matched term surrounded by highlight tags,
+// and Matches are the offsets of those terms within Value.
+type Highlight struct {
+	Field   string  `json:"field"`
+	Value   string  `json:"value"`
+	Snippet string  `json:"snippet"`
+	Matches []Match `json:"matches"`
+}
+
+// Match is the byte offsets of a matched term within a field value.
+type Match struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// ResultV2 is a single result entry of the v2 response schema.
+type ResultV2 struct {
+	APIVersion        string                  `json:"apiVersion,omitempty"`
+	Kind              string                  `json:"kind,omitempty"`
+	Name              string                  `json:"name,omitempty"`
+	Namespace         string                  `json:"namespace,omitempty"`
+	UID               types.UID               `json:"uid,omitempty"`
+	Labels            map[string]string       `json:"labels,omitempty"`
+	CreationTimestamp metav1.Time             `json:"creationTimestamp,omitempty"`
+	OwnerReferences   []metav1.OwnerReference `json:"ownerReferences,omitempty"`
+	MatchedFields     []string                `json:"matchedFields,omitempty"`
+	Highlights        []Highlight             `json:"highlights,omitempty"`
+	Rank              int                     `json:"rank,omitempty"`
+	Command           string                  `json:"command,omitempty"`
+	Explanation       *searcher.Explanation   `json:"explanation,omitempty"`
+}
+
+// CreateResultV2 returns the result of the given object of the given
+// posting, with the terms and field clauses of the given query
+// highlighted.
+func CreateResultV2(posting index.Posting, item interface{}, query searcher.Query) ResultV2 {
+	objects := []finder.K8sObject{{Key: createKeyFromPosting(posting), Item: item}}
+	return createResultsV2(objects, []index.Posting{posting}, query, tokenizer.TokenizerWithOffsets())[0]
+}
+
+func createResultsV2(objects []finder.K8sObject, postings []index.Posting, query searcher.Query, tokenize tokenizer.TokenizeWithOffsetsFunc) (results []ResultV2) {
+	for i, o := range objects {
+		result := createResultV2(postings[i].K8sResourceKind, o.Item, postings[i].TermFrequency)
+		result.Highlights = append(highlights(result, query.Terms, tokenize), fieldHighlights(result, o.Item, query.Fields)...)
+		result.MatchedFields = matchedFields(result.Highlights)
+		results = append(results, result)
+	}
+	return
+}
+
+func createResultV2(kind string, item interface{}, termFrequency int) ResultV2 {
+	result := ResultV2{
+		APIVersion: apiVersion(kind, item),
+		Kind:       kind,
+		Rank:       termFrequency,
+	}
+
+	object, err := meta.Accessor(item)
+
+	if err != nil {
+		klog.Warningln("error reading object metadata: ", err)
+		return result
+	}
+
+	result.Name = object.GetName()
+	result.Namespace = object.GetNamespace()
+	result.UID = object.GetUID()
+	result.Labels = object.GetLabels()
+	result.CreationTimestamp = object.GetCreationTimestamp()
+	result.OwnerReferences = object.GetOwnerReferences()
+	result.Command = command(kind, result.Name, result.Namespace)
+
+	return result
+}
+
+// apiVersion returns the API version of the given indexed kind.
+// Objects returned by informers have no TypeMeta, so it's read from
+// the given object only if the kind isn't indexed by Controllers,
+// e.g., objects decoded from local manifests.
+func apiVersion(kind string, item interface{}) string {
+	for _, r := range controller.IndexedResources() {
+		if r.Kind == kind {
+			return r.GroupVersionResource.GroupVersion().String()
+		}
+	}
+
+	if object, ok := item.(runtime.Object); ok {
+		return object.GetObjectKind().GroupVersionKind().GroupVersion().String()
+	}
+
+	return ""
+}
+
+// command returns a kubectl command that gets the given object.
+func command(kind, name, namespace string) string {
+	if namespace == "" {
+		return fmt.Sprintf("kubectl get %s %s", strings.ToLower(kind), name)
+	}
+	return fmt.Sprintf("kubectl get %s %s -n %s", strings.ToLower(kind), name, namespace)
+}
+
+// highlights returns a Highlight for each indexed field of the given
+// result that contains any of the given query terms.
+func highlights(result ResultV2, terms []string, tokenize tokenizer.TokenizeWithOffsetsFunc) (results []Highlight) {
+	fields := []struct {
+		path  string
+		value string
+	}{
+		{path: FieldName, value: result.Name},
+		{path: FieldNamespace, value: result.Namespace},
+	}
+
+	for _, f := range fields {
+		matches := match(tokenize(f.value), terms)
+
+		if len(matches) == 0 {
+			continue
+		}
+
+		results = append(results, Highlight{
+			Field:   f.path,
+			Value:   f.value,
+			Snippet: snippet(f.value, matches),
+			Matches: matches,
+		})
+	}
+
+	return
+}
+
+func match(tokens []tokenizer.Token, terms []string) (matches []Match) {
+	for _, token := range tokens {
+		if contains(terms, token.Term) {
+			matches = append(matches, Match{Start: token.Start, End: token.End})
+		}
+	}
+	return
+}
+
+func contains(terms []string, term string) bool {
+	for _, t := range terms {
+		if t == term {
+			return true
+		}
+	}
+	return false
+}
+
+// snippet surrounds each of the given matches of value with tags.
+// The matches must be sorted and must not overlap.
+func snippet(value string, matches []Match) string {
+	var builder strings.Builder
+
+	last := 0
+
+	for _, m := range matches {
+		builder.WriteString(value[last:m.Start])
+		builder.WriteString(HighlightPreTag)
+		builder.WriteString(value[m.Start:m.End])
+		builder.WriteString(HighlightPostTag)
+		last = m.End
+	}
+
+	builder.WriteString(value[last:])
+
+	return builder.String()
+}
+
+// fieldHighlights returns a Highlight for the namespace, each label
+// and each container image of the given result that matches any of
+// the given field clauses. The whole value is highlighted. Clauses
+// that match objects without a label have nothing to highlight.
+func fieldHighlights(result ResultV2, item interface{}, clauses []searcher.FieldClause) (results []Highlight) {
+	terms := make([]string, 0, len(clauses))
+
+	for _, c := range clauses {
+		if !c.Absent {
+			terms = append(terms, c.Term)
+		}
+	}
+
+	if len(terms) == 0 {
+		return
+	}
+
+	if contains(terms, index.NamespaceTerm(result.Namespace)) {
+		results = append(results, wholeHighlight(FieldNamespace, result.Namespace))
+	}
+
+	keys := make([]string, 0, len(result.Labels))
+
+	for key := range result.Labels {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if containsAny(terms, index.LabelTerms(key, result.Labels[key])) {
+			results = append(results, wholeHighlight(FieldLabelPrefix+key, result.Labels[key]))
+		}
+	}
+
+	for _, image := range controller.Images(item) {
+		if containsAny(terms, index.ImageTerms(image)) {
+			results = append(results, wholeHighlight(FieldImage, image))
+		}
+	}
+
+	return
+}
+
+func wholeHighlight(field, value string) Highlight {
+	matches := []Match{{Start: 0, End: len(value)}}
+
+	return Highlight{
+		Field:   field,
+		Value:   value,
+		Snippet: snippet(value, matches),
+		Matches: matches,
+	}
+}
+
+func containsAny(terms []string, candidates []string) bool {
+	for _, c := range candidates {
+		if contains(terms, c) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchedFields returns the field of each of the given highlights,
+// once each.
+func matchedFields(highlights []Highlight) (fields []string) {
+	for _, h := range highlights {
+		if !contains(fields, h.Field) {
+			fields = append(fields, h.Field)
+		}
+	}
+	return
+}
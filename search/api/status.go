@@ -0,0 +1,32 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/kubideh/kubesearch/search/controller"
+	"k8s.io/klog/v2"
+)
+
+const statusEndpointPath = "/v1/status"
+
+// RegisterStatusHandler registers the status API handler with the
+// given mux at the appropriate endpoint path.
+func RegisterStatusHandler(mux *http.ServeMux, handler http.HandlerFunc) {
+	mux.HandleFunc(statusEndpointPath, handler)
+}
+
+// CreateStatusHandler is a `http.HandlerFunc` that responds with
+// the JSON-encoded indexing status of each kind of Kubernetes
+// object.
+func CreateStatusHandler(status controller.StatusFunc) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+		encoder := json.NewEncoder(writer)
+
+		if err := encoder.Encode(status()); err != nil {
+			klog.Warningln("error marshaling status: ", err)
+		}
+	}
+}
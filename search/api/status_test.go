@@ -0,0 +1,62 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kubideh/kubesearch/search/controller"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestStatus(t *testing.T) {
+	client := fake.NewSimpleClientset()
+
+	aController := controller.Create(client)
+	cancel := aController.Start()
+	defer cancel()
+
+	mux := http.NewServeMux()
+	RegisterStatusHandler(mux, CreateStatusHandler(aController.Status))
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	for _, p := range testPods() {
+		_, err := client.CoreV1().Pods(p.GetNamespace()).Create(context.TODO(), p, metav1.CreateOptions{})
+		require.NoError(t, err)
+	}
+
+	assert.Eventually(t, func() bool {
+		status := getStatus(t, server.URL)
+		return status.Ready && status.Kinds[1].ObjectCount == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	status := getStatus(t, server.URL)
+
+	require.Len(t, status.Kinds, 2)
+	assert.Equal(t, "Deployment", status.Kinds[0].Kind)
+	assert.True(t, status.Kinds[0].Synced)
+	assert.Zero(t, status.Kinds[0].ObjectCount)
+	assert.Nil(t, status.Kinds[0].LastEventTime)
+	assert.Equal(t, "Pod", status.Kinds[1].Kind)
+	assert.True(t, status.Kinds[1].Synced)
+	assert.Zero(t, status.Kinds[1].QueueDepth)
+	assert.NotNil(t, status.Kinds[1].LastEventTime)
+}
+
+func getStatus(t *testing.T, endpoint string) (status controller.Status) {
+	response, err := http.Get(endpoint + statusEndpointPath)
+	require.NoError(t, err)
+	defer response.Body.Close()
+
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&status))
+
+	return
+}
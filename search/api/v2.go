@@ -0,0 +1,158 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kubideh/kubesearch/search/auth"
+	"github.com/kubideh/kubesearch/search/finder"
+	"github.com/kubideh/kubesearch/search/index"
+	"github.com/kubideh/kubesearch/search/metrics"
+	"github.com/kubideh/kubesearch/search/searcher"
+	"github.com/kubideh/kubesearch/search/tokenizer"
+)
+
+const endpointPathV2 = "/v2/search"
+
+// ResponseV2 is the v2 response schema of the search API.
+type ResponseV2 struct {
+	Metadata ListMeta   `json:"metadata"`
+	Results  []ResultV2 `json:"results"`
+	Facets   []Facet    `json:"facets,omitempty"`
+}
+
+// RegisterSearchHandlerV2 registers the v2 search API handler with
+// the given mux at the appropriate endpoint path.
+func RegisterSearchHandlerV2(mux *http.ServeMux, handler http.HandlerFunc) {
+	mux.HandleFunc(endpointPathV2, handler)
+}
+
+// CreateSearchHandlerV2 is a `http.HandlerFunc` that responds with a
+// JSON-encoded ResponseV2 based on the given query string. Results
+// are sorted by relevance, or by the doc values given by the
+// parameter `sort` using the given sort key functor.
+func CreateSearchHandlerV2(search searcher.SearchFunc, findAll finder.FindAllFunc, explain searcher.ExplainFunc, sortKey searcher.SortKeyFunc) http.HandlerFunc {
+	return CreateFilteredSearchHandlerV2(search, findAll, explain, sortKey, unfiltered)
+}
+
+// CreateFilteredSearchHandlerV2 is the same as CreateSearchHandlerV2,
+// but the given filter removes postings that the caller may not see
+// before any objects are found.
+func CreateFilteredSearchHandlerV2(search searcher.SearchFunc, findAll finder.FindAllFunc, explain searcher.ExplainFunc, sortKey searcher.SortKeyFunc, filter auth.FilterFunc) http.HandlerFunc {
+	searchV2 := createFilteredSearchV2Func(search, findAll, explain, sortKey, filter)
+
+	return func(writer http.ResponseWriter, request *http.Request) {
+		options, err := parseOptions(request)
+
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		response, err := searchV2(request.Context(), queryString(request), options)
+
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		start := time.Now()
+		writeResults(writer, response)
+		metrics.ObserveQueryPhase(metrics.PhaseEncode, start)
+	}
+}
+
+// SearchV2Func returns the response of the v2 search API to the
+// given query and options, without a request being sent. An error
+// is returned if the query or options are invalid.
+type SearchV2Func func(ctx context.Context, query string, options Options) (ResponseV2, error)
+
+// CreateSearchV2Func returns a SearchV2Func that searches using the
+// given functors, e.g., for objects indexed in this process.
+func CreateSearchV2Func(search searcher.SearchFunc, findAll finder.FindAllFunc, explain searcher.ExplainFunc, sortKey searcher.SortKeyFunc) SearchV2Func {
+	return createFilteredSearchV2Func(search, findAll, explain, sortKey, unfiltered)
+}
+
+func createFilteredSearchV2Func(search searcher.SearchFunc, findAll finder.FindAllFunc, explain searcher.ExplainFunc, sortKey searcher.SortKeyFunc, filter auth.FilterFunc) SearchV2Func {
+	find := createFindFunc(search, findAll, filter)
+	tokenize := tokenizer.Tokenizer()
+	tokenizeWithOffsets := tokenizer.TokenizerWithOffsets()
+
+	return func(ctx context.Context, query string, options Options) (ResponseV2, error) {
+		parsed, err := searcher.ParseQuery(query, tokenize)
+
+		if err != nil {
+			return ResponseV2{}, err
+		}
+
+		page, err := createPageRequest(query, options, sortKey)
+
+		if err != nil {
+			return ResponseV2{}, err
+		}
+
+		dimensions, err := ParseDimensions(strings.Join(options.Facets, ","), DimensionKind, DimensionNamespace, DimensionLabel, DimensionOwnerKind, DimensionNode)
+
+		if err != nil {
+			return ResponseV2{}, err
+		}
+
+		var hits []index.Posting
+
+		objects, postings := find(ctx, query, normalizeNamespaces(options.Namespaces), func(postings []index.Posting) []index.Posting {
+			hits = postings
+			return page.selectExistingPage(postings, func(candidates []index.Posting) []index.Posting {
+				return existing(findAll, candidates)
+			})
+		})
+		found := createResultsV2(objects, postings, parsed, tokenizeWithOffsets)
+
+		for i, e := range explanations(options.Explain, explain, query, postings[:len(found)]) {
+			found[i].Explanation = e
+		}
+
+		if found == nil {
+			found = []ResultV2{}
+		}
+
+		var facets []Facet
+
+		if len(dimensions) > 0 {
+			facets = countFacets(findAll, dimensions, hits)
+		}
+
+		return ResponseV2{Metadata: page.listMeta(), Results: found, Facets: facets}, nil
+	}
+}
+
+// parseOptions returns the options given by the parameters of the
+// given request.
+func parseOptions(request *http.Request) (Options, error) {
+	values := request.URL.Query()
+	options := Options{
+		Explain:    values.Get(explainParamName) == "true",
+		Continue:   values.Get(continueParamName),
+		Sort:       values.Get(sortParamName),
+		Namespaces: ParseNamespaces(request),
+	}
+
+	if limit := values.Get(limitParamName); limit != "" {
+		n, err := strconv.Atoi(limit)
+
+		if err != nil || n < 0 {
+			return Options{}, fmt.Errorf("invalid %s: %q", limitParamName, limit)
+		}
+
+		options.Limit = n
+	}
+
+	if facets := values.Get(facetsParamName); facets != "" {
+		options.Facets = []string{facets}
+	}
+
+	return options, nil
+}
@@ -0,0 +1,243 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/kubideh/kubesearch/search/auth"
+	"github.com/kubideh/kubesearch/search/finder"
+	"github.com/kubideh/kubesearch/search/index"
+	"github.com/kubideh/kubesearch/search/searcher"
+	"github.com/kubideh/kubesearch/search/tokenizer"
+	"github.com/kubideh/kubesearch/search/watch"
+	"k8s.io/klog/v2"
+)
+
+const endpointPathWatchV2 = "/v2/watch"
+
+// Types of WatchEventV2, the same as those of the Kubernetes API.
+const (
+	EventAdded    = "ADDED"
+	EventModified = "MODIFIED"
+	EventDeleted  = "DELETED"
+	EventError    = "ERROR"
+)
+
+// ErrWatchEnded is returned when the server ends a watch, e.g.,
+// because the watch fell behind the changes to the index. Changes
+// may have been missed, so the query must be watched again.
+var ErrWatchEnded = errors.New("the watch was ended by the server")
+
+// WatchEventV2 is a change to the results of a query, and it mirrors
+// the watch events of the Kubernetes API. The Object of a deleted
+// result has only its kind, name and namespace. An error event is
+// the last event of a watch, and it has only an Error.
+type WatchEventV2 struct {
+	Type   string   `json:"type"`
+	Object ResultV2 `json:"object"`
+	Error  string   `json:"error,omitempty"`
+}
+
+// RegisterWatchHandlerV2 registers the v2 watch API handler with the
+// given mux at the appropriate endpoint path.
+func RegisterWatchHandlerV2(mux *http.ServeMux, handler http.HandlerFunc) {
+	mux.HandleFunc(endpointPathWatchV2, handler)
+}
+
+// CreateWatchHandlerV2 is a `http.HandlerFunc` that streams the
+// results of the given query as newline-delimited JSON-encoded
+// WatchEventV2s. The current results are streamed first as added,
+// and then each change to the results is streamed as the given
+// Registry delivers it, until the caller disconnects. If the
+// Registry cancels the watch, because it fell behind, then an error
+// event is streamed last.
+func CreateWatchHandlerV2(search searcher.SearchFunc, findAll finder.FindAllFunc, registry *watch.Registry) http.HandlerFunc {
+	return CreateFilteredWatchHandlerV2(search, findAll, registry, unfiltered)
+}
+
+// CreateFilteredWatchHandlerV2 is the same as CreateWatchHandlerV2,
+// but the given filter removes results, and changes, that the caller
+// may not see.
+func CreateFilteredWatchHandlerV2(search searcher.SearchFunc, findAll finder.FindAllFunc, registry *watch.Registry, filter auth.FilterFunc) http.HandlerFunc {
+	find := createFindFunc(search, findAll, filter)
+	tokenize := tokenizer.Tokenizer()
+	tokenizeWithOffsets := tokenizer.TokenizerWithOffsets()
+
+	return func(writer http.ResponseWriter, request *http.Request) {
+		query := queryString(request)
+		parsed, err := searcher.ParseQuery(query, tokenize)
+
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		flusher, ok := writer.(http.Flusher)
+
+		if !ok {
+			http.Error(writer, "streaming is not supported", http.StatusInternalServerError)
+			return
+		}
+
+		namespaces := ParseNamespaces(request)
+
+		// Subscribe before searching, so that no change is missed.
+		subscription := registry.Subscribe(parsed, namespaces)
+		defer registry.Cancel(subscription)
+
+		objects, postings := find(request.Context(), query, namespaces, selectAll)
+		registry.Seed(subscription, postings)
+
+		writer.Header().Set("Content-Type", "application/x-ndjson")
+		encoder := json.NewEncoder(writer)
+		sent := make(map[string]bool)
+
+		for i, r := range createResultsV2(objects, postings, parsed, tokenizeWithOffsets) {
+			sent[postings[i].DocID().String()] = true
+
+			if err := encoder.Encode(WatchEventV2{Type: EventAdded, Object: r}); err != nil {
+				klog.V(2).Infoln("error streaming watch event: ", err)
+				return
+			}
+		}
+
+		flusher.Flush()
+
+		for {
+			select {
+			case <-request.Context().Done():
+				return
+			case hit, ok := <-subscription.Hits():
+				if !ok {
+					if err := encoder.Encode(WatchEventV2{Type: EventError, Error: "the watch fell behind the changes to the index; watch again"}); err != nil {
+						klog.V(2).Infoln("error streaming watch event: ", err)
+					}
+
+					flusher.Flush()
+					return
+				}
+
+				event, ok := createWatchEvent(request.Context(), hit, sent, parsed, tokenizeWithOffsets, filter)
+
+				if !ok {
+					continue
+				}
+
+				if err := encoder.Encode(event); err != nil {
+					klog.V(2).Infoln("error streaming watch event: ", err)
+					return
+				}
+
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+// createWatchEvent returns the event of the given hit, and it
+// records in sent which results the caller has been sent. It returns
+// false if the caller may not see the object of the hit, or if the
+// caller was never sent the result that the hit removes.
+func createWatchEvent(ctx context.Context, hit watch.Hit, sent map[string]bool, query searcher.Query, tokenize tokenizer.TokenizeWithOffsetsFunc, filter auth.FilterFunc) (WatchEventV2, bool) {
+	posting := hit.Change.Posting
+	id := posting.DocID().String()
+
+	if len(filter(ctx, []index.Posting{posting})) == 0 {
+		return WatchEventV2{}, false
+	}
+
+	result := WatchEventV2{Type: EventAdded}
+
+	switch {
+	case hit.Matched && sent[id]:
+		result.Type = EventModified
+	case !hit.Matched && sent[id]:
+		result.Type = EventDeleted
+		delete(sent, id)
+	case !hit.Matched:
+		return WatchEventV2{}, false
+	}
+
+	if hit.Matched {
+		sent[id] = true
+	}
+
+	if hit.Change.Deleted() {
+		result.Object = createDeletedResultV2(posting)
+		return result, true
+	}
+
+	objects := []finder.K8sObject{{Key: createKeyFromPosting(posting), Item: hit.Change.Object}}
+	result.Object = createResultsV2(objects, []index.Posting{posting}, query, tokenize)[0]
+
+	return result, true
+}
+
+// createDeletedResultV2 returns a result that identifies the deleted
+// object of the given posting.
+func createDeletedResultV2(posting index.Posting) ResultV2 {
+	namespace, name := posting.Namespace(), posting.StoredObjectKey
+
+	if namespace != "" {
+		name = name[len(namespace)+1:]
+	}
+
+	return ResultV2{
+		APIVersion: apiVersion(posting.K8sResourceKind, nil),
+		Kind:       posting.K8sResourceKind,
+		Name:       name,
+		Namespace:  namespace,
+	}
+}
+
+// WatchV2WithClient streams the results of the given query, and then
+// each change to them, to the given function until ctx is done, the
+// server ends the stream or the function returns an error. It
+// returns ErrWatchEnded if the server ends the stream. Only the
+// Namespaces of the given options are used.
+func WatchV2WithClient(ctx context.Context, client *http.Client, endpoint, query string, options Options, handle func(event WatchEventV2) error) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, searchURL(endpoint, endpointPathWatchV2, query, Options{Namespaces: options.Namespaces}), nil)
+
+	if err != nil {
+		return err
+	}
+
+	response, err := client.Do(request)
+
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status from %s: %s", request.URL.Host, response.Status)
+	}
+
+	decoder := json.NewDecoder(bufio.NewReader(response.Body))
+
+	for {
+		var event WatchEventV2
+
+		if err := decoder.Decode(&event); ctx.Err() != nil {
+			return nil
+		} else if errors.Is(err, io.EOF) {
+			return ErrWatchEnded
+		} else if err != nil {
+			return err
+		}
+
+		if event.Type == EventError {
+			return fmt.Errorf("%w: %s", ErrWatchEnded, event.Error)
+		}
+
+		if err := handle(event); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kubideh/kubesearch/search/controller"
+	"github.com/kubideh/kubesearch/search/finder"
+	"github.com/kubideh/kubesearch/search/index"
+	"github.com/kubideh/kubesearch/search/searcher"
+	"github.com/kubideh/kubesearch/search/tokenizer"
+	"github.com/kubideh/kubesearch/search/watch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestWatchV2(t *testing.T) {
+	client := fake.NewSimpleClientset(testPodFlargleBlargle())
+
+	aController := controller.Create(client)
+	registry := watch.CreateRegistry()
+	aController.OnChange(registry.Notify)
+	cancel := aController.Start()
+	defer cancel()
+
+	mux := http.NewServeMux()
+	RegisterWatchHandlerV2(mux, CreateWatchHandlerV2(searcher.Create(aController.Index(), tokenizer.Tokenizer()), finder.Create(aController.Store()), registry))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	require.Eventually(t, aController.Ready, 5*time.Second, 10*time.Millisecond)
+
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+
+	events := make(chan WatchEventV2, 10)
+
+	go WatchV2WithClient(ctx, http.DefaultClient, server.URL, "flargle", Options{Namespaces: []string{"flargle"}}, func(event WatchEventV2) error {
+		events <- event
+		return nil
+	})
+
+	assertEvent(t, events, EventAdded, "blargle")
+
+	_, err := client.CoreV1().Pods("flargle").Create(context.TODO(), testPodFlargleFoo(), metav1.CreateOptions{})
+	require.NoError(t, err)
+	assertEvent(t, events, EventAdded, "foo")
+
+	_, err = client.CoreV1().Pods("bobble").Create(context.TODO(), &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "flargle", Namespace: "bobble"}}, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	updated := testPodFlargleFoo()
+	updated.Labels = map[string]string{"app": "foo"}
+	_, err = client.CoreV1().Pods("flargle").Update(context.TODO(), updated, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	assertEvent(t, events, EventModified, "foo")
+
+	require.NoError(t, client.CoreV1().Pods("flargle").Delete(context.TODO(), "blargle", metav1.DeleteOptions{}))
+	event := assertEvent(t, events, EventDeleted, "blargle")
+	assert.Equal(t, "flargle", event.Object.Namespace)
+	assert.Equal(t, "v1", event.Object.APIVersion)
+}
+
+func TestWatchV2_fallsBehind(t *testing.T) {
+	registry := watch.CreateRegistry()
+	subscribed := make(chan struct{})
+	release := make(chan struct{})
+	calls := 0
+
+	// The filter blocks the handler after it subscribes, so that the
+	// subscription falls behind.
+	filter := func(_ context.Context, postings []index.Posting) []index.Posting {
+		if calls++; calls == 1 {
+			close(subscribed)
+		} else {
+			<-release
+		}
+		return postings
+	}
+
+	search := func(string) []index.Posting { return nil }
+	findAll := func([]finder.Key) ([]finder.K8sObject, error) { return nil, nil }
+
+	mux := http.NewServeMux()
+	RegisterWatchHandlerV2(mux, CreateFilteredWatchHandlerV2(search, findAll, registry, filter))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	events := make(chan WatchEventV2, 1024)
+	errs := make(chan error, 1)
+
+	go func() {
+		errs <- WatchV2WithClient(context.Background(), http.DefaultClient, server.URL, "flargle", Options{}, func(event WatchEventV2) error {
+			events <- event
+			return nil
+		})
+	}()
+
+	<-subscribed
+
+	for i := 0; i < 300; i++ {
+		registry.Notify(controller.Change{
+			Posting: index.Posting{StoredObjectKey: "flargle/foo", K8sResourceKind: "Pod"},
+			Terms:   []string{"flargle"},
+			Object:  testPodFlargleFoo(),
+		})
+	}
+
+	close(release)
+
+	select {
+	case err := <-errs:
+		assert.ErrorIs(t, err, ErrWatchEnded)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "the watch didn't end")
+	}
+
+	assert.NotEmpty(t, events, "the changes delivered before the watch fell behind are streamed")
+}
+
+func TestCreateWatchEvent_filtered(t *testing.T) {
+	hit := watch.Hit{Change: controller.Change{Posting: index.Posting{StoredObjectKey: "flargle/blargle", K8sResourceKind: "Pod"}, Object: testPodFlargleBlargle()}, Matched: true}
+	deny := func(context.Context, []index.Posting) []index.Posting { return nil }
+
+	_, ok := createWatchEvent(context.Background(), hit, map[string]bool{}, searcher.Query{}, tokenizer.TokenizerWithOffsets(), deny)
+
+	assert.False(t, ok)
+}
+
+func assertEvent(t *testing.T, events <-chan WatchEventV2, eventType, name string) WatchEventV2 {
+	select {
+	case event := <-events:
+		assert.Equal(t, eventType, event.Type)
+		assert.Equal(t, name, event.Object.Name)
+		return event
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "timed out waiting for a watch event", "%s %s", eventType, name)
+	}
+	return WatchEventV2{}
+}
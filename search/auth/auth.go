@@ -0,0 +1,83 @@
+// Package auth provides authentication of API callers and filtering
+// of search results by what each caller is authorized to see.
+// Callers are authenticated using bearer tokens checked with a
+// TokenReview or using client certificates, and they're authorized
+// using SubjectAccessReviews.
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"k8s.io/klog/v2"
+)
+
+// User is an authenticated caller.
+type User struct {
+	Name   string
+	UID    string
+	Groups []string
+	Extra  map[string][]string
+}
+
+// AuthenticateFunc returns the User that sent the given request.
+// It returns false if the request carries no credentials that it
+// understands, and it returns an error if the credentials are
+// invalid.
+type AuthenticateFunc func(request *http.Request) (User, bool, error)
+
+// ErrUnauthenticated is returned when no authenticator recognizes
+// the credentials of a request.
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+// Union returns an AuthenticateFunc that tries each of the given
+// authenticators in order, and it uses the first one that
+// recognizes the credentials of the request.
+func Union(authenticators ...AuthenticateFunc) AuthenticateFunc {
+	return func(request *http.Request) (User, bool, error) {
+		for _, authenticate := range authenticators {
+			user, ok, err := authenticate(request)
+
+			if err != nil || ok {
+				return user, ok, err
+			}
+		}
+
+		return User{}, false, nil
+	}
+}
+
+// Authenticate wraps the given handler so that it's only called for
+// authenticated requests. The User is stored in the context of the
+// request, and unauthenticated requests are rejected with `401
+// Unauthorized`.
+func Authenticate(handler http.HandlerFunc, authenticate AuthenticateFunc) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		user, ok, err := authenticate(request)
+
+		if err != nil {
+			klog.Warningln("error authenticating request: ", err)
+		}
+
+		if err != nil || !ok {
+			http.Error(writer, ErrUnauthenticated.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		handler(writer, request.WithContext(WithUser(request.Context(), user)))
+	}
+}
+
+type userKey struct{}
+
+// WithUser returns a copy of ctx that carries the given User.
+func WithUser(ctx context.Context, user User) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+// UserFrom returns the User carried by ctx, if any.
+func UserFrom(ctx context.Context) (User, bool) {
+	user, ok := ctx.Value(userKey{}).(User)
+	return user, ok
+}
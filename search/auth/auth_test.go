@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kubideh/kubesearch/search/controller"
+	"github.com/kubideh/kubesearch/search/index"
+	"github.com/stretchr/testify/assert"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestAuthenticate_rejectsMissingToken(t *testing.T) {
+	server := setupAuthenticatedServer(fake.NewSimpleClientset())
+	defer server.Close()
+
+	response, err := http.Get(server.URL)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, response.StatusCode)
+}
+
+func TestAuthenticate_rejectsInvalidToken(t *testing.T) {
+	server := setupAuthenticatedServer(createTokenReviewClient())
+	defer server.Close()
+
+	response, err := getWithToken(server.URL, "invalid")
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, response.StatusCode)
+}
+
+func TestAuthenticate_acceptsValidToken(t *testing.T) {
+	server := setupAuthenticatedServer(createTokenReviewClient())
+	defer server.Close()
+
+	response, err := getWithToken(server.URL, "valid")
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "blargle", response.Header.Get("X-User"))
+}
+
+func TestFilter(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "subjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+		attributes := review.Spec.ResourceAttributes
+		review.Status.Allowed = attributes.Namespace == "flargle" || attributes.Name == "visible"
+		return true, review, nil
+	})
+
+	filter := CreateFilter(CreateSubjectAccessReviewAuthorizer(client, time.Minute), controller.IndexedResources())
+	postings := []index.Posting{
+		{StoredObjectKey: "flargle/blargle", K8sResourceKind: "Pod"},
+		{StoredObjectKey: "flargle/foo", K8sResourceKind: "Deployment"},
+		{StoredObjectKey: "bobble/visible", K8sResourceKind: "Pod"},
+		{StoredObjectKey: "bobble/hidden", K8sResourceKind: "Pod"},
+	}
+
+	ctx := WithUser(context.Background(), User{Name: "blargle"})
+
+	assert.Equal(t, []index.Posting{
+		{StoredObjectKey: "flargle/blargle", K8sResourceKind: "Pod"},
+		{StoredObjectKey: "flargle/foo", K8sResourceKind: "Deployment"},
+		{StoredObjectKey: "bobble/visible", K8sResourceKind: "Pod"},
+	}, filter(ctx, postings))
+}
+
+func TestFilter_withoutUser(t *testing.T) {
+	filter := CreateFilter(CreateSubjectAccessReviewAuthorizer(fake.NewSimpleClientset(), time.Minute), controller.IndexedResources())
+	postings := []index.Posting{
+		{StoredObjectKey: "flargle/blargle", K8sResourceKind: "Pod"},
+	}
+
+	assert.Empty(t, filter(context.Background(), postings))
+}
+
+func TestSubjectAccessReviewAuthorizer_distinctUsers(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "subjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+		review.Status.Allowed = len(review.Spec.Groups) == 2 && len(review.Spec.Extra["scopes"]) == 0
+		return true, review, nil
+	})
+
+	authorize := CreateSubjectAccessReviewAuthorizer(client, time.Minute)
+	attributes := Attributes{Verb: "list", Resource: "pods", Namespace: "flargle"}
+
+	for _, tc := range []struct {
+		user    User
+		allowed bool
+	}{
+		{user: User{Name: "blargle", Groups: []string{"bobble", "foo"}}, allowed: true},
+		{user: User{Name: "blargle", Groups: []string{"bobble,foo"}}, allowed: false},
+		{user: User{Name: "blargle", Groups: []string{"bobble", "foo"}, Extra: map[string][]string{"scopes": {"read"}}}, allowed: false},
+	} {
+		allowed, err := authorize(context.Background(), tc.user, attributes)
+
+		assert.NoError(t, err)
+		assert.Equal(t, tc.allowed, allowed, "%+v", tc.user)
+	}
+}
+
+func setupAuthenticatedServer(client *fake.Clientset) *httptest.Server {
+	authenticate := Union(CreateClientCertAuthenticator(), CreateTokenAuthenticator(client, time.Minute))
+
+	handler := Authenticate(func(writer http.ResponseWriter, request *http.Request) {
+		user, _ := UserFrom(request.Context())
+		writer.Header().Set("X-User", user.Name)
+	}, authenticate)
+
+	return httptest.NewServer(handler)
+}
+
+func createTokenReviewClient() *fake.Clientset {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "tokenreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+		if review.Spec.Token == "valid" {
+			review.Status.Authenticated = true
+			review.Status.User = authenticationv1.UserInfo{Username: "blargle"}
+		}
+		return true, review, nil
+	})
+	return client
+}
+
+func getWithToken(url, token string) (*http.Response, error) {
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Set("Authorization", "Bearer "+token)
+
+	return http.DefaultClient.Do(request)
+}
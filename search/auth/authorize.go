@@ -0,0 +1,228 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kubideh/kubesearch/search/controller"
+	"github.com/kubideh/kubesearch/search/index"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/cache"
+	"k8s.io/client-go/kubernetes"
+	toolscache "k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// maxConcurrentReviews bounds the number of SubjectAccessReviews
+// that are sent at the same time while filtering one result set.
+const maxConcurrentReviews = 8
+
+// Attributes describe an action on a Kubernetes object. An empty
+// Name refers to every object of the resource in the namespace.
+type Attributes struct {
+	Verb      string
+	Group     string
+	Resource  string
+	Namespace string
+	Name      string
+}
+
+// AuthorizeFunc returns true if the given User may perform the
+// action described by the given Attributes.
+type AuthorizeFunc func(ctx context.Context, user User, attributes Attributes) (bool, error)
+
+// CreateSubjectAccessReviewAuthorizer returns an AuthorizeFunc that
+// uses SubjectAccessReviews. Decisions are cached for the given TTL.
+func CreateSubjectAccessReviewAuthorizer(client kubernetes.Interface, ttl time.Duration) AuthorizeFunc {
+	decisions := cache.NewLRUExpireCache(cacheSize)
+
+	return func(ctx context.Context, user User, attributes Attributes) (bool, error) {
+		key := decisionKey(user, attributes)
+
+		if cached, ok := decisions.Get(key); ok {
+			return cached.(bool), nil
+		}
+
+		allowed, err := reviewAccess(ctx, client, user, attributes)
+
+		if err != nil {
+			return false, err
+		}
+
+		decisions.Add(key, allowed, ttl)
+
+		return allowed, nil
+	}
+}
+
+// decisionKey returns the key under which the decision for the given
+// user and attributes is cached. It's JSON, so that no two distinct
+// users or attributes have the same key, and map keys are sorted.
+func decisionKey(user User, attributes Attributes) string {
+	groups := append([]string(nil), user.Groups...)
+	sort.Strings(groups)
+
+	// Marshaling strings, and slices and maps of them, can't fail.
+	key, _ := json.Marshal(struct {
+		Name       string
+		UID        string
+		Groups     []string
+		Extra      map[string][]string
+		Attributes Attributes
+	}{user.Name, user.UID, groups, user.Extra, attributes})
+
+	return string(key)
+}
+
+func reviewAccess(ctx context.Context, client kubernetes.Interface, user User, attributes Attributes) (bool, error) {
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Verb:      attributes.Verb,
+				Group:     attributes.Group,
+				Resource:  attributes.Resource,
+				Namespace: attributes.Namespace,
+				Name:      attributes.Name,
+			},
+			User:   user.Name,
+			UID:    user.UID,
+			Groups: user.Groups,
+			Extra:  createExtraValues(user.Extra),
+		},
+	}
+
+	result, err := client.AuthorizationV1().SubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+
+	if err != nil {
+		return false, err
+	}
+
+	return result.Status.Allowed, nil
+}
+
+func createExtraValues(extra map[string][]string) map[string]authorizationv1.ExtraValue {
+	result := make(map[string]authorizationv1.ExtraValue, len(extra))
+
+	for k, v := range extra {
+		result[k] = v
+	}
+
+	return result
+}
+
+// FilterFunc removes the postings that the User carried by ctx is
+// not authorized to see.
+type FilterFunc func(ctx context.Context, postings []index.Posting) []index.Posting
+
+// CreateFilter returns a FilterFunc that keeps only postings whose
+// objects the User could `get`. Access is first checked once for
+// each kind and namespace, and only the postings in namespaces that
+// are not fully readable are checked object-by-object. Postings are
+// dropped if ctx carries no User.
+func CreateFilter(authorize AuthorizeFunc, resources []controller.Resource) FilterFunc {
+	resourcesByKind := make(map[string]controller.Resource, len(resources))
+
+	for _, r := range resources {
+		resourcesByKind[r.Kind] = r
+	}
+
+	return func(ctx context.Context, postings []index.Posting) []index.Posting {
+		user, ok := UserFrom(ctx)
+
+		if !ok {
+			return nil
+		}
+
+		namespaceAttributes := make([]Attributes, len(postings))
+
+		for i, p := range postings {
+			namespaceAttributes[i] = createAttributes(resourcesByKind[p.K8sResourceKind], p)
+			namespaceAttributes[i].Name = ""
+		}
+
+		namespaceDecisions := authorizeAll(ctx, authorize, user, namespaceAttributes)
+
+		objectAttributes := make([]Attributes, 0)
+
+		for i, p := range postings {
+			if !namespaceDecisions[namespaceAttributes[i]] {
+				objectAttributes = append(objectAttributes, createAttributes(resourcesByKind[p.K8sResourceKind], p))
+			}
+		}
+
+		objectDecisions := authorizeAll(ctx, authorize, user, objectAttributes)
+
+		result := make([]index.Posting, 0, len(postings))
+
+		for i, p := range postings {
+			if namespaceDecisions[namespaceAttributes[i]] || objectDecisions[createAttributes(resourcesByKind[p.K8sResourceKind], p)] {
+				result = append(result, p)
+			}
+		}
+
+		return result
+	}
+}
+
+func createAttributes(resource controller.Resource, posting index.Posting) Attributes {
+	namespace, name, err := toolscache.SplitMetaNamespaceKey(posting.StoredObjectKey)
+
+	if err != nil {
+		klog.Warningln("error splitting key: ", err)
+	}
+
+	return Attributes{
+		Verb:      "get",
+		Group:     resource.GroupVersionResource.Group,
+		Resource:  resource.GroupVersionResource.Resource,
+		Namespace: namespace,
+		Name:      name,
+	}
+}
+
+// authorizeAll checks each distinct set of attributes once, with a
+// bounded number of concurrent checks, and it returns the decision
+// for each. Errors are logged and treated as a denial.
+func authorizeAll(ctx context.Context, authorize AuthorizeFunc, user User, attributes []Attributes) map[Attributes]bool {
+	decisions := make(map[Attributes]bool, len(attributes))
+	distinct := make([]Attributes, 0, len(attributes))
+
+	for _, a := range attributes {
+		if _, ok := decisions[a]; !ok {
+			decisions[a] = false
+			distinct = append(distinct, a)
+		}
+	}
+
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, maxConcurrentReviews)
+
+	for _, a := range distinct {
+		wg.Add(1)
+		semaphore <- struct{}{}
+
+		go func(a Attributes) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			allowed, err := authorize(ctx, user, a)
+
+			if err != nil {
+				klog.Warningln("error authorizing request: ", err)
+			}
+
+			mutex.Lock()
+			decisions[a] = allowed
+			mutex.Unlock()
+		}(a)
+	}
+
+	wg.Wait()
+
+	return decisions
+}
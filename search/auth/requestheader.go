@@ -0,0 +1,169 @@
+package auth
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	certutil "k8s.io/client-go/util/cert"
+)
+
+const (
+	authenticationConfigMapNamespace = "kube-system"
+	authenticationConfigMapName      = "extension-apiserver-authentication"
+)
+
+// RequestHeaderConfig is how the Kubernetes API aggregator
+// identifies the users on whose behalf it proxies requests. It's
+// published in the ConfigMap
+// `kube-system/extension-apiserver-authentication`.
+type RequestHeaderConfig struct {
+	ClientCAs           *x509.CertPool      // ClientCAs verify the client certificate of the aggregator
+	ClientCACerts       []*x509.Certificate // ClientCACerts are the certificates of ClientCAs
+	AllowedNames        []string            // AllowedNames are the allowed common names of the aggregator; any name is allowed if empty
+	UsernameHeaders     []string
+	GroupHeaders        []string
+	ExtraHeaderPrefixes []string
+}
+
+// LoadRequestHeaderConfig reads the RequestHeaderConfig published
+// by the Kubernetes API server.
+func LoadRequestHeaderConfig(ctx context.Context, client kubernetes.Interface) (RequestHeaderConfig, error) {
+	configMap, err := client.CoreV1().ConfigMaps(authenticationConfigMapNamespace).Get(ctx, authenticationConfigMapName, metav1.GetOptions{})
+
+	if err != nil {
+		return RequestHeaderConfig{}, err
+	}
+
+	var config RequestHeaderConfig
+
+	config.ClientCACerts, err = certutil.ParseCertsPEM([]byte(configMap.Data["requestheader-client-ca-file"]))
+
+	if err != nil {
+		return config, fmt.Errorf("no request header client CA found in %s/%s: %w", authenticationConfigMapNamespace, authenticationConfigMapName, err)
+	}
+
+	config.ClientCAs = x509.NewCertPool()
+
+	for _, certificate := range config.ClientCACerts {
+		config.ClientCAs.AddCert(certificate)
+	}
+
+	for key, value := range map[string]*[]string{
+		"requestheader-allowed-names":        &config.AllowedNames,
+		"requestheader-username-headers":     &config.UsernameHeaders,
+		"requestheader-group-headers":        &config.GroupHeaders,
+		"requestheader-extra-headers-prefix": &config.ExtraHeaderPrefixes,
+	} {
+		if data, ok := configMap.Data[key]; ok && data != "" {
+			if err := json.Unmarshal([]byte(data), value); err != nil {
+				return config, fmt.Errorf("error decoding %s: %w", key, err)
+			}
+		}
+	}
+
+	return config, nil
+}
+
+// CreateRequestHeaderAuthenticator returns an AuthenticateFunc that
+// trusts the user given by request headers, but only if the request
+// carries a client certificate that is verified by the given
+// config, e.g., when the request is proxied by the Kubernetes API
+// aggregator.
+func CreateRequestHeaderAuthenticator(config RequestHeaderConfig) AuthenticateFunc {
+	return func(request *http.Request) (User, bool, error) {
+		if request.TLS == nil || len(request.TLS.PeerCertificates) == 0 {
+			return User{}, false, nil
+		}
+
+		if err := verifyRequestHeaderClient(config, request.TLS.PeerCertificates); err != nil {
+			return User{}, false, nil
+		}
+
+		name := firstHeaderValue(request.Header, config.UsernameHeaders)
+
+		if name == "" {
+			return User{}, false, nil
+		}
+
+		return User{
+			Name:   name,
+			Groups: allHeaderValues(request.Header, config.GroupHeaders),
+			Extra:  extraHeaderValues(request.Header, config.ExtraHeaderPrefixes),
+		}, true, nil
+	}
+}
+
+func verifyRequestHeaderClient(config RequestHeaderConfig, certificates []*x509.Certificate) error {
+	intermediates := x509.NewCertPool()
+
+	for _, c := range certificates[1:] {
+		intermediates.AddCert(c)
+	}
+
+	_, err := certificates[0].Verify(x509.VerifyOptions{
+		Roots:         config.ClientCAs,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if len(config.AllowedNames) == 0 {
+		return nil
+	}
+
+	for _, name := range config.AllowedNames {
+		if name == certificates[0].Subject.CommonName {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("client certificate common name %q is not allowed", certificates[0].Subject.CommonName)
+}
+
+func firstHeaderValue(header http.Header, names []string) string {
+	for _, n := range names {
+		if value := strings.TrimSpace(header.Get(n)); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func allHeaderValues(header http.Header, names []string) (result []string) {
+	for _, n := range names {
+		result = append(result, header.Values(n)...)
+	}
+	return
+}
+
+func extraHeaderValues(header http.Header, prefixes []string) map[string][]string {
+	result := make(map[string][]string)
+
+	for name, values := range header {
+		for _, p := range prefixes {
+			if !strings.HasPrefix(strings.ToLower(name), strings.ToLower(p)) {
+				continue
+			}
+
+			key, err := url.PathUnescape(strings.ToLower(name[len(p):]))
+
+			if err != nil {
+				continue
+			}
+
+			result[key] = append(result[key], values...)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/cache"
+	"k8s.io/client-go/kubernetes"
+)
+
+const cacheSize = 4096
+
+// CreateTokenAuthenticator returns an AuthenticateFunc that checks
+// bearer tokens using TokenReviews. Reviews are cached for the
+// given TTL, and tokens are cached only as SHA-256 digests.
+func CreateTokenAuthenticator(client kubernetes.Interface, ttl time.Duration) AuthenticateFunc {
+	reviews := cache.NewLRUExpireCache(cacheSize)
+
+	return func(request *http.Request) (User, bool, error) {
+		token := bearerToken(request)
+
+		if token == "" {
+			return User{}, false, nil
+		}
+
+		key := digest(token)
+
+		if cached, ok := reviews.Get(key); ok {
+			return cached.(User), true, nil
+		}
+
+		user, err := reviewToken(request.Context(), client, token)
+
+		if err != nil {
+			return User{}, false, err
+		}
+
+		reviews.Add(key, user, ttl)
+
+		return user, true, nil
+	}
+}
+
+func bearerToken(request *http.Request) string {
+	header := strings.TrimSpace(request.Header.Get("Authorization"))
+	parts := strings.SplitN(header, " ", 2)
+
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "bearer") {
+		return ""
+	}
+
+	return strings.TrimSpace(parts[1])
+}
+
+func digest(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func reviewToken(ctx context.Context, client kubernetes.Interface, token string) (User, error) {
+	review := &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{
+			Token: token,
+		},
+	}
+
+	result, err := client.AuthenticationV1().TokenReviews().Create(ctx, review, metav1.CreateOptions{})
+
+	if err != nil {
+		return User{}, err
+	}
+
+	if !result.Status.Authenticated {
+		return User{}, fmt.Errorf("token was not authenticated: %s", result.Status.Error)
+	}
+
+	return createUserFromUserInfo(result.Status.User), nil
+}
+
+func createUserFromUserInfo(info authenticationv1.UserInfo) User {
+	extra := make(map[string][]string, len(info.Extra))
+
+	for k, v := range info.Extra {
+		extra[k] = v
+	}
+
+	return User{
+		Name:   info.Username,
+		UID:    info.UID,
+		Groups: info.Groups,
+		Extra:  extra,
+	}
+}
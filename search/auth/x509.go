@@ -0,0 +1,24 @@
+package auth
+
+import (
+	"net/http"
+)
+
+// CreateClientCertAuthenticator returns an AuthenticateFunc that
+// uses the verified client certificate of a TLS request. The common
+// name is the user name, and the organizations are the groups,
+// which is the same convention used by the Kubernetes API server.
+func CreateClientCertAuthenticator() AuthenticateFunc {
+	return func(request *http.Request) (User, bool, error) {
+		if request.TLS == nil || len(request.TLS.VerifiedChains) == 0 || len(request.TLS.VerifiedChains[0]) == 0 {
+			return User{}, false, nil
+		}
+
+		certificate := request.TLS.VerifiedChains[0][0]
+
+		return User{
+			Name:   certificate.Subject.CommonName,
+			Groups: certificate.Subject.Organization,
+		}, true, nil
+	}
+}
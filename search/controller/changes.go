@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"sync"
+
+	"github.com/kubideh/kubesearch/search/index"
+)
+
+// Change is a change to a single indexed object, as seen by an
+// indexer once the change is indexed. Object is nil if the object
+// was deleted.
+type Change struct {
+	Posting   index.Posting
+	Terms     []string        // Terms are the terms under which the object is indexed
+	DocValues index.DocValues // DocValues are empty if the object was deleted
+	Object    interface{}
+}
+
+// Deleted returns whether the object of the given Change was
+// deleted.
+func (c Change) Deleted() bool {
+	return c.Object == nil
+}
+
+// ChangeFunc is called by the indexers of a Controller after each
+// change is indexed. It's called from the indexing loop, so it must
+// not block.
+type ChangeFunc func(change Change)
+
+// OnChange registers the given ChangeFunc, e.g., one that evaluates
+// standing queries against each changed object.
+func (c *Controller) OnChange(f ChangeFunc) {
+	c.changeFuncs.add(f)
+}
+
+// changeFuncs are the ChangeFuncs registered with a Controller.
+type changeFuncs struct {
+	funcs []ChangeFunc
+	mutex sync.RWMutex
+}
+
+func (c *changeFuncs) add(f ChangeFunc) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.funcs = append(c.funcs, f)
+}
+
+func (c *changeFuncs) notify(change Change) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	for _, f := range c.funcs {
+		f(change)
+	}
+}
@@ -9,6 +9,9 @@ import (
 
 	"github.com/kubideh/kubesearch/search/index"
 	"github.com/kubideh/kubesearch/search/tokenizer"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
@@ -22,6 +25,24 @@ type Controller struct {
 	informerFactory informers.SharedInformerFactory
 	informers       map[string]informerWorkqueuePair
 	tokenizer       tokenizer.TokenizeFunc
+	changeFuncs     *changeFuncs
+}
+
+// Resource is a kind of Kubernetes object that is indexed by
+// Controllers.
+type Resource struct {
+	Kind                 string
+	GroupVersionResource schema.GroupVersionResource
+}
+
+// IndexedResources returns every kind of Kubernetes object indexed
+// by Controllers. Anything that depends on which objects are
+// indexed, e.g., RBAC, should be derived from this list.
+func IndexedResources() []Resource {
+	return []Resource{
+		{Kind: "Deployment", GroupVersionResource: appsv1.SchemeGroupVersion.WithResource("deployments")},
+		{Kind: "Pod", GroupVersionResource: corev1.SchemeGroupVersion.WithResource("pods")},
+	}
 }
 
 // Create returns Controller objects.
@@ -33,14 +54,28 @@ func Create(client kubernetes.Interface) *Controller {
 	return &Controller{
 		index:           index.Create(),
 		informerFactory: factory,
-		informers: map[string]informerWorkqueuePair{
-			"Deployment": bindInformerToNewWorkqueue(factory.Apps().V1().Deployments().Informer(), "Deployment-queue"),
-			"Pod":        bindInformerToNewWorkqueue(factory.Core().V1().Pods().Informer(), "Pod-queue"),
-		},
-		tokenizer: tokenizer.Tokenizer(),
+		informers:       bindInformersToNewWorkqueues(factory, IndexedResources()),
+		tokenizer:       tokenizer.Tokenizer(),
+		changeFuncs:     &changeFuncs{},
 	}
 }
 
+func bindInformersToNewWorkqueues(factory informers.SharedInformerFactory, resources []Resource) map[string]informerWorkqueuePair {
+	result := make(map[string]informerWorkqueuePair)
+
+	for _, r := range resources {
+		informer, err := factory.ForResource(r.GroupVersionResource)
+
+		if err != nil {
+			klog.Fatalln(err)
+		}
+
+		result[r.Kind] = bindInformerToNewWorkqueue(informer.Informer(), r.Kind+"-queue", r.Kind)
+	}
+
+	return result
+}
+
 // Index returns the index bound to this Controller.
 func (c *Controller) Index() *index.Index {
 	return c.index
@@ -61,39 +96,72 @@ func (c *Controller) store(kind string) cache.Store {
 	return c.informers[kind].informer.GetStore()
 }
 
+// HasSynced returns true once the cache of every informer has been
+// synced.
+func (c *Controller) HasSynced() bool {
+	for _, pair := range c.informers {
+		if !pair.informer.HasSynced() {
+			return false
+		}
+	}
+
+	return true
+}
+
 // Start this controller. The caller should defer the call to the
-// return cancel function.
+// return cancel function. Start blocks until the informer caches
+// are synced, and it logs any informer that failed to sync.
 func (c *Controller) Start() context.CancelFunc {
 	c.startIndexers()
 
 	ctx, cancel := context.WithCancel(context.Background())
 	c.informerFactory.Start(ctx.Done())
-	c.informerFactory.WaitForCacheSync(ctx.Done())
+
+	for informerType, synced := range c.informerFactory.WaitForCacheSync(ctx.Done()) {
+		if !synced {
+			klog.Errorf("Failed to sync the informer cache for %v", informerType)
+		}
+	}
+
+	klog.Infoln("Informer caches are synced")
 
 	return cancel
 }
 
 func (c *Controller) startIndexers() {
 	for kind, informer := range c.informers {
-		startIndexer(informer.queue, c.index, c.tokenizer, kind)
+		startIndexer(informer.queue, informer.pending, informer.informer.GetStore(), c.index, c.tokenizer, kind, c.changeFuncs.notify)
 	}
 }
 
-func startIndexer(queue workqueue.RateLimitingInterface, idx *index.Index, tokenize tokenizer.TokenizeFunc, kind string) {
-	go indexObjects(queue, idx, tokenize, kind)
+func startIndexer(queue workqueue.RateLimitingInterface, pending *pendingKeys, store cache.Store, idx *index.Index, tokenize tokenizer.TokenizeFunc, kind string, notify ChangeFunc) {
+	go indexObjects(queue, pending, store, idx, tokenize, kind, notify)
 }
 
-func indexObjects(queue workqueue.RateLimitingInterface, idx *index.Index, tokenize tokenizer.TokenizeFunc, kind string) {
+func indexObjects(queue workqueue.RateLimitingInterface, pending *pendingKeys, store cache.Store, idx *index.Index, tokenize tokenizer.TokenizeFunc, kind string, notify ChangeFunc) {
 	key, shutdown := queue.Get()
 
 	for !shutdown {
-		if namespace(key) != "" {
-			idx.Put(tokenize(namespace(key)), index.Posting{StoredObjectKey: keyString(key), K8sResourceKind: kind})
+		enqueues := pending.start(keyString(key))
+
+		obj, exists, err := store.GetByKey(keyString(key))
+
+		if err != nil {
+			klog.Errorln(err)
 		}
 
-		idx.Put(tokenize(name(key)), index.Posting{StoredObjectKey: keyString(key), K8sResourceKind: kind})
+		if !exists {
+			obj = nil
+		}
+
+		change := IndexObject(idx, tokenize, kind, keyString(key), obj)
 
-		// XXX Support indexing annotations and labels
+		if err == nil {
+			notify(change)
+		}
+
+		pending.finish(keyString(key), enqueues)
+		queue.Done(key)
 
 		key, shutdown = queue.Get()
 	}
@@ -101,6 +169,44 @@ func indexObjects(queue workqueue.RateLimitingInterface, idx *index.Index, token
 	klog.Infof("Shutting down %s queue", kind)
 }
 
+// IndexObject puts the terms of the object of the given kind and
+// store key into the given index, including those of its namespace,
+// labels and container images, and it puts the doc values of the
+// given object too. If the object is nil because it was deleted,
+// then its postings and doc values are removed from the index
+// instead. It returns the indexed change.
+func IndexObject(idx *index.Index, tokenize tokenizer.TokenizeFunc, kind, key string, obj interface{}) Change {
+	posting := index.Posting{StoredObjectKey: key, K8sResourceKind: kind}
+	change := Change{Posting: posting}
+
+	if namespace(key) != "" {
+		change.Terms = append(tokenize(namespace(key)), index.NamespaceTerm(namespace(key)))
+	}
+
+	change.Terms = append(change.Terms, tokenize(name(key))...)
+
+	// The terms of a deleted object are still part of its change,
+	// so that standing queries see it stop matching.
+	if obj == nil {
+		idx.Remove(posting)
+		return change
+	}
+
+	// XXX Support indexing annotations
+
+	change.Terms = append(change.Terms, fieldTerms(obj)...)
+
+	// Labels and images may change, so the terms of an earlier
+	// version of the object are replaced.
+	idx.Update(change.Terms, posting)
+
+	change.Object = obj
+	change.DocValues = docValues(obj)
+	idx.PutDocValues(posting, change.DocValues)
+
+	return change
+}
+
 func keyString(key interface{}) string {
 	return key.(string)
 }
@@ -127,36 +233,57 @@ func name(key interface{}) (result string) {
 	return
 }
 
-func bindInformerToNewWorkqueue(informer cache.SharedIndexInformer, name string) informerWorkqueuePair {
+func bindInformerToNewWorkqueue(informer cache.SharedIndexInformer, name, kind string) informerWorkqueuePair {
 	queue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), name)
+	lastEvent := &eventClock{}
+	pending := newPendingKeys()
 
-	addEventHandlerToInformerUsingQueue(informer, queue)
+	addEventHandlerToInformerUsingQueue(informer, queue, pending, lastEvent)
 
-	return newInformerWorkqueuePair(informer, queue)
+	return newInformerWorkqueuePair(informer, queue, pending, lastEvent, kind)
 }
 
 // informerWorkqueuePair binds an informer and a workqueue.
 type informerWorkqueuePair struct {
-	informer cache.SharedIndexInformer
-	queue    workqueue.RateLimitingInterface
+	informer  cache.SharedIndexInformer
+	queue     workqueue.RateLimitingInterface
+	pending   *pendingKeys
+	lastEvent *eventClock
+	kind      string
 }
 
-func newInformerWorkqueuePair(informer cache.SharedIndexInformer, queue workqueue.RateLimitingInterface) informerWorkqueuePair {
+func newInformerWorkqueuePair(informer cache.SharedIndexInformer, queue workqueue.RateLimitingInterface, pending *pendingKeys, lastEvent *eventClock, kind string) informerWorkqueuePair {
 	return informerWorkqueuePair{
-		informer: informer,
-		queue:    queue,
+		informer:  informer,
+		queue:     queue,
+		pending:   pending,
+		lastEvent: lastEvent,
+		kind:      kind,
 	}
 }
 
-func addEventHandlerToInformerUsingQueue(informer cache.SharedIndexInformer, queue workqueue.RateLimitingInterface) {
+func addEventHandlerToInformerUsingQueue(informer cache.SharedIndexInformer, queue workqueue.RateLimitingInterface, pending *pendingKeys, lastEvent *eventClock) {
+	enqueue := func(obj interface{}) {
+		lastEvent.tick()
+
+		key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+		if err != nil {
+			klog.Errorln(err)
+		} else {
+			pending.add(key)
+			queue.Add(key)
+		}
+	}
+
 	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
-		AddFunc: func(obj interface{}) {
-			key, err := cache.MetaNamespaceKeyFunc(obj)
-			if err != nil {
-				klog.Errorln(err)
-			} else {
-				queue.Add(key)
-			}
+		AddFunc: enqueue,
+		// Updates refresh the doc values of objects, e.g., restart
+		// counts and replicas.
+		UpdateFunc: func(_, obj interface{}) {
+			enqueue(obj)
 		},
+		// The postings and doc values of deleted objects are
+		// removed from the index once their keys are dequeued.
+		DeleteFunc: enqueue,
 	})
 }
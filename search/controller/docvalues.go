@@ -0,0 +1,66 @@
+package controller
+
+import (
+	"github.com/kubideh/kubesearch/search/index"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/klog/v2"
+)
+
+// docValues returns the values of the given object that are stored
+// in the index for sorting.
+func docValues(obj interface{}) index.DocValues {
+	result := make(index.DocValues)
+
+	object, err := meta.Accessor(obj)
+
+	if err != nil {
+		klog.Errorln(err)
+		return result
+	}
+
+	result[index.DocValueName] = index.StringValue(object.GetName())
+	result[index.DocValueNamespace] = index.StringValue(object.GetNamespace())
+
+	if created := object.GetCreationTimestamp(); !created.IsZero() {
+		result[index.DocValueCreationTimestamp] = index.NumberValue(created.Unix())
+	}
+
+	switch o := obj.(type) {
+	case *corev1.Pod:
+		result[index.DocValueRestartCount] = index.NumberValue(restartCount(o))
+		putRequests(result, o.Spec)
+	case *appsv1.Deployment:
+		if o.Spec.Replicas != nil {
+			result[index.DocValueReplicas] = index.NumberValue(int64(*o.Spec.Replicas))
+		}
+		putRequests(result, o.Spec.Template.Spec)
+	}
+
+	return result
+}
+
+// putRequests stores the sum of the CPU requests, in millicores, and
+// the sum of the memory requests, in bytes, of every container of
+// the given Pod spec.
+func putRequests(values index.DocValues, spec corev1.PodSpec) {
+	var cpu, memory int64
+
+	for _, c := range spec.Containers {
+		cpu += c.Resources.Requests.Cpu().MilliValue()
+		memory += c.Resources.Requests.Memory().Value()
+	}
+
+	values[index.DocValueCPU] = index.NumberValue(cpu)
+	values[index.DocValueMemory] = index.NumberValue(memory)
+}
+
+// restartCount returns the sum of the restart counts of every
+// container of the given Pod.
+func restartCount(pod *corev1.Pod) (result int64) {
+	for _, s := range pod.Status.ContainerStatuses {
+		result += int64(s.RestartCount)
+	}
+	return
+}
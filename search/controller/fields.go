@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"github.com/kubideh/kubesearch/search/index"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/klog/v2"
+)
+
+// fieldTerms returns the terms under which the labels and the
+// container images of the given object are indexed, so that field
+// clauses such as `label:app=web` or `image:nginx` match them.
+func fieldTerms(obj interface{}) (result []string) {
+	object, err := meta.Accessor(obj)
+
+	if err != nil {
+		klog.Errorln(err)
+		return
+	}
+
+	for key, value := range object.GetLabels() {
+		result = append(result, index.LabelTerms(key, value)...)
+	}
+
+	for _, image := range Images(obj) {
+		result = append(result, index.ImageTerms(image)...)
+	}
+
+	return
+}
+
+// Images returns the image of each init container and container of
+// the given object, if it has a Pod spec.
+func Images(obj interface{}) (result []string) {
+	if spec := podSpec(obj); spec != nil {
+		for _, c := range append(append([]corev1.Container(nil), spec.InitContainers...), spec.Containers...) {
+			result = append(result, c.Image)
+		}
+	}
+	return
+}
+
+// podSpec returns the Pod spec of the given object, or nil if it
+// has none.
+func podSpec(obj interface{}) *corev1.PodSpec {
+	switch o := obj.(type) {
+	case *corev1.Pod:
+		return &o.Spec
+	case *appsv1.Deployment:
+		return &o.Spec.Template.Spec
+	case *appsv1.StatefulSet:
+		return &o.Spec.Template.Spec
+	case *appsv1.DaemonSet:
+		return &o.Spec.Template.Spec
+	case *appsv1.ReplicaSet:
+		return &o.Spec.Template.Spec
+	case *batchv1.Job:
+		return &o.Spec.Template.Spec
+	case *batchv1.CronJob:
+		return &o.Spec.JobTemplate.Spec.Template.Spec
+	}
+	return nil
+}
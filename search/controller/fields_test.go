@@ -0,0 +1,69 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/kubideh/kubesearch/search/index"
+	"github.com/kubideh/kubesearch/search/tokenizer"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestIndexObject_fields(t *testing.T) {
+	idx := index.Create()
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "payment", Namespace: "prod", Labels: map[string]string{"app": "payment"}},
+		Spec: corev1.PodSpec{
+			InitContainers: []corev1.Container{{Image: "busybox:1.35"}},
+			Containers:     []corev1.Container{{Image: "nginx"}},
+		},
+	}
+
+	change := IndexObject(idx, tokenizer.Tokenizer(), "Pod", "prod/payment", pod)
+
+	posting := index.Posting{StoredObjectKey: "prod/payment", K8sResourceKind: "Pod"}
+
+	for _, term := range []string{"ns:prod", "label:app=payment", "label:app", "image:nginx", "image:latest", "image:busybox:1.35"} {
+		assert.Contains(t, change.Terms, term)
+		assert.Equal(t, []index.Posting{posting}, idx.Get(term), term)
+	}
+
+	// The labels and images of the earlier version are replaced.
+	pod = pod.DeepCopy()
+	pod.Labels = map[string]string{"app": "payment", "owner": "payments-team"}
+	pod.Spec.Containers[0].Image = "nginx:1.21"
+
+	IndexObject(idx, tokenizer.Tokenizer(), "Pod", "prod/payment", pod)
+
+	assert.Empty(t, idx.Get("image:latest"))
+	assert.Equal(t, []index.Posting{posting}, idx.Get("image:nginx:1.21"))
+	assert.Equal(t, []index.Posting{posting}, idx.Get("label:owner"))
+	assert.Equal(t, []index.Posting{posting}, idx.Get("ns:prod"))
+
+	IndexObject(idx, tokenizer.Tokenizer(), "Pod", "prod/payment", nil)
+
+	assert.Empty(t, idx.Get("label:owner"))
+	assert.Empty(t, idx.Get("ns:prod"))
+}
+
+func TestFieldTerms_podTemplates(t *testing.T) {
+	template := corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{Image: "nginx:1.21"}}}}
+
+	objects := []interface{}{
+		&appsv1.Deployment{Spec: appsv1.DeploymentSpec{Template: template}},
+		&appsv1.StatefulSet{Spec: appsv1.StatefulSetSpec{Template: template}},
+		&appsv1.DaemonSet{Spec: appsv1.DaemonSetSpec{Template: template}},
+		&appsv1.ReplicaSet{Spec: appsv1.ReplicaSetSpec{Template: template}},
+		&batchv1.Job{Spec: batchv1.JobSpec{Template: template}},
+		&batchv1.CronJob{Spec: batchv1.CronJobSpec{JobTemplate: batchv1.JobTemplateSpec{Spec: batchv1.JobSpec{Template: template}}}},
+	}
+
+	for _, o := range objects {
+		assert.Contains(t, fieldTerms(o), "image:nginx:1.21")
+	}
+
+	assert.Empty(t, fieldTerms(&corev1.ConfigMap{}))
+}
@@ -0,0 +1,142 @@
+package controller
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Status is the indexing status of a Controller. The Controller is
+// ready once every informer cache is synced and every indexer has
+// drained its workqueue.
+type Status struct {
+	Ready bool         `json:"ready"`
+	Kinds []KindStatus `json:"kinds"`
+}
+
+// KindStatus is the indexing status of a single kind of Kubernetes
+// object.
+type KindStatus struct {
+	Kind          string     `json:"kind"`
+	Synced        bool       `json:"synced"`
+	ObjectCount   int        `json:"objectCount"`
+	QueueDepth    int        `json:"queueDepth"` // QueueDepth counts the keys that are queued or being indexed
+	LastEventTime *time.Time `json:"lastEventTime,omitempty"`
+}
+
+// StatusFunc returns the current indexing status.
+type StatusFunc func() Status
+
+// Status returns the current indexing status of this Controller
+// with kinds sorted by name.
+func (c *Controller) Status() Status {
+	result := Status{
+		Ready: true,
+		Kinds: make([]KindStatus, 0, len(c.informers)),
+	}
+
+	for _, pair := range c.informers {
+		kindStatus := pair.status()
+		result.Ready = result.Ready && kindStatus.Synced && kindStatus.QueueDepth == 0
+		result.Kinds = append(result.Kinds, kindStatus)
+	}
+
+	sort.Slice(result.Kinds, func(i, j int) bool {
+		return result.Kinds[i].Kind < result.Kinds[j].Kind
+	})
+
+	return result
+}
+
+// Ready returns true once every informer cache is synced and every
+// indexer has drained its workqueue.
+func (c *Controller) Ready() bool {
+	return c.Status().Ready
+}
+
+func (p informerWorkqueuePair) status() KindStatus {
+	return KindStatus{
+		Kind:          p.kind,
+		Synced:        p.informer.HasSynced(),
+		ObjectCount:   len(p.informer.GetStore().ListKeys()),
+		QueueDepth:    p.pending.len(),
+		LastEventTime: p.lastEvent.time(),
+	}
+}
+
+// pendingKeys counts how many times each key was enqueued since its
+// indexing last started. A key is pending until it's been indexed
+// after its most recent enqueue, so keys taken from a workqueue but
+// not yet indexed are still pending, unlike Len of the workqueue.
+type pendingKeys struct {
+	counts map[string]int
+	mutex  sync.Mutex
+}
+
+func newPendingKeys() *pendingKeys {
+	return &pendingKeys{counts: make(map[string]int)}
+}
+
+// add records that the given key was enqueued.
+func (p *pendingKeys) add(key string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.counts[key]++
+}
+
+// start records that indexing of the given key started, and it
+// returns the number of enqueues that it covers.
+func (p *pendingKeys) start(key string) int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.counts[key]
+}
+
+// finish records that indexing of the given key finished, and that it
+// covered the given number of enqueues. The key is still pending if
+// it was enqueued again while it was being indexed.
+func (p *pendingKeys) finish(key string, enqueues int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.counts[key] -= enqueues; p.counts[key] <= 0 {
+		delete(p.counts, key)
+	}
+}
+
+func (p *pendingKeys) len() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return len(p.counts)
+}
+
+// eventClock records the time of the most recent informer event.
+type eventClock struct {
+	last  time.Time
+	mutex sync.RWMutex
+}
+
+func (e *eventClock) tick() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.last = time.Now()
+}
+
+// time returns the time of the most recent event, or nil if no
+// event has been received.
+func (e *eventClock) time() *time.Time {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	if e.last.IsZero() {
+		return nil
+	}
+
+	result := e.last
+
+	return &result
+}
@@ -0,0 +1,37 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPendingKeys(t *testing.T) {
+	pending := newPendingKeys()
+
+	pending.add("flargle/blargle")
+	pending.add("flargle/blargle") // deduplicated by the workqueue
+	assert.Equal(t, 1, pending.len())
+
+	// Keys being indexed are still pending.
+	enqueues := pending.start("flargle/blargle")
+	assert.Equal(t, 1, pending.len())
+
+	pending.finish("flargle/blargle", enqueues)
+	assert.Equal(t, 0, pending.len())
+}
+
+func TestPendingKeys_enqueuedWhileIndexing(t *testing.T) {
+	pending := newPendingKeys()
+
+	pending.add("flargle/blargle")
+	enqueues := pending.start("flargle/blargle")
+	pending.add("flargle/blargle")
+	pending.finish("flargle/blargle", enqueues)
+
+	// It's pending until it's indexed again.
+	assert.Equal(t, 1, pending.len())
+
+	pending.finish("flargle/blargle", pending.start("flargle/blargle"))
+	assert.Equal(t, 0, pending.len())
+}
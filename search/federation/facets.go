@@ -0,0 +1,40 @@
+package federation
+
+import (
+	"github.com/kubideh/kubesearch/search/api"
+)
+
+const facetsParamName = "facets"
+
+// DimensionCluster counts federated hits by the peer, i.e., the
+// cluster, that returned them.
+const DimensionCluster = "cluster"
+
+// createFacets counts the given merged results by each of the given
+// dimensions.
+func createFacets(dimensions []string, results []Result) (facets []api.Facet) {
+	for _, d := range dimensions {
+		counts := make(map[string]int)
+
+		for _, r := range results {
+			if value := dimensionValue(d, r); value != "" {
+				counts[value]++
+			}
+		}
+
+		facets = append(facets, api.CreateFacet(d, counts))
+	}
+	return
+}
+
+func dimensionValue(dimension string, result Result) string {
+	switch dimension {
+	case api.DimensionKind:
+		return result.Kind
+	case api.DimensionNamespace:
+		return result.Namespace
+	case DimensionCluster:
+		return result.Peer
+	}
+	return ""
+}
@@ -0,0 +1,63 @@
+// Package federation provides the API for searching for Kubernetes
+// objects across multiple KubeSearch servers. A federated server
+// fans each query out to its peers, and then it merges and
+// re-ranks their results.
+package federation
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Search is the API used to query a federated KubeSearch server.
+func Search(endpoint, query string) (result Response, err error) {
+	return SearchWithClient(http.DefaultClient, endpoint, query, nil, nil)
+}
+
+// SearchWithClient is the same as Search, but the request is sent
+// using the given client, e.g., one that adds credentials. Only the
+// given namespaces are searched, if any, and the results are counted
+// by the given facet dimensions, if any. It returns an error unless
+// the server responds with a 2xx status.
+func SearchWithClient(client *http.Client, endpoint, query string, namespaces, facets []string) (result Response, err error) {
+	response, err := client.Get(searchURL(endpoint, query, namespaces, facets))
+
+	if err != nil {
+		return
+	}
+
+	body, err := ioutil.ReadAll(response.Body)
+	defer response.Body.Close()
+
+	if err != nil {
+		return
+	}
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		err = fmt.Errorf("unexpected status from %s: %s", response.Request.URL.Host, response.Status)
+		return
+	}
+
+	err = json.Unmarshal(body, &result)
+
+	return
+}
+
+func searchURL(endpoint, query string, namespaces, facets []string) string {
+	values := url.Values{}
+	values.Set(queryParamName, query)
+
+	for _, n := range namespaces {
+		values.Add(namespaceParamName, n)
+	}
+
+	if len(facets) > 0 {
+		values.Set(facetsParamName, strings.Join(facets, ","))
+	}
+
+	return fmt.Sprintf("%s%s?%s", endpoint, endpointPath, values.Encode())
+}
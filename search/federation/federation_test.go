@@ -0,0 +1,191 @@
+package federation
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kubideh/kubesearch/search/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearch_mergesAndNormalizesResultsFromPeers(t *testing.T) {
+	flargle := createPeer(t, []api.Result{
+		{Kind: "Pod", Name: "blargle", Namespace: "flargle", Rank: 2},
+		{Kind: "Pod", Name: "foo", Namespace: "flargle", Rank: 1},
+	})
+	defer flargle.Close()
+
+	bobble := createPeer(t, []api.Result{
+		{Kind: "Pod", Name: "blargle", Namespace: "bobble", Rank: 4},
+	})
+	defer bobble.Close()
+
+	server := setup(flargle.URL, bobble.URL)
+	defer server.Close()
+
+	response, err := Search(server.URL, "blargle")
+
+	assert.NoError(t, err)
+	assert.Empty(t, response.Failures)
+	assert.ElementsMatch(t, []Result{
+		{Result: api.Result{Kind: "Pod", Name: "blargle", Namespace: "bobble", Rank: 4}, Peer: bobble.URL, Score: 1},
+		{Result: api.Result{Kind: "Pod", Name: "blargle", Namespace: "flargle", Rank: 2}, Peer: flargle.URL, Score: 1},
+		{Result: api.Result{Kind: "Pod", Name: "foo", Namespace: "flargle", Rank: 1}, Peer: flargle.URL, Score: 0.5},
+	}, response.Results)
+	require.Len(t, response.Results, 3)
+	assert.Equal(t, 0.5, response.Results[2].Score)
+}
+
+func TestSearch_reportsFailingPeers(t *testing.T) {
+	flargle := createPeer(t, []api.Result{
+		{Kind: "Pod", Name: "blargle", Namespace: "flargle", Rank: 1},
+	})
+	defer flargle.Close()
+
+	failing := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	server := setup(flargle.URL, failing.URL)
+	defer server.Close()
+
+	response, err := Search(server.URL, "blargle")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Result{
+		{Result: api.Result{Kind: "Pod", Name: "blargle", Namespace: "flargle", Rank: 1}, Peer: flargle.URL, Score: 1},
+	}, response.Results)
+	require.Len(t, response.Failures, 1)
+	assert.Equal(t, failing.URL, response.Failures[0].Peer)
+}
+
+func TestSearch_reportsUnauthorizedPeers(t *testing.T) {
+	unauthorized := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		http.Error(writer, `{"results": [{"kind": "Pod", "name": "blargle"}]}`, http.StatusUnauthorized)
+	}))
+	defer unauthorized.Close()
+
+	server := setup(unauthorized.URL)
+	defer server.Close()
+
+	response, err := Search(server.URL, "blargle")
+
+	assert.NoError(t, err)
+	assert.Empty(t, response.Results)
+	require.Len(t, response.Failures, 1)
+	assert.Equal(t, unauthorized.URL, response.Failures[0].Peer)
+}
+
+func TestSearch_unexpectedStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		http.Error(writer, `{"results": [{"name": "blargle"}]}`, http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	response, err := Search(server.URL, "blargle")
+
+	assert.EqualError(t, err, "unexpected status from "+strings.TrimPrefix(server.URL, "http://")+": 401 Unauthorized")
+	assert.Empty(t, response.Results)
+}
+
+func TestSearch_reportsSlowPeers(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		time.Sleep(time.Second)
+	}))
+	defer slow.Close()
+
+	server := setup(slow.URL)
+	defer server.Close()
+
+	response, err := Search(server.URL, "blargle")
+
+	assert.NoError(t, err)
+	assert.Empty(t, response.Results)
+	require.Len(t, response.Failures, 1)
+	assert.Equal(t, slow.URL, response.Failures[0].Peer)
+}
+
+func TestSearch_facets(t *testing.T) {
+	flargle := createPeer(t, []api.Result{
+		{Kind: "Pod", Name: "blargle", Namespace: "flargle", Rank: 2},
+		{Kind: "Pod", Name: "foo", Namespace: "flargle", Rank: 1},
+	})
+	defer flargle.Close()
+
+	bobble := createPeer(t, []api.Result{
+		{Kind: "Deployment", Name: "blargle", Namespace: "bobble", Rank: 4},
+	})
+	defer bobble.Close()
+
+	server := setup(flargle.URL, bobble.URL)
+	defer server.Close()
+
+	response, err := SearchWithClient(http.DefaultClient, server.URL, "blargle", nil, []string{api.DimensionKind, DimensionCluster})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []api.Facet{
+		{Dimension: api.DimensionKind, Counts: []api.FacetCount{{Value: "Pod", Count: 2}, {Value: "Deployment", Count: 1}}},
+		{Dimension: DimensionCluster, Counts: []api.FacetCount{{Value: flargle.URL, Count: 2}, {Value: bobble.URL, Count: 1}}},
+	}, response.Facets)
+}
+
+func TestSearch_namespaces(t *testing.T) {
+	var namespaces []string
+	flargle := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		namespaces = api.ParseNamespaces(request)
+		require.NoError(t, json.NewEncoder(writer).Encode([]api.Result{{Kind: "Pod", Name: "blargle", Namespace: "flargle", Rank: 1}}))
+	}))
+	defer flargle.Close()
+
+	bobble := createPeer(t, []api.Result{
+		{Kind: "Pod", Name: "blargle", Namespace: "flargle", Rank: 2},
+		{Kind: "Pod", Name: "blargle", Namespace: "bobble", Rank: 1},
+		{Kind: "Node", Name: "blargle", Rank: 1},
+	})
+	defer bobble.Close()
+
+	server := setup(flargle.URL, bobble.URL)
+	defer server.Close()
+
+	response, err := SearchWithClient(http.DefaultClient, server.URL, "blargle", []string{"flargle"}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"flargle"}, namespaces, "peers search only the given namespaces")
+	assert.ElementsMatch(t, []Result{
+		{Result: api.Result{Kind: "Pod", Name: "blargle", Namespace: "flargle", Rank: 1}, Peer: flargle.URL, Score: 1},
+		{Result: api.Result{Kind: "Pod", Name: "blargle", Namespace: "flargle", Rank: 2}, Peer: bobble.URL, Score: 1},
+	}, response.Results, "results of peers that ignore the namespaces are left out")
+}
+
+func TestSearch_invalidFacet(t *testing.T) {
+	server := setup()
+	defer server.Close()
+
+	response, err := http.Get(server.URL + endpointPath + "?queryString=blargle&facets=node")
+	require.NoError(t, err)
+	defer response.Body.Close()
+
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+}
+
+func setup(peers ...string) *httptest.Server {
+	search := Create(peers, 100*time.Millisecond)
+	handler := CreateSearchHandler(search)
+	mux := http.NewServeMux()
+
+	RegisterSearchHandler(mux, handler)
+
+	return httptest.NewServer(mux)
+}
+
+func createPeer(t *testing.T, results []api.Result) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		require.NoError(t, json.NewEncoder(writer).Encode(results))
+	}))
+}
@@ -0,0 +1,61 @@
+package federation
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/kubideh/kubesearch/search/api"
+	"k8s.io/klog/v2"
+)
+
+const (
+	endpointPath       = "/v1/federation/search"
+	queryParamName     = "queryString"
+	namespaceParamName = "namespace"
+)
+
+// RegisterSearchHandler registers the federated search handler
+// with the given mux at the appropriate endpoint path.
+func RegisterSearchHandler(mux *http.ServeMux, handler http.HandlerFunc) {
+	mux.HandleFunc(endpointPath, handler)
+}
+
+// CreateSearchHandler is a `http.HandlerFunc` that responds with a
+// JSON-encoded Response based on the given query string. Peers search
+// only the namespaces given by the parameter `namespace`, if any. The
+// merged results are counted by kind, namespace and cluster if asked
+// for using the parameter `facets`.
+func CreateSearchHandler(search SearchFunc) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		dimensions, err := api.ParseDimensions(request.URL.Query().Get(facetsParamName), api.DimensionKind, api.DimensionNamespace, DimensionCluster)
+
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		response := search(request.Context(), queryString(request), api.ParseNamespaces(request))
+		response.Facets = createFacets(dimensions, response.Results)
+		writeResponse(writer, response)
+	}
+}
+
+func queryString(request *http.Request) string {
+	values, ok := request.URL.Query()[queryParamName]
+
+	if !ok || len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
+
+func writeResponse(writer http.ResponseWriter, response Response) {
+	writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	encoder := json.NewEncoder(writer)
+
+	if err := encoder.Encode(response); err != nil {
+		klog.Warningln("error marshaling response: ", err)
+	}
+}
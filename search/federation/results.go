@@ -0,0 +1,103 @@
+package federation
+
+import (
+	"sort"
+
+	"github.com/kubideh/kubesearch/search/api"
+)
+
+// Response is the merged response of a federated search. Peers
+// that failed or timed out are listed in Failures, and the results
+// of the remaining peers are still returned.
+type Response struct {
+	Results  []Result    `json:"results"`
+	Failures []Failure   `json:"failures,omitempty"`
+	Facets   []api.Facet `json:"facets,omitempty"`
+}
+
+// Result is a single result entry returned by a peer. Score is the
+// rank of the result normalized against the largest rank returned
+// by the same peer, so that results from different peers can be
+// compared.
+type Result struct {
+	api.Result
+	Peer  string  `json:"peer,omitempty"`
+	Score float64 `json:"score,omitempty"`
+}
+
+// Failure describes a peer that could not be searched.
+type Failure struct {
+	Peer  string `json:"peer"`
+	Error string `json:"error"`
+}
+
+func normalizeResults(peer string, results []api.Result) []Result {
+	normalized := make([]Result, 0, len(results))
+	largest := largestRank(results)
+
+	for _, r := range results {
+		normalized = append(normalized, Result{
+			Result: r,
+			Peer:   peer,
+			Score:  normalizeRank(r.Rank, largest),
+		})
+	}
+
+	return normalized
+}
+
+func largestRank(results []api.Result) (result int) {
+	for _, r := range results {
+		if r.Rank > result {
+			result = r.Rank
+		}
+	}
+	return
+}
+
+func normalizeRank(rank, largest int) float64 {
+	if largest == 0 {
+		return 0
+	}
+	return float64(rank) / float64(largest)
+}
+
+// ResultsList is a list of Result objects sorted by largest Score
+// and then by peer, kind, namespace and name.
+type ResultsList []Result
+
+func (r ResultsList) Len() int {
+	return len(r)
+}
+
+func (r ResultsList) Swap(i, j int) {
+	r[i], r[j] = r[j], r[i]
+}
+
+func (r ResultsList) Less(i, j int) bool {
+	if r[i].Score != r[j].Score {
+		return r[j].Score < r[i].Score // Large scores should come before small scores
+	}
+	if r[i].Peer != r[j].Peer {
+		return r[i].Peer < r[j].Peer
+	}
+	if r[i].Kind != r[j].Kind {
+		return r[i].Kind < r[j].Kind
+	}
+	if r[i].Namespace != r[j].Namespace {
+		return r[i].Namespace < r[j].Namespace
+	}
+	return r[i].Name < r[j].Name
+}
+
+func mergeResults(results ...[]Result) []Result {
+	merged := make([]Result, 0)
+
+	for _, r := range results {
+		merged = append(merged, r...)
+	}
+
+	sort.Sort(ResultsList(merged))
+
+	return merged
+}
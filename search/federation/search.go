@@ -0,0 +1,112 @@
+package federation
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kubideh/kubesearch/search/api"
+)
+
+// SearchFunc is a federated search function. Only objects in the
+// given namespaces are searched, or every object if none is given.
+type SearchFunc func(ctx context.Context, query string, namespaces []string) Response
+
+// Create returns the default federated search functor. Each query
+// is sent to every peer concurrently, and each peer must respond
+// within the given timeout or it's reported as a failure.
+func Create(peers []string, timeout time.Duration) SearchFunc {
+	return func(ctx context.Context, query string, namespaces []string) Response {
+		responses := make(chan peerResponse, len(peers))
+
+		for _, p := range peers {
+			go searchPeer(ctx, responses, p, query, namespaces, timeout)
+		}
+
+		return collectResponses(responses, len(peers), namespaces)
+	}
+}
+
+// peerResponse is the outcome of searching a single peer.
+type peerResponse struct {
+	peer    string
+	results []api.Result
+	err     error
+}
+
+// searchPeer sends the query to the given peer, which searches only
+// the given namespaces.
+func searchPeer(ctx context.Context, responses chan<- peerResponse, peer, query string, namespaces []string, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	results, err := api.SearchWithOptions(ctx, http.DefaultClient, peerEndpoint(peer), query, api.Options{Namespaces: namespaces})
+
+	responses <- peerResponse{
+		peer:    peer,
+		results: results,
+		err:     err,
+	}
+}
+
+// peerEndpoint returns the given peer as an endpoint that can be
+// used by api.Search. Peers given without a scheme use `http://`.
+func peerEndpoint(peer string) string {
+	if strings.Contains(peer, "://") {
+		return peer
+	}
+	return "http://" + peer
+}
+
+// collectResponses merges the results of the given number of peers.
+// Results outside the given namespaces are left out, in case a peer
+// predates the parameter `namespace` and ignored it.
+func collectResponses(responses <-chan peerResponse, count int, namespaces []string) (result Response) {
+	results := make([][]Result, 0, count)
+
+	for i := 0; i < count; i++ {
+		response := <-responses
+
+		if response.err != nil {
+			result.Failures = append(result.Failures, Failure{Peer: response.peer, Error: response.err.Error()})
+			continue
+		}
+
+		results = append(results, normalizeResults(response.peer, retainNamespaces(response.results, namespaces)))
+	}
+
+	sort.Slice(result.Failures, func(i, j int) bool {
+		return result.Failures[i].Peer < result.Failures[j].Peer
+	})
+
+	result.Results = mergeResults(results...)
+
+	return
+}
+
+// retainNamespaces returns the given results of objects in any of
+// the given namespaces, or every result if no namespace is given, the
+// same as api.RetainNamespaces does for postings.
+func retainNamespaces(results []api.Result, namespaces []string) []api.Result {
+	if len(namespaces) == 0 {
+		return results
+	}
+
+	retained := make(map[string]bool, len(namespaces))
+
+	for _, n := range namespaces {
+		retained[n] = true
+	}
+
+	result := make([]api.Result, 0, len(results))
+
+	for _, r := range results {
+		if retained[r.Namespace] {
+			result = append(result, r)
+		}
+	}
+
+	return result
+}
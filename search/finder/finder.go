@@ -1,9 +1,10 @@
 package finder
 
 import (
-	"fmt"
+	"github.com/kubideh/kubesearch/search/metrics"
 
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
 )
 
 // XXX This finder is actually a Gateway. Maybe refactor into Active Records.s
@@ -22,7 +23,10 @@ type Key struct {
 type FindAllFunc func(keys []Key) ([]K8sObject, error)
 
 // Create returns the default functor that finds all objects for
-// the given Kubernetes object store `store`.
+// the given Kubernetes object store `store`. Keys whose objects are
+// missing, e.g., because they were deleted after they were searched,
+// are skipped, so the results are in the order of the keys, but
+// there may be fewer of them.
 func Create(store map[string]cache.Store) FindAllFunc {
 	return func(keys []Key) ([]K8sObject, error) {
 		var results []K8sObject
@@ -35,8 +39,9 @@ func Create(store map[string]cache.Store) FindAllFunc {
 			}
 
 			if !exists {
-				// XXX: Ignore because the object might have been deleted.
-				return results, fmt.Errorf("missing object for key %v", k.StoredObjectKey)
+				metrics.IncFinderMisses(k.K8sResourceKind)
+				klog.V(2).Infof("Skipping the %s %s, because it's missing", k.K8sResourceKind, k.StoredObjectKey)
+				continue
 			}
 
 			object := K8sObject{
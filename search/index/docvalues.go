@@ -0,0 +1,101 @@
+package index
+
+import (
+	"strings"
+)
+
+// Fields of documents that are stored as doc values, and by which
+// postings may be sorted.
+const (
+	DocValueName              = "name"
+	DocValueNamespace         = "namespace"
+	DocValueCreationTimestamp = "creationTimestamp"
+	DocValueRestartCount      = "restartCount"
+	DocValueReplicas          = "replicas"
+	DocValueCPU               = "cpu"
+	DocValueMemory            = "memory"
+)
+
+// DocValueFields returns every field stored as a doc value.
+func DocValueFields() []string {
+	return []string{
+		DocValueName,
+		DocValueNamespace,
+		DocValueCreationTimestamp,
+		DocValueRestartCount,
+		DocValueReplicas,
+		DocValueCPU,
+		DocValueMemory,
+	}
+}
+
+// DocValues maps fields of a single document to their values.
+type DocValues map[string]Value
+
+// Value is a doc value, and it's either a string or a number. The
+// zero Value is missing, e.g., the replicas of a Pod.
+type Value struct {
+	String  string `json:"s,omitempty"`
+	Number  int64  `json:"n,omitempty"`
+	Present bool   `json:"p,omitempty"`
+}
+
+// StringValue returns a Value that holds the given string.
+func StringValue(s string) Value {
+	return Value{String: s, Present: true}
+}
+
+// NumberValue returns a Value that holds the given number.
+func NumberValue(n int64) Value {
+	return Value{Number: n, Present: true}
+}
+
+// Compare returns -1, 0 or +1 depending on whether v is less than,
+// equal to, or greater than other. Both Values must be present.
+func (v Value) Compare(other Value) int {
+	switch {
+	case v.Number < other.Number:
+		return -1
+	case v.Number > other.Number:
+		return 1
+	}
+	return strings.Compare(v.String, other.String)
+}
+
+// PutDocValues stores the given values of the document of the given
+// posting, and it replaces any values stored before. Doc values are
+// stored column-wise, i.e., by field and then by document.
+func (idx *Index) PutDocValues(posting Posting, values DocValues) {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+
+	id := posting.DocID().String()
+
+	for field, column := range idx.docValues {
+		if _, ok := values[field]; !ok {
+			delete(column, id)
+		}
+	}
+
+	for field, value := range values {
+		column, ok := idx.docValues[field]
+
+		if !ok {
+			column = make(map[string]Value)
+			idx.docValues[field] = column
+		}
+
+		column[id] = value
+	}
+
+	idx.putRanges(posting, values)
+}
+
+// DocValue returns the value of the given field of the document of
+// the given posting. The Value is missing if none was stored.
+func (idx *Index) DocValue(field string, posting Posting) Value {
+	idx.mutex.RLock()
+	defer idx.mutex.RUnlock()
+
+	return idx.docValues[field][posting.DocID().String()]
+}
@@ -0,0 +1,28 @@
+package index
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDocValues(t *testing.T) {
+	idx := Create()
+	posting := Posting{StoredObjectKey: "flargle/blargle", K8sResourceKind: "Pod"}
+
+	idx.PutDocValues(posting, DocValues{DocValueName: StringValue("blargle"), DocValueRestartCount: NumberValue(3)})
+
+	assert.Equal(t, StringValue("blargle"), idx.DocValue(DocValueName, posting))
+	assert.Equal(t, NumberValue(3), idx.DocValue(DocValueRestartCount, posting))
+	assert.Equal(t, Value{}, idx.DocValue(DocValueReplicas, posting))
+
+	idx.PutDocValues(posting, DocValues{DocValueName: StringValue("blargle")})
+
+	assert.Equal(t, Value{}, idx.DocValue(DocValueRestartCount, posting), "values that are no longer given are removed")
+}
+
+func TestValueCompare(t *testing.T) {
+	assert.Equal(t, -1, NumberValue(1).Compare(NumberValue(2)))
+	assert.Equal(t, 1, StringValue("b").Compare(StringValue("a")))
+	assert.Equal(t, 0, StringValue("a").Compare(StringValue("a")))
+}
@@ -0,0 +1,86 @@
+package index
+
+import "strings"
+
+// Prefixes of the terms under which fields of objects are indexed,
+// so that field clauses such as `image:nginx` or `label:app=web`
+// match exactly instead of as text.
+const (
+	TermPrefixNamespace = "ns:"
+	TermPrefixLabel     = "label:"
+	TermPrefixImage     = "image:"
+)
+
+// NamespaceTerm returns the term of objects in the given namespace.
+func NamespaceTerm(namespace string) string {
+	return TermPrefixNamespace + namespace
+}
+
+// LabelTerms returns the terms of an object with the given label,
+// i.e., `label:<key>=<value>`, and `label:<key>` unless the value is
+// empty.
+func LabelTerms(key, value string) []string {
+	result := []string{TermPrefixLabel + key + "=" + value}
+
+	if value != "" {
+		result = append(result, LabelKeyTerm(key))
+	}
+
+	return result
+}
+
+// LabelKeyTerm returns the term of objects that have a label with
+// the given key and a value that isn't empty.
+func LabelKeyTerm(key string) string {
+	return TermPrefixLabel + key
+}
+
+// ImageTerms returns the terms of an object with a container of the
+// given image, e.g., `docker.io/library/nginx:1.21` has the terms of
+// the image itself, of its repository `docker.io/library/nginx` and
+// its last path segment `nginx`, each with and without the tag, and
+// of the tag `1.21`. The tag is `latest` unless a tag or a digest is
+// given, so `nginx` has the terms of `nginx:latest` and `latest` too.
+func ImageTerms(image string) []string {
+	repository, tag := image, ""
+
+	if i := strings.Index(repository, "@"); i >= 0 {
+		repository = repository[:i]
+	} else if i := strings.LastIndex(repository, ":"); i > strings.LastIndex(repository, "/") {
+		repository, tag = repository[:i], repository[i+1:]
+	} else {
+		tag = "latest"
+	}
+
+	names := []string{repository}
+
+	if i := strings.LastIndex(repository, "/"); i >= 0 {
+		names = append(names, repository[i+1:])
+	}
+
+	values := []string{image}
+
+	for _, name := range names {
+		values = append(values, name)
+
+		if tag != "" {
+			values = append(values, name+":"+tag)
+		}
+	}
+
+	if tag != "" {
+		values = append(values, tag)
+	}
+
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, TermPrefixImage+v)
+		}
+	}
+
+	return result
+}
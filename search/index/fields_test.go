@@ -0,0 +1,39 @@
+package index
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImageTerms(t *testing.T) {
+	cases := []struct {
+		image string
+		terms []string
+	}{
+		{image: "nginx", terms: []string{"image:nginx", "image:nginx:latest", "image:latest"}},
+		{image: "nginx:1.21", terms: []string{"image:nginx:1.21", "image:nginx", "image:1.21"}},
+		{image: "docker.io/library/nginx:1.21", terms: []string{
+			"image:docker.io/library/nginx:1.21",
+			"image:docker.io/library/nginx",
+			"image:nginx",
+			"image:nginx:1.21",
+			"image:1.21",
+		}},
+		{image: "localhost:5000/payment", terms: []string{"image:localhost:5000/payment", "image:localhost:5000/payment:latest", "image:payment", "image:payment:latest", "image:latest"}},
+		{image: "nginx@sha256:0123", terms: []string{"image:nginx@sha256:0123", "image:nginx"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.image, func(t *testing.T) {
+			assert.Equal(t, c.terms, ImageTerms(c.image))
+		})
+	}
+}
+
+func TestLabelTerms(t *testing.T) {
+	assert.Equal(t, []string{"label:app=web", "label:app"}, LabelTerms("app", "web"))
+	assert.Equal(t, []string{"label:owner="}, LabelTerms("owner", ""))
+	assert.Equal(t, "label:owner", LabelKeyTerm("owner"))
+	assert.Equal(t, "ns:prod", NamespaceTerm("prod"))
+}
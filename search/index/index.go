@@ -7,10 +7,15 @@ import (
 	"sync"
 )
 
-// Index maps terms to object keys.
+// Index maps terms to object keys, and it stores the doc values of
+// each object, including numeric columns used by range queries.
 type Index struct {
-	index map[string][]Posting
-	mutex sync.RWMutex
+	index     map[string][]Posting
+	terms     map[string][]string // terms of each document, by DocID
+	documents map[string]Posting  // documents are a posting of each document, by DocID
+	docValues map[string]map[string]Value
+	ranges    map[string]*numericColumn
+	mutex     sync.RWMutex
 }
 
 // Put adds a posting to the search index for each of the given
@@ -37,6 +42,97 @@ func (idx *Index) putOne(term string, posting Posting) {
 	sort.Sort(PostingsList(postings))
 
 	idx.index[term] = postings
+
+	id := posting.DocID().String()
+	idx.terms[id] = append(idx.terms[id], term)
+
+	posting.TermFrequency = 0
+	idx.documents[id] = posting
+}
+
+// Update replaces the terms under which the document of the given
+// posting is indexed, e.g., because the labels of its object were
+// changed. Its doc values are kept.
+func (idx *Index) Update(terms []string, posting Posting) {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+
+	id := posting.DocID().String()
+	updated := make(map[string]bool, len(terms))
+
+	for _, t := range terms {
+		updated[t] = true
+	}
+
+	var kept []string
+
+	for _, term := range idx.terms[id] {
+		if updated[term] {
+			kept = append(kept, term)
+		} else {
+			idx.removeOne(term, posting)
+		}
+	}
+
+	idx.terms[id] = kept
+
+	for _, t := range terms {
+		idx.putOne(t, posting)
+	}
+}
+
+// Remove removes the postings, doc values and numeric columns of the
+// document of the given posting, e.g., because its object was
+// deleted. Terms that have no postings left are removed too.
+func (idx *Index) Remove(posting Posting) {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+
+	id := posting.DocID().String()
+
+	for _, term := range idx.terms[id] {
+		idx.removeOne(term, posting)
+	}
+
+	delete(idx.terms, id)
+	delete(idx.documents, id)
+
+	for _, column := range idx.docValues {
+		delete(column, id)
+	}
+
+	for _, column := range idx.ranges {
+		column.remove(posting)
+	}
+}
+
+// removeOne removes the posting of the document of the given posting
+// from the given term, and it removes the term if it has no postings
+// left.
+func (idx *Index) removeOne(term string, posting Posting) {
+	postings := remove(idx.index[term], posting)
+
+	if len(postings) == 0 {
+		delete(idx.index, term)
+		return
+	}
+
+	idx.index[term] = postings
+}
+
+// remove returns a copy of the given postings without that of the
+// document of the given item, so that slices returned by Get aren't
+// modified.
+func remove(postings []Posting, item Posting) []Posting {
+	result := make([]Posting, 0, len(postings))
+
+	for _, p := range postings {
+		if p.DocID() != item.DocID() {
+			result = append(result, p)
+		}
+	}
+
+	return result
 }
 
 func contains(postings []Posting, item Posting) bool {
@@ -57,9 +153,54 @@ func (idx *Index) Get(term string) []Posting {
 	return idx.index[term]
 }
 
+// Documents returns a posting of every document in the search index,
+// sorted by DocID, and without term frequencies.
+func (idx *Index) Documents() []Posting {
+	idx.mutex.RLock()
+	defer idx.mutex.RUnlock()
+
+	result := make([]Posting, 0, len(idx.documents))
+
+	for _, p := range idx.documents {
+		result = append(result, p)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].DocID().String() < result[j].DocID().String()
+	})
+
+	return result
+}
+
+// TermCount returns the number of distinct terms in the search
+// index.
+func (idx *Index) TermCount() int {
+	idx.mutex.RLock()
+	defer idx.mutex.RUnlock()
+
+	return len(idx.index)
+}
+
+// PostingCount returns the total number of postings in the search
+// index.
+func (idx *Index) PostingCount() (result int) {
+	idx.mutex.RLock()
+	defer idx.mutex.RUnlock()
+
+	for _, postings := range idx.index {
+		result += len(postings)
+	}
+
+	return
+}
+
 // Create returns InvertedIndex objects.
 func Create() *Index {
 	return &Index{
-		index: make(map[string][]Posting),
+		index:     make(map[string][]Posting),
+		terms:     make(map[string][]string),
+		documents: make(map[string]Posting),
+		docValues: make(map[string]map[string]Value),
+		ranges:    make(map[string]*numericColumn),
 	}
 }
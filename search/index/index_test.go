@@ -0,0 +1,77 @@
+package index
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCounts(t *testing.T) {
+	idx := Create()
+	idx.Put([]string{"flargle", "blargle"}, Posting{StoredObjectKey: "flargle/blargle", K8sResourceKind: "Pod"})
+	idx.Put([]string{"flargle", "foo"}, Posting{StoredObjectKey: "flargle/foo", K8sResourceKind: "Pod"})
+
+	assert.Equal(t, 3, idx.TermCount())
+	assert.Equal(t, 4, idx.PostingCount())
+}
+
+func TestRemove(t *testing.T) {
+	idx := Create()
+	blargle := Posting{StoredObjectKey: "flargle/blargle", K8sResourceKind: "Pod"}
+	foo := Posting{StoredObjectKey: "flargle/foo", K8sResourceKind: "Pod"}
+
+	idx.Put([]string{"flargle", "blargle"}, blargle)
+	idx.Put([]string{"flargle", "foo"}, foo)
+	idx.PutDocValues(blargle, DocValues{DocValueName: StringValue("blargle"), DocValueRestartCount: NumberValue(3)})
+
+	postings := idx.Get("flargle")
+
+	idx.Remove(blargle)
+
+	assert.Equal(t, []Posting{{StoredObjectKey: "flargle/foo", K8sResourceKind: "Pod", TermFrequency: 1}}, idx.Get("flargle"))
+	assert.Empty(t, idx.Get("blargle"))
+	assert.Equal(t, 2, idx.TermCount(), "terms without postings are removed")
+	assert.Equal(t, 2, idx.PostingCount())
+	assert.Equal(t, Value{}, idx.DocValue(DocValueName, blargle))
+	assert.Empty(t, idx.Range(DocValueRestartCount, Unbounded()))
+	assert.Len(t, postings, 2, "postings returned before are left as is")
+
+	// Removing a document that isn't indexed does nothing.
+	idx.Remove(blargle)
+	assert.Equal(t, 2, idx.PostingCount())
+}
+
+func TestUpdate(t *testing.T) {
+	idx := Create()
+	blargle := Posting{StoredObjectKey: "flargle/blargle", K8sResourceKind: "Pod"}
+
+	idx.Put([]string{"blargle", "label:app=web"}, blargle)
+	idx.PutDocValues(blargle, DocValues{DocValueRestartCount: NumberValue(3)})
+	postings := idx.Get("label:app=web")
+
+	idx.Update([]string{"blargle", "label:app=api"}, blargle)
+
+	assert.Empty(t, idx.Get("label:app=web"), "terms that aren't given are removed")
+	assert.Equal(t, []Posting{blargle}, idx.Get("label:app=api"))
+	assert.Equal(t, []Posting{{StoredObjectKey: "flargle/blargle", K8sResourceKind: "Pod", TermFrequency: 1}}, idx.Get("blargle"))
+	assert.Equal(t, 2, idx.TermCount())
+	assert.Equal(t, NumberValue(3), idx.DocValue(DocValueRestartCount, blargle), "doc values are kept")
+	assert.Len(t, postings, 1, "postings returned before are left as is")
+
+	idx.Remove(blargle)
+	assert.Empty(t, idx.Get("label:app=api"))
+}
+
+func TestDocuments(t *testing.T) {
+	idx := Create()
+	blargle := Posting{StoredObjectKey: "flargle/blargle", K8sResourceKind: "Pod"}
+	foo := Posting{StoredObjectKey: "flargle/foo", K8sResourceKind: "Deployment"}
+
+	idx.Put([]string{"flargle", "blargle"}, blargle)
+	idx.Put([]string{"flargle", "foo"}, foo)
+
+	assert.Equal(t, []Posting{foo, blargle}, idx.Documents())
+
+	idx.Remove(foo)
+	assert.Equal(t, []Posting{blargle}, idx.Documents())
+}
@@ -30,20 +30,71 @@ func (p Posting) DocID() DocID {
 	return DocID{id: fmt.Sprintf("%s/%s", p.K8sResourceKind, p.StoredObjectKey)}
 }
 
+// Fields of a Posting in which terms are counted.
+const (
+	FieldKind      = "kind"
+	FieldNamespace = "metadata.namespace"
+	FieldName      = "metadata.name"
+)
+
 // ComputeTermFrequency returns the number of times term appears in
 // the given Posting.
 func (p Posting) ComputeTermFrequency(term string) int {
 	result := 0
 
+	for _, frequency := range p.ComputeFieldTermFrequencies(term) {
+		result += frequency
+	}
+
+	return result
+}
+
+// ComputeFieldTermFrequencies returns the number of times term
+// appears in each field of the given Posting. Fields in which term
+// doesn't appear are omitted.
+func (p Posting) ComputeFieldTermFrequencies(term string) map[string]int {
+	result := make(map[string]int)
+
 	if p.K8sResourceKind == term {
-		result++
+		result[FieldKind] = 1
 	}
 
-	result += strings.Count(p.StoredObjectKey, term) // XXX: this will break unless the StoredObjectKey is split properly
+	namespace, name := p.splitStoredObjectKey()
+
+	// XXX: Substrings are counted, not tokens.
+	if n := strings.Count(namespace, term); n > 0 {
+		result[FieldNamespace] = n
+	}
+
+	if n := strings.Count(name, term); n > 0 {
+		result[FieldName] = n
+	}
 
 	return result
 }
 
+// Namespace returns the namespace of the object of the given
+// Posting, or an empty string if the object is cluster-scoped.
+func (p Posting) Namespace() string {
+	namespace, _ := p.splitStoredObjectKey()
+	return namespace
+}
+
+// Name returns the name of the object of the given Posting.
+func (p Posting) Name() string {
+	_, name := p.splitStoredObjectKey()
+	return name
+}
+
+// splitStoredObjectKey returns the namespace, if any, and the name
+// of the stored object key of the given Posting.
+func (p Posting) splitStoredObjectKey() (namespace, name string) {
+	if i := strings.Index(p.StoredObjectKey, "/"); i >= 0 {
+		return p.StoredObjectKey[:i], p.StoredObjectKey[i+1:]
+	}
+	return "", p.StoredObjectKey
+}
+
 // PostingsList is a list of Posting objects. When used in an
 // index, the list is sorted by largest TermFrequency and then
 // DocID.
@@ -59,3 +59,24 @@ func TestSortPostings(t *testing.T) {
 
 	assert.Equal(t, expected, postings)
 }
+
+func TestFieldTermFrequencies(t *testing.T) {
+	posting := Posting{
+		StoredObjectKey: "flargle/blargle-flargle",
+		K8sResourceKind: "flargle",
+	}
+
+	assert.Equal(t, map[string]int{FieldKind: 1, FieldNamespace: 1, FieldName: 1}, posting.ComputeFieldTermFrequencies("flargle"))
+	assert.Equal(t, map[string]int{FieldName: 1}, posting.ComputeFieldTermFrequencies("blargle"))
+	assert.Equal(t, 3, posting.ComputeTermFrequency("flargle"))
+}
+
+func TestNamespace(t *testing.T) {
+	assert.Equal(t, "flargle", Posting{StoredObjectKey: "flargle/blargle", K8sResourceKind: "Pod"}.Namespace())
+	assert.Equal(t, "", Posting{StoredObjectKey: "blargle", K8sResourceKind: "Node"}.Namespace())
+}
+
+func TestName(t *testing.T) {
+	assert.Equal(t, "blargle", Posting{StoredObjectKey: "flargle/blargle", K8sResourceKind: "Pod"}.Name())
+	assert.Equal(t, "blargle", Posting{StoredObjectKey: "blargle", K8sResourceKind: "Node"}.Name())
+}
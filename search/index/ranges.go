@@ -0,0 +1,174 @@
+package index
+
+import (
+	"math"
+	"sort"
+)
+
+// FieldType is the type of a field that may be queried by range.
+type FieldType string
+
+// Types of fields that may be queried by range.
+const (
+	FieldTypeInteger   FieldType = "integer"   // e.g., `restarts:>5`
+	FieldTypeTimestamp FieldType = "timestamp" // e.g., `created:>2021-10-01`
+	FieldTypeAge       FieldType = "age"       // e.g., `age:<1h`, i.e., the time since a timestamp
+	FieldTypeQuantity  FieldType = "quantity"  // e.g., `cpu:>500m`
+)
+
+// NumericField is a field that may be queried by range. The value
+// of the field is the number held by a doc value.
+type NumericField struct {
+	Name     string    // Name is used in queries
+	DocValue string    // DocValue holds the value of the field
+	Type     FieldType // Type determines how values given in queries are parsed
+	Milli    bool      // Milli is true if quantities are stored in thousandths, e.g., millicores
+}
+
+// NumericFields returns every field that may be queried by range.
+func NumericFields() []NumericField {
+	return []NumericField{
+		{Name: "restarts", DocValue: DocValueRestartCount, Type: FieldTypeInteger},
+		{Name: "replicas", DocValue: DocValueReplicas, Type: FieldTypeInteger},
+		{Name: "created", DocValue: DocValueCreationTimestamp, Type: FieldTypeTimestamp},
+		{Name: "age", DocValue: DocValueCreationTimestamp, Type: FieldTypeAge},
+		{Name: "cpu", DocValue: DocValueCPU, Type: FieldTypeQuantity, Milli: true},
+		{Name: "memory", DocValue: DocValueMemory, Type: FieldTypeQuantity},
+	}
+}
+
+// isNumericDocValue returns true if any NumericField is held by the
+// given doc value.
+func isNumericDocValue(docValue string) bool {
+	for _, f := range NumericFields() {
+		if f.DocValue == docValue {
+			return true
+		}
+	}
+	return false
+}
+
+// Range is an inclusive range of numbers.
+type Range struct {
+	Min int64
+	Max int64
+}
+
+// Unbounded returns a Range that includes every number.
+func Unbounded() Range {
+	return Range{Min: math.MinInt64, Max: math.MaxInt64}
+}
+
+// Contains returns true if the given number is within r.
+func (r Range) Contains(n int64) bool {
+	return r.Min <= n && n <= r.Max
+}
+
+// numericColumn holds the numeric doc values of a single field, and
+// its entries are sorted by value and then by DocID so that ranges
+// are found using binary search.
+type numericColumn struct {
+	entries []numericEntry
+	values  map[string]int64
+}
+
+type numericEntry struct {
+	value   int64
+	posting Posting
+}
+
+func newNumericColumn() *numericColumn {
+	return &numericColumn{values: make(map[string]int64)}
+}
+
+func (c *numericColumn) put(posting Posting, value int64) {
+	c.remove(posting)
+
+	entry := numericEntry{value: value, posting: posting}
+	i := sort.Search(len(c.entries), func(i int) bool {
+		return !c.entries[i].less(entry)
+	})
+
+	c.entries = append(c.entries, numericEntry{})
+	copy(c.entries[i+1:], c.entries[i:])
+	c.entries[i] = entry
+	c.values[posting.DocID().String()] = value
+}
+
+func (c *numericColumn) remove(posting Posting) {
+	id := posting.DocID().String()
+	value, ok := c.values[id]
+
+	if !ok {
+		return
+	}
+
+	i := sort.Search(len(c.entries), func(i int) bool {
+		return !c.entries[i].less(numericEntry{value: value, posting: posting})
+	})
+
+	c.entries = append(c.entries[:i], c.entries[i+1:]...)
+	delete(c.values, id)
+}
+
+func (c *numericColumn) find(r Range) []Posting {
+	start := sort.Search(len(c.entries), func(i int) bool {
+		return c.entries[i].value >= r.Min
+	})
+
+	var result []Posting
+
+	for i := start; i < len(c.entries) && c.entries[i].value <= r.Max; i++ {
+		result = append(result, c.entries[i].posting)
+	}
+
+	return result
+}
+
+func (e numericEntry) less(other numericEntry) bool {
+	if e.value == other.value {
+		return e.posting.DocID().String() < other.posting.DocID().String()
+	}
+	return e.value < other.value
+}
+
+// Range returns the postings of every document whose given numeric
+// doc value is within the given range.
+func (idx *Index) Range(docValue string, r Range) []Posting {
+	idx.mutex.RLock()
+	defer idx.mutex.RUnlock()
+
+	column, ok := idx.ranges[docValue]
+
+	if !ok {
+		return nil
+	}
+
+	return column.find(r)
+}
+
+// putRanges updates the numeric columns using the given doc values
+// of the document of the given posting. The caller must hold the
+// lock of the index.
+func (idx *Index) putRanges(posting Posting, values DocValues) {
+	for field, column := range idx.ranges {
+		if _, ok := values[field]; !ok {
+			column.remove(posting)
+		}
+	}
+
+	for field, value := range values {
+		if !value.Present || !isNumericDocValue(field) {
+			continue
+		}
+
+		column, ok := idx.ranges[field]
+
+		if !ok {
+			column = newNumericColumn()
+			idx.ranges[field] = column
+		}
+
+		column.put(posting, value.Number)
+	}
+}
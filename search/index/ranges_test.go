@@ -0,0 +1,41 @@
+package index
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRange(t *testing.T) {
+	idx := Create()
+
+	a := Posting{StoredObjectKey: "flargle/a", K8sResourceKind: "Pod"}
+	b := Posting{StoredObjectKey: "flargle/b", K8sResourceKind: "Pod"}
+	c := Posting{StoredObjectKey: "flargle/c", K8sResourceKind: "Pod"}
+
+	idx.PutDocValues(a, DocValues{DocValueRestartCount: NumberValue(0), DocValueName: StringValue("a")})
+	idx.PutDocValues(b, DocValues{DocValueRestartCount: NumberValue(7)})
+	idx.PutDocValues(c, DocValues{DocValueRestartCount: NumberValue(5)})
+
+	assert.Equal(t, []Posting{c, b}, idx.Range(DocValueRestartCount, Range{Min: 5, Max: 10}))
+	assert.Equal(t, []Posting{a}, idx.Range(DocValueRestartCount, Range{Min: 0, Max: 0}))
+	assert.Equal(t, []Posting{a, c, b}, idx.Range(DocValueRestartCount, Unbounded()))
+	assert.Empty(t, idx.Range(DocValueName, Unbounded()), "strings aren't numeric")
+	assert.Empty(t, idx.Range(DocValueReplicas, Unbounded()))
+}
+
+func TestRange_updatesReplaceValues(t *testing.T) {
+	idx := Create()
+
+	a := Posting{StoredObjectKey: "flargle/a", K8sResourceKind: "Pod"}
+
+	idx.PutDocValues(a, DocValues{DocValueRestartCount: NumberValue(1)})
+	idx.PutDocValues(a, DocValues{DocValueRestartCount: NumberValue(9)})
+
+	assert.Empty(t, idx.Range(DocValueRestartCount, Range{Min: 0, Max: 5}))
+	assert.Equal(t, []Posting{a}, idx.Range(DocValueRestartCount, Range{Min: 6, Max: 10}))
+
+	idx.PutDocValues(a, DocValues{})
+
+	assert.Empty(t, idx.Range(DocValueRestartCount, Unbounded()))
+}
@@ -0,0 +1,128 @@
+package local
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// Stdin is the path from which manifests are read from stdin, e.g.,
+// the output of `helm template` or `kustomize build`.
+const Stdin = "-"
+
+// manifestExtensions are those of the files read from directories.
+var manifestExtensions = map[string]bool{".yaml": true, ".yml": true, ".json": true}
+
+// Load decodes the objects of the manifests in the given paths. Each
+// path is a file, a directory that's walked for .yaml, .yml and .json
+// files, or Stdin. Files may have many YAML documents or JSON values,
+// and the items of lists are decoded as objects.
+func Load(paths []string, stdin io.Reader) (result []*unstructured.Unstructured, err error) {
+	for _, path := range paths {
+		var objects []*unstructured.Unstructured
+
+		if path == Stdin {
+			objects, err = decode(Stdin, stdin)
+		} else {
+			objects, err = loadPath(path)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, objects...)
+	}
+
+	return
+}
+
+func loadPath(path string) (result []*unstructured.Unstructured, err error) {
+	err = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// Files given explicitly are read whatever their extension.
+		if entry.IsDir() || (file != path && !manifestExtensions[strings.ToLower(filepath.Ext(file))]) {
+			return nil
+		}
+
+		data, err := os.ReadFile(file)
+
+		if err != nil {
+			return err
+		}
+
+		objects, err := decode(file, bytes.NewReader(data))
+		result = append(result, objects...)
+
+		return err
+	})
+
+	return
+}
+
+// decode returns the objects of every document read from the given
+// reader. Empty documents, e.g., those of templates that rendered
+// nothing, are skipped.
+func decode(source string, reader io.Reader) (result []*unstructured.Unstructured, err error) {
+	decoder := yaml.NewYAMLOrJSONDecoder(reader, 4096)
+
+	for document := 1; ; document++ {
+		var content map[string]interface{}
+
+		if err := decoder.Decode(&content); errors.Is(err, io.EOF) {
+			return result, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("%s: document %d: %w", source, document, err)
+		}
+
+		if len(content) == 0 {
+			continue
+		}
+
+		objects, err := objectsOf(&unstructured.Unstructured{Object: content})
+
+		if err != nil {
+			return nil, fmt.Errorf("%s: document %d: %w", source, document, err)
+		}
+
+		result = append(result, objects...)
+	}
+}
+
+// objectsOf returns the given object, or the items of the given list.
+func objectsOf(obj *unstructured.Unstructured) (result []*unstructured.Unstructured, err error) {
+	if !obj.IsList() {
+		return []*unstructured.Unstructured{obj}, validate(obj)
+	}
+
+	err = obj.EachListItem(func(item runtime.Object) error {
+		result = append(result, item.(*unstructured.Unstructured))
+		return validate(item.(*unstructured.Unstructured))
+	})
+
+	return
+}
+
+func validate(obj *unstructured.Unstructured) error {
+	switch {
+	case obj.GetAPIVersion() == "":
+		return errors.New("an object has no apiVersion")
+	case obj.GetKind() == "":
+		return errors.New("an object has no kind")
+	case obj.GetName() == "":
+		return fmt.Errorf("a %s has no name", obj.GetKind())
+	}
+	return nil
+}
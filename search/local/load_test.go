@@ -0,0 +1,76 @@
+package local
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const manifests = `# Source: flargle/templates/empty.yaml
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: blargle
+  namespace: flargle
+---
+apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: bobble
+- apiVersion: apps/v1
+  kind: Deployment
+  metadata:
+    name: blargle
+`
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "nested"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "manifests.yaml"), []byte(manifests), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "nested", "service.json"), []byte(`{"apiVersion": "v1", "kind": "Service", "metadata": {"name": "blargle"}}`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("# Not a manifest"), 0o644))
+
+	objects, err := Load([]string{dir, Stdin}, strings.NewReader(`{"apiVersion": "v1", "kind": "Secret", "metadata": {"name": "blargle"}}`))
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Pod flargle/blargle", "ConfigMap /bobble", "Deployment /blargle", "Service /blargle", "Secret /blargle"}, describe(objects))
+}
+
+func TestLoad_fileWithAnyExtension(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "manifests.txt")
+	require.NoError(t, os.WriteFile(file, []byte(manifests), 0o644))
+
+	objects, err := Load([]string{file}, nil)
+
+	require.NoError(t, err)
+	assert.Len(t, objects, 3)
+}
+
+func TestLoad_invalidObject(t *testing.T) {
+	_, err := Load([]string{Stdin}, strings.NewReader("apiVersion: v1\nkind: Pod\nmetadata:\n  name: blargle\n---\napiVersion: v1\nkind: Pod\nmetadata:\n  namespace: flargle\n"))
+
+	assert.EqualError(t, err, "-: document 2: a Pod has no name")
+}
+
+func TestLoad_missingPath(t *testing.T) {
+	_, err := Load([]string{filepath.Join(t.TempDir(), "flargle")}, nil)
+
+	assert.Error(t, err)
+}
+
+func describe(objects []*unstructured.Unstructured) (result []string) {
+	for _, o := range objects {
+		result = append(result, o.GetKind()+" "+o.GetNamespace()+"/"+o.GetName())
+	}
+	return
+}
@@ -0,0 +1,97 @@
+// Package local indexes Kubernetes objects decoded from manifests,
+// e.g., a directory of YAML files or the output of `helm template`,
+// so that they can be searched without a cluster.
+package local
+
+import (
+	"github.com/kubideh/kubesearch/search/controller"
+	"github.com/kubideh/kubesearch/search/index"
+	"github.com/kubideh/kubesearch/search/tokenizer"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// Local is an in-memory index of objects and the objects themselves,
+// which are searched in the same way as those of a Controller.
+type Local struct {
+	index  *index.Index
+	stores map[string]cache.Store
+}
+
+// Create returns Local objects with the given objects indexed. Every
+// kind of object is indexed, not only those indexed by Controllers.
+// Objects of the same kind, namespace and name replace each other.
+func Create(objects []*unstructured.Unstructured) *Local {
+	l := &Local{
+		index:  index.Create(),
+		stores: make(map[string]cache.Store),
+	}
+
+	tokenize := tokenizer.Tokenizer()
+
+	for _, obj := range objects {
+		l.put(obj, tokenize)
+	}
+
+	return l
+}
+
+func (l *Local) put(obj *unstructured.Unstructured, tokenize tokenizer.TokenizeFunc) {
+	kind := obj.GetKind()
+
+	if l.stores[kind] == nil {
+		l.stores[kind] = cache.NewStore(cache.MetaNamespaceKeyFunc)
+	}
+
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+
+	if err != nil {
+		klog.Errorln(err)
+		return
+	}
+
+	if _, exists, _ := l.stores[kind].GetByKey(key); exists {
+		klog.Warningf("Replacing the %s %s, because it's given more than once", kind, key)
+	}
+
+	typed := typedObject(obj)
+
+	if err := l.stores[kind].Add(typed); err != nil {
+		klog.Errorln(err)
+		return
+	}
+
+	controller.IndexObject(l.index, tokenize, kind, key, typed)
+}
+
+// typedObject returns the given object as the Go type of its kind,
+// e.g., a Pod, so that the same doc values are indexed as for objects
+// of a Controller. Objects of unknown kinds, e.g., custom resources,
+// are returned as is.
+func typedObject(obj *unstructured.Unstructured) runtime.Object {
+	typed, err := scheme.Scheme.New(obj.GroupVersionKind())
+
+	if err != nil {
+		return obj
+	}
+
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, typed); err != nil {
+		klog.V(2).Infof("Indexing the %s %s as is: %v", obj.GetKind(), obj.GetName(), err)
+		return obj
+	}
+
+	return typed
+}
+
+// Index returns the index of the objects.
+func (l *Local) Index() *index.Index {
+	return l.index
+}
+
+// Store returns the object store of each kind.
+func (l *Local) Store() map[string]cache.Store {
+	return l.stores
+}
@@ -0,0 +1,59 @@
+package local
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kubideh/kubesearch/search/finder"
+	"github.com/kubideh/kubesearch/search/index"
+	"github.com/kubideh/kubesearch/search/searcher"
+	"github.com/kubideh/kubesearch/search/tokenizer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestCreate(t *testing.T) {
+	objects, err := Load([]string{Stdin}, strings.NewReader(manifests+`---
+apiVersion: example.com/v1
+kind: Flargle
+metadata:
+  name: blargle
+`))
+	require.NoError(t, err)
+
+	l := Create(objects)
+
+	postings := searcher.Create(l.Index(), tokenizer.Tokenizer())("blargle")
+	assert.ElementsMatch(t, []index.Posting{
+		{StoredObjectKey: "flargle/blargle", K8sResourceKind: "Pod", TermFrequency: 1},
+		{StoredObjectKey: "blargle", K8sResourceKind: "Deployment", TermFrequency: 1},
+		{StoredObjectKey: "blargle", K8sResourceKind: "Flargle", TermFrequency: 1},
+	}, postings)
+
+	found, err := finder.Create(l.Store())([]finder.Key{
+		{StoredObjectKey: "blargle", K8sResourceKind: "Deployment"},
+		{StoredObjectKey: "blargle", K8sResourceKind: "Flargle"},
+	})
+	require.NoError(t, err)
+	require.Len(t, found, 2)
+	assert.IsType(t, &appsv1.Deployment{}, found[0].Item)
+	assert.IsType(t, &unstructured.Unstructured{}, found[1].Item)
+}
+
+func TestCreate_docValues(t *testing.T) {
+	objects, err := Load([]string{Stdin}, strings.NewReader(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: blargle
+spec:
+  replicas: 3
+`))
+	require.NoError(t, err)
+
+	l := Create(objects)
+
+	posting := index.Posting{StoredObjectKey: "blargle", K8sResourceKind: "Deployment"}
+	assert.Equal(t, index.NumberValue(3), l.Index().DocValue(index.DocValueReplicas, posting))
+}
@@ -0,0 +1,32 @@
+package metrics
+
+import (
+	"github.com/kubideh/kubesearch/search/index"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// RegisterIndex registers gauges that report the number of terms
+// and postings in the given index.
+func RegisterIndex(idx *index.Index) error {
+	terms := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "index_terms",
+		Help:      "Number of distinct terms in the index.",
+	}, func() float64 {
+		return float64(idx.TermCount())
+	})
+
+	postings := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "index_postings",
+		Help:      "Number of postings in the index.",
+	}, func() float64 {
+		return float64(idx.PostingCount())
+	})
+
+	if err := prometheus.Register(terms); err != nil {
+		return err
+	}
+
+	return prometheus.Register(postings)
+}
@@ -0,0 +1,91 @@
+// Package metrics provides the Prometheus metrics of the indexing
+// and query paths, and the handler that exposes them.
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	namespace    = "kubesearch"
+	endpointPath = "/metrics"
+)
+
+// Phases of a query that are observed by QueryDuration.
+const (
+	PhaseParse  = "parse"
+	PhaseSearch = "search"
+	PhaseFind   = "find"
+	PhaseEncode = "encode"
+)
+
+var (
+	queryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "query_duration_seconds",
+		Help:      "How long each phase of a query takes in seconds.",
+		Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 10),
+	}, []string{"phase"})
+
+	queryResults = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "query_results",
+		Help:      "How many results are returned by each query.",
+		Buckets:   prometheus.ExponentialBuckets(1, 4, 10),
+	})
+
+	queriesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "queries_total",
+		Help:      "Total number of queries.",
+	})
+
+	zeroResultQueriesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "zero_result_queries_total",
+		Help:      "Total number of queries that returned no results.",
+	})
+
+	finderMissesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "finder_misses_total",
+		Help:      "Total number of indexed objects that were missing from the object store.",
+	}, []string{"kind"})
+)
+
+func init() {
+	prometheus.MustRegister(queryDuration, queryResults, queriesTotal, zeroResultQueriesTotal, finderMissesTotal)
+}
+
+// RegisterHandler registers the Prometheus metrics handler with the
+// given mux at the appropriate endpoint path.
+func RegisterHandler(mux *http.ServeMux) {
+	mux.Handle(endpointPath, promhttp.Handler())
+}
+
+// ObserveQueryPhase records how long the given phase of a query has
+// taken since start.
+func ObserveQueryPhase(phase string, start time.Time) {
+	queryDuration.WithLabelValues(phase).Observe(time.Since(start).Seconds())
+}
+
+// ObserveQueryResults records the number of results returned by a
+// query.
+func ObserveQueryResults(count int) {
+	queriesTotal.Inc()
+	queryResults.Observe(float64(count))
+
+	if count == 0 {
+		zeroResultQueriesTotal.Inc()
+	}
+}
+
+// IncFinderMisses records an indexed object of the given kind that
+// was missing from the object store.
+func IncFinderMisses(kind string) {
+	finderMissesTotal.WithLabelValues(kind).Inc()
+}
@@ -0,0 +1,131 @@
+package savedsearch
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/kubideh/kubesearch/search/auth"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// CreatorAnnotation is the annotation in which the admission webhook
+// records the user who created or last changed a SavedSearch, as the
+// JSON of an authentication.k8s.io/v1 UserInfo.
+const CreatorAnnotation = GroupName + "/creator"
+
+const admissionEndpointPath = "/v1alpha1/savedsearches/admission"
+
+// RegisterAdmissionHandler registers the admission webhook handler
+// with the given mux at the appropriate endpoint path.
+func RegisterAdmissionHandler(mux *http.ServeMux, handler http.HandlerFunc) {
+	mux.HandleFunc(admissionEndpointPath, handler)
+}
+
+// CreateAdmissionHandler is a `http.HandlerFunc` that serves a
+// mutating admission webhook, which records the user who creates or
+// changes a SavedSearch in its CreatorAnnotation. Any value given by
+// the user is replaced.
+func CreateAdmissionHandler() http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		var review admissionv1.AdmissionReview
+
+		if err := json.NewDecoder(request.Body).Decode(&review); err != nil || review.Request == nil {
+			http.Error(writer, "invalid AdmissionReview", http.StatusBadRequest)
+			return
+		}
+
+		review.Response = admit(review.Request)
+		review.Request = nil
+
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+		if err := json.NewEncoder(writer).Encode(review); err != nil {
+			klog.Warningln("error marshaling AdmissionReview: ", err)
+		}
+	}
+}
+
+// admit returns a response that patches the CreatorAnnotation of the
+// SavedSearch of the given request.
+func admit(request *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	response := &admissionv1.AdmissionResponse{UID: request.UID, Allowed: true}
+
+	if request.Operation != admissionv1.Create && request.Operation != admissionv1.Update {
+		return response
+	}
+
+	patch, err := creatorPatch(request.Object.Raw, request.UserInfo)
+
+	if err != nil {
+		response.Allowed = false
+		response.Result = &metav1.Status{Status: metav1.StatusFailure, Code: http.StatusBadRequest, Message: err.Error()}
+		return response
+	}
+
+	patchType := admissionv1.PatchTypeJSONPatch
+	response.Patch = patch
+	response.PatchType = &patchType
+
+	return response
+}
+
+// creatorPatch returns a JSON patch that sets the CreatorAnnotation
+// of the given object to the given user.
+func creatorPatch(object []byte, user authenticationv1.UserInfo) ([]byte, error) {
+	var obj metav1.PartialObjectMetadata
+
+	if err := json.Unmarshal(object, &obj); err != nil {
+		return nil, fmt.Errorf("invalid SavedSearch: %w", err)
+	}
+
+	creator, err := json.Marshal(user)
+
+	if err != nil {
+		return nil, err
+	}
+
+	type operation struct {
+		Op    string      `json:"op"`
+		Path  string      `json:"path"`
+		Value interface{} `json:"value"`
+	}
+
+	op := operation{Op: "add", Path: "/metadata/annotations", Value: map[string]string{CreatorAnnotation: string(creator)}}
+
+	// "add" replaces the value of a member that already exists.
+	if obj.Annotations != nil {
+		op = operation{Op: "add", Path: "/metadata/annotations/" + escapeJSONPointer(CreatorAnnotation), Value: string(creator)}
+	}
+
+	return json.Marshal([]operation{op})
+}
+
+func escapeJSONPointer(s string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
+
+// creator returns the user recorded in the CreatorAnnotation of the
+// given SavedSearch. It returns false if there's none.
+func creator(savedSearch SavedSearch) (auth.User, bool) {
+	var info authenticationv1.UserInfo
+
+	if err := json.Unmarshal([]byte(savedSearch.Annotations[CreatorAnnotation]), &info); err != nil || info.Username == "" {
+		return auth.User{}, false
+	}
+
+	user := auth.User{Name: info.Username, UID: info.UID, Groups: info.Groups}
+
+	for k, v := range info.Extra {
+		if user.Extra == nil {
+			user.Extra = make(map[string][]string, len(info.Extra))
+		}
+		user.Extra[k] = v
+	}
+
+	return user, true
+}
@@ -0,0 +1,121 @@
+package savedsearch
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kubideh/kubesearch/search/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestAdmissionHandler(t *testing.T) {
+	alice := authenticationv1.UserInfo{Username: "alice", Groups: []string{"payments"}}
+
+	cases := []struct {
+		name      string
+		operation admissionv1.Operation
+		object    string
+		patch     string
+	}{
+		{
+			name:      "create",
+			operation: admissionv1.Create,
+			object:    `{"metadata":{"name":"blargle"}}`,
+			patch:     `[{"op":"add","path":"/metadata/annotations","value":{"kubesearch.kubideh.io/creator":"{\"username\":\"alice\",\"groups\":[\"payments\"]}"}}]`,
+		},
+		{
+			name:      "update with annotations",
+			operation: admissionv1.Update,
+			object:    `{"metadata":{"name":"blargle","annotations":{"kubesearch.kubideh.io/creator":"{\"username\":\"bob\"}"}}}`,
+			patch:     `[{"op":"add","path":"/metadata/annotations/kubesearch.kubideh.io~1creator","value":"{\"username\":\"alice\",\"groups\":[\"payments\"]}"}]`,
+		},
+		{
+			name:      "delete",
+			operation: admissionv1.Delete,
+			object:    `null`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			review := admissionv1.AdmissionReview{
+				TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+				Request: &admissionv1.AdmissionRequest{
+					UID:       types.UID("flargle"),
+					Operation: c.operation,
+					UserInfo:  alice,
+					Object:    runtime.RawExtension{Raw: []byte(c.object)},
+				},
+			}
+
+			response := serveAdmission(t, review)
+
+			assert.Equal(t, "admission.k8s.io/v1", response.APIVersion)
+			assert.Nil(t, response.Request)
+			require.NotNil(t, response.Response)
+			assert.Equal(t, types.UID("flargle"), response.Response.UID)
+			assert.True(t, response.Response.Allowed)
+
+			if c.patch == "" {
+				assert.Nil(t, response.Response.Patch)
+			} else {
+				assert.JSONEq(t, c.patch, string(response.Response.Patch))
+				require.NotNil(t, response.Response.PatchType)
+				assert.Equal(t, admissionv1.PatchTypeJSONPatch, *response.Response.PatchType)
+			}
+		})
+	}
+}
+
+func TestAdmissionHandler_invalid(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	CreateAdmissionHandler()(recorder, httptest.NewRequest(http.MethodPost, admissionEndpointPath, bytes.NewBufferString("{}")))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	response := serveAdmission(t, admissionv1.AdmissionReview{Request: &admissionv1.AdmissionRequest{
+		Operation: admissionv1.Create,
+		Object:    runtime.RawExtension{Raw: []byte(`[]`)},
+	}})
+
+	require.NotNil(t, response.Response)
+	assert.False(t, response.Response.Allowed)
+}
+
+func TestCreator(t *testing.T) {
+	savedSearch := SavedSearch{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+		CreatorAnnotation: `{"username":"alice","uid":"1","groups":["payments"],"extra":{"scopes":["view"]}}`,
+	}}}
+
+	user, ok := creator(savedSearch)
+	require.True(t, ok)
+	assert.Equal(t, auth.User{Name: "alice", UID: "1", Groups: []string{"payments"}, Extra: map[string][]string{"scopes": {"view"}}}, user)
+
+	_, ok = creator(SavedSearch{})
+	assert.False(t, ok)
+
+	savedSearch.Annotations[CreatorAnnotation] = "alice"
+	_, ok = creator(savedSearch)
+	assert.False(t, ok)
+}
+
+func serveAdmission(t *testing.T, review admissionv1.AdmissionReview) (result admissionv1.AdmissionReview) {
+	body, err := json.Marshal(review)
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	CreateAdmissionHandler()(recorder, httptest.NewRequest(http.MethodPost, admissionEndpointPath, bytes.NewBuffer(body)))
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &result))
+
+	return
+}
@@ -0,0 +1,457 @@
+// Package savedsearch runs SavedSearches on their schedules, and it
+// reports their results in their status.
+package savedsearch
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kubideh/kubesearch/search/api"
+	"github.com/kubideh/kubesearch/search/auth"
+	"github.com/kubideh/kubesearch/search/controller"
+	"github.com/kubideh/kubesearch/search/index"
+	"github.com/kubideh/kubesearch/search/searcher"
+	"github.com/kubideh/kubesearch/search/tokenizer"
+	"github.com/kubideh/kubesearch/search/webhook"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// DefaultSchedule is how often a SavedSearch without a schedule
+	// is run.
+	DefaultSchedule = 5 * time.Minute
+
+	// MinSchedule is how often a SavedSearch may be run at most.
+	MinSchedule = 10 * time.Second
+
+	// SourceSavedSearches is the source of the standing queries given
+	// to the Notifier for the notifications of SavedSearches.
+	SourceSavedSearches = "savedsearches"
+
+	maxTopHits = 10
+)
+
+// Controller runs SavedSearches.
+type Controller struct {
+	client            dynamic.Interface
+	search            searcher.SearchFunc
+	notifier          *webhook.Notifier
+	ready             func() bool
+	adminNamespace    string
+	authorize         auth.AuthorizeFunc // authorize is nil unless creators of SavedSearches are recorded
+	filter            auth.FilterFunc
+	notificationHosts []string
+	now               func() time.Time
+
+	queries      map[string][]webhook.StandingQuery // queries are the standing queries given to the notifier for each SavedSearch, by key
+	queriesMutex sync.Mutex
+}
+
+// Create returns Controller objects. SavedSearches in adminNamespace
+// may search any namespace; all others search only their own
+// namespace, and they report only the hits that their creator, as
+// recorded by the admission webhook, is authorized to see. Those are
+// withheld if authorize is nil. Their notifications may be sent only
+// to the given hosts, and only if their creator may get every
+// indexed kind of object in their namespace. The notifier may be nil,
+// in which case notifications of SavedSearches are ignored. Ready
+// must return true once the index is up-to-date.
+func Create(client dynamic.Interface, search searcher.SearchFunc, notifier *webhook.Notifier, ready func() bool, adminNamespace string, authorize auth.AuthorizeFunc, notificationHosts []string) *Controller {
+	result := &Controller{
+		client:            client,
+		search:            search,
+		notifier:          notifier,
+		ready:             ready,
+		adminNamespace:    adminNamespace,
+		authorize:         authorize,
+		notificationHosts: notificationHosts,
+		now:               time.Now,
+		queries:           make(map[string][]webhook.StandingQuery),
+	}
+
+	if authorize != nil {
+		result.filter = auth.CreateFilter(authorize, controller.IndexedResources())
+	}
+
+	return result
+}
+
+// Run watches SavedSearches and runs each on its schedule until the
+// given context is done.
+func (c *Controller) Run(ctx context.Context) {
+	if err := wait.PollImmediateUntil(time.Second, func() (bool, error) { return c.ready(), nil }, ctx.Done()); err != nil {
+		return
+	}
+
+	factory := dynamicinformer.NewDynamicSharedInformerFactory(c.client, 0)
+	informer := factory.ForResource(Resource).Informer()
+	queue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "savedsearches")
+	defer queue.ShutDown()
+
+	informer.AddEventHandler(createEventHandler(queue))
+
+	factory.Start(ctx.Done())
+
+	if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+		return
+	}
+
+	klog.Infoln("Running SavedSearches")
+
+	go c.runAll(ctx, queue, informer.GetStore())
+
+	<-ctx.Done()
+}
+
+// createEventHandler enqueues SavedSearches when they're created,
+// deleted or their spec changes. Changes to only their status are
+// ignored, because those are made by Controllers.
+func createEventHandler(queue workqueue.RateLimitingInterface) cache.ResourceEventHandlerFuncs {
+	enqueue := func(obj interface{}) {
+		if key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj); err == nil {
+			queue.Add(key)
+		}
+	}
+
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: enqueue,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			if oldObj.(metav1.Object).GetGeneration() != newObj.(metav1.Object).GetGeneration() {
+				enqueue(newObj)
+			}
+		},
+		DeleteFunc: enqueue,
+	}
+}
+
+func (c *Controller) runAll(ctx context.Context, queue workqueue.RateLimitingInterface, store cache.Store) {
+	key, shutdown := queue.Get()
+
+	for !shutdown {
+		c.runOne(ctx, queue, store, key.(string))
+		queue.Done(key)
+
+		key, shutdown = queue.Get()
+	}
+}
+
+// runOne runs the SavedSearch with the given key, and it schedules
+// the next run. Its standing queries are replaced, or removed if it
+// was deleted or can't be run.
+func (c *Controller) runOne(ctx context.Context, queue workqueue.RateLimitingInterface, store cache.Store, key string) {
+	obj, exists, err := store.GetByKey(key)
+
+	if err != nil || !exists {
+		c.setStandingQueries(key, nil)
+		queue.Forget(key)
+		return
+	}
+
+	savedSearch, err := convert(obj.(*unstructured.Unstructured))
+
+	if err != nil {
+		klog.Errorf("Skipping SavedSearch %s: %v", key, err)
+		c.setStandingQueries(key, nil)
+		queue.Forget(key)
+		return
+	}
+
+	savedSearch.Status = c.execute(ctx, savedSearch)
+
+	if savedSearch.Status.Error == "" {
+		c.setStandingQueries(key, standingQueries(savedSearch, c.namespaces(savedSearch)))
+	} else {
+		c.setStandingQueries(key, nil)
+	}
+
+	if err := c.updateStatus(ctx, savedSearch); err != nil {
+		klog.V(2).Infof("Retrying SavedSearch %s: %v", key, err)
+		queue.AddRateLimited(key)
+		return
+	}
+
+	queue.Forget(key)
+
+	if savedSearch.Status.Error == "" {
+		schedule, _ := parseSchedule(savedSearch.Spec.Schedule)
+		queue.AddAfter(key, schedule)
+	}
+}
+
+// execute runs the given SavedSearch, and it returns its new status.
+func (c *Controller) execute(ctx context.Context, savedSearch SavedSearch) Status {
+	now := metav1.NewTime(c.now())
+	status := Status{ObservedGeneration: savedSearch.Generation, LastRunTime: &now}
+
+	if err := c.validate(savedSearch); err != nil {
+		status.Error = err.Error()
+		return status
+	}
+
+	if err := c.authorizeNotifications(ctx, savedSearch); err != nil {
+		status.Error = err.Error()
+		return status
+	}
+
+	postings, err := c.authorizedPostings(ctx, savedSearch, api.RetainNamespaces(c.search(savedSearch.Spec.Query), c.namespaces(savedSearch)))
+
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+
+	sort.Sort(index.PostingsList(postings))
+
+	status.HitCount = len(postings)
+
+	for i := 0; i < len(postings) && i < maxTopHits; i++ {
+		status.TopHits = append(status.TopHits, createHit(postings[i]))
+	}
+
+	return status
+}
+
+func (c *Controller) updateStatus(ctx context.Context, savedSearch SavedSearch) error {
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&savedSearch)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = c.client.Resource(Resource).Namespace(savedSearch.Namespace).UpdateStatus(ctx, &unstructured.Unstructured{Object: obj}, metav1.UpdateOptions{})
+
+	return err
+}
+
+// validate returns an error if the given SavedSearch can't be run.
+func (c *Controller) validate(savedSearch SavedSearch) error {
+	query, err := searcher.ParseQuery(savedSearch.Spec.Query, tokenizer.Tokenizer())
+
+	if err != nil {
+		return fmt.Errorf("invalid query: %w", err)
+	}
+
+	if query.Empty() {
+		return fmt.Errorf("the query is empty")
+	}
+
+	if _, err := parseSchedule(savedSearch.Spec.Schedule); err != nil {
+		return err
+	}
+
+	if len(savedSearch.Spec.Namespaces) > 0 && savedSearch.Namespace != c.adminNamespace {
+		return fmt.Errorf("namespaces may be given only by SavedSearches in the namespace %q", c.adminNamespace)
+	}
+
+	for _, q := range standingQueries(savedSearch, nil) {
+		if err := q.Validate(); err != nil {
+			return fmt.Errorf("invalid notification: %w", err)
+		}
+
+		if savedSearch.Namespace != c.adminNamespace && !c.notificationHostAllowed(q.URL) {
+			return fmt.Errorf("notifications of SavedSearches outside the namespace %q may be sent only to allowed hosts, and %q isn't one", c.adminNamespace, q.URL)
+		}
+	}
+
+	return nil
+}
+
+func (c *Controller) notificationHostAllowed(notificationURL string) bool {
+	u, err := url.Parse(notificationURL)
+
+	if err != nil {
+		return false
+	}
+
+	for _, host := range c.notificationHosts {
+		if strings.EqualFold(host, u.Hostname()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// creator returns the recorded creator of the given SavedSearch
+// outside the admin namespace, whose access decides what it may
+// report.
+func (c *Controller) creator(savedSearch SavedSearch) (auth.User, error) {
+	if c.authorize == nil {
+		return auth.User{}, fmt.Errorf("SavedSearches outside the namespace %q report hits only if their creators are recorded by the admission webhook", c.adminNamespace)
+	}
+
+	user, ok := creator(savedSearch)
+
+	if !ok {
+		return auth.User{}, fmt.Errorf("the creator of the SavedSearch is unknown; change it to have it recorded by the admission webhook")
+	}
+
+	return user, nil
+}
+
+// authorizedPostings returns the given postings whose objects the
+// creator of the given SavedSearch could get. Every posting is
+// returned for SavedSearches in the admin namespace.
+func (c *Controller) authorizedPostings(ctx context.Context, savedSearch SavedSearch, postings []index.Posting) ([]index.Posting, error) {
+	if savedSearch.Namespace == c.adminNamespace {
+		return postings, nil
+	}
+
+	user, err := c.creator(savedSearch)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return c.filter(auth.WithUser(ctx, user), postings), nil
+}
+
+// authorizeNotifications returns an error unless the creator of the
+// given SavedSearch may get every indexed kind of object in its
+// namespace, because notifications carry whole objects. SavedSearches
+// in the admin namespace and those without notifications are always
+// authorized.
+func (c *Controller) authorizeNotifications(ctx context.Context, savedSearch SavedSearch) error {
+	if savedSearch.Namespace == c.adminNamespace || len(savedSearch.Spec.Notifications) == 0 {
+		return nil
+	}
+
+	user, err := c.creator(savedSearch)
+
+	if err != nil {
+		return err
+	}
+
+	for _, resource := range controller.IndexedResources() {
+		allowed, err := c.authorize(ctx, user, auth.Attributes{
+			Verb:      "get",
+			Group:     resource.GroupVersionResource.Group,
+			Resource:  resource.GroupVersionResource.Resource,
+			Namespace: savedSearch.Namespace,
+		})
+
+		if err != nil {
+			return fmt.Errorf("error authorizing notifications: %w", err)
+		}
+
+		if !allowed {
+			return fmt.Errorf("notifications are sent only if the creator %q may get every %s in the namespace %q", user.Name, resource.Kind, savedSearch.Namespace)
+		}
+	}
+
+	return nil
+}
+
+// namespaces returns the namespaces searched by the given
+// SavedSearch. It's nil for every namespace.
+func (c *Controller) namespaces(savedSearch SavedSearch) []string {
+	if savedSearch.Namespace == c.adminNamespace {
+		return savedSearch.Spec.Namespaces
+	}
+	return []string{savedSearch.Namespace}
+}
+
+// setStandingQueries replaces the standing queries of the
+// SavedSearch with the given key, and it gives the notifier those of
+// every SavedSearch, unless they haven't changed.
+func (c *Controller) setStandingQueries(key string, queries []webhook.StandingQuery) {
+	if c.notifier == nil {
+		return
+	}
+
+	c.queriesMutex.Lock()
+	defer c.queriesMutex.Unlock()
+
+	if reflect.DeepEqual(queries, c.queries[key]) {
+		return
+	}
+
+	if len(queries) == 0 {
+		delete(c.queries, key)
+	} else {
+		c.queries[key] = queries
+	}
+
+	var all []webhook.StandingQuery
+
+	for _, q := range c.queries {
+		all = append(all, q...)
+	}
+
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].Name != all[j].Name {
+			return all[i].Name < all[j].Name
+		}
+		return all[i].URL < all[j].URL
+	})
+
+	c.notifier.Set(SourceSavedSearches, all)
+}
+
+// standingQueries returns a standing query for each notification of
+// the given SavedSearch. Each is named after the SavedSearch.
+func standingQueries(savedSearch SavedSearch, namespaces []string) (result []webhook.StandingQuery) {
+	for _, target := range savedSearch.Spec.Notifications {
+		result = append(result, webhook.StandingQuery{
+			Name:       savedSearch.Namespace + "/" + savedSearch.Name,
+			Query:      savedSearch.Spec.Query,
+			Namespaces: namespaces,
+			URL:        target.URL,
+		})
+	}
+	return
+}
+
+func parseSchedule(schedule string) (time.Duration, error) {
+	if schedule == "" {
+		return DefaultSchedule, nil
+	}
+
+	duration, err := time.ParseDuration(schedule)
+
+	if err != nil {
+		return 0, fmt.Errorf("invalid schedule: %w", err)
+	}
+
+	if duration < MinSchedule {
+		return 0, fmt.Errorf("the schedule must be at least %v", MinSchedule)
+	}
+
+	return duration, nil
+}
+
+func createHit(posting index.Posting) Hit {
+	hit := Hit{
+		Kind:      posting.K8sResourceKind,
+		Namespace: posting.Namespace(),
+		Name:      posting.Name(),
+		Rank:      posting.TermFrequency,
+	}
+
+	for _, resource := range controller.IndexedResources() {
+		if resource.Kind == hit.Kind {
+			hit.APIVersion = resource.GroupVersionResource.GroupVersion().String()
+		}
+	}
+
+	return hit
+}
+
+func convert(obj *unstructured.Unstructured) (result SavedSearch, err error) {
+	err = runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), &result)
+	return
+}
@@ -0,0 +1,316 @@
+package savedsearch
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/kubideh/kubesearch/search/auth"
+	"github.com/kubideh/kubesearch/search/index"
+	"github.com/kubideh/kubesearch/search/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+var postings = []index.Posting{
+	{StoredObjectKey: "flargle/blargle-1", K8sResourceKind: "Pod", TermFrequency: 1},
+	{StoredObjectKey: "flargle/blargle-2", K8sResourceKind: "Deployment", TermFrequency: 2},
+	{StoredObjectKey: "bobble/blargle", K8sResourceKind: "Pod", TermFrequency: 3},
+}
+
+func search(string) []index.Posting {
+	return append([]index.Posting(nil), postings...)
+}
+
+// authorize lets alice get everything, and everyone else get only
+// Pods.
+func authorize(_ context.Context, user auth.User, attributes auth.Attributes) (bool, error) {
+	return user.Name == "alice" || attributes.Resource == "pods", nil
+}
+
+func TestExecute(t *testing.T) {
+	now := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	cases := []struct {
+		name      string
+		namespace string
+		creator   string
+		spec      Spec
+		hits      []Hit
+		err       string
+	}{
+		{
+			name:      "own namespace",
+			namespace: "flargle",
+			creator:   "alice",
+			spec:      Spec{Query: "blargle"},
+			hits: []Hit{
+				{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "flargle", Name: "blargle-2", Rank: 2},
+				{APIVersion: "v1", Kind: "Pod", Namespace: "flargle", Name: "blargle-1", Rank: 1},
+			},
+		},
+		{
+			name:      "hits the creator can't get",
+			namespace: "flargle",
+			creator:   "bob",
+			spec:      Spec{Query: "blargle"},
+			hits: []Hit{
+				{APIVersion: "v1", Kind: "Pod", Namespace: "flargle", Name: "blargle-1", Rank: 1},
+			},
+		},
+		{
+			name:      "unknown creator",
+			namespace: "flargle",
+			spec:      Spec{Query: "blargle"},
+			err:       "the creator of the SavedSearch is unknown; change it to have it recorded by the admission webhook",
+		},
+		{
+			name:      "admin namespace",
+			namespace: "kubesearch",
+			spec:      Spec{Query: "blargle"},
+			hits: []Hit{
+				{APIVersion: "v1", Kind: "Pod", Namespace: "bobble", Name: "blargle", Rank: 3},
+				{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "flargle", Name: "blargle-2", Rank: 2},
+				{APIVersion: "v1", Kind: "Pod", Namespace: "flargle", Name: "blargle-1", Rank: 1},
+			},
+		},
+		{
+			name:      "admin namespace with namespaces",
+			namespace: "kubesearch",
+			spec:      Spec{Query: "blargle", Namespaces: []string{"bobble"}},
+			hits: []Hit{
+				{APIVersion: "v1", Kind: "Pod", Namespace: "bobble", Name: "blargle", Rank: 3},
+			},
+		},
+		{
+			name:      "namespaces outside the admin namespace",
+			namespace: "flargle",
+			spec:      Spec{Query: "blargle", Namespaces: []string{"bobble"}},
+			err:       `namespaces may be given only by SavedSearches in the namespace "kubesearch"`,
+		},
+		{
+			name:      "field clause only",
+			namespace: "flargle",
+			creator:   "alice",
+			spec:      Spec{Query: "label:owner="},
+			hits: []Hit{
+				{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "flargle", Name: "blargle-2", Rank: 2},
+				{APIVersion: "v1", Kind: "Pod", Namespace: "flargle", Name: "blargle-1", Rank: 1},
+			},
+		},
+		{
+			name:      "empty query",
+			namespace: "flargle",
+			spec:      Spec{Query: " "},
+			err:       "the query is empty",
+		},
+		{
+			name:      "invalid schedule",
+			namespace: "flargle",
+			spec:      Spec{Query: "blargle", Schedule: "often"},
+			err:       `invalid schedule: time: invalid duration "often"`,
+		},
+		{
+			name:      "schedule too short",
+			namespace: "flargle",
+			spec:      Spec{Query: "blargle", Schedule: "1s"},
+			err:       "the schedule must be at least 10s",
+		},
+		{
+			name:      "invalid notification",
+			namespace: "flargle",
+			spec:      Spec{Query: "blargle", Notifications: []NotificationTarget{{URL: "blargle"}}},
+			err:       `invalid notification: standing query "flargle/bobble": the url must be an absolute http or https URL, got "blargle"`,
+		},
+		{
+			name:      "notification to an allowed host",
+			namespace: "flargle",
+			creator:   "alice",
+			spec:      Spec{Query: "blargle", Notifications: []NotificationTarget{{URL: "https://Hooks.example.com/kubesearch"}}},
+			hits: []Hit{
+				{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "flargle", Name: "blargle-2", Rank: 2},
+				{APIVersion: "v1", Kind: "Pod", Namespace: "flargle", Name: "blargle-1", Rank: 1},
+			},
+		},
+		{
+			name:      "notification to another host",
+			namespace: "flargle",
+			creator:   "alice",
+			spec:      Spec{Query: "blargle", Notifications: []NotificationTarget{{URL: "https://evil.example.com/kubesearch"}}},
+			err:       `notifications of SavedSearches outside the namespace "kubesearch" may be sent only to allowed hosts, and "https://evil.example.com/kubesearch" isn't one`,
+		},
+		{
+			name:      "notification of a creator who can't get every object",
+			namespace: "flargle",
+			creator:   "bob",
+			spec:      Spec{Query: "blargle", Notifications: []NotificationTarget{{URL: "https://hooks.example.com/kubesearch"}}},
+			err:       `notifications are sent only if the creator "bob" may get every Deployment in the namespace "flargle"`,
+		},
+		{
+			name:      "notification in the admin namespace",
+			namespace: "kubesearch",
+			spec:      Spec{Query: "blargle", Namespaces: []string{"bobble"}, Notifications: []NotificationTarget{{URL: "https://evil.example.com/kubesearch"}}},
+			hits: []Hit{
+				{APIVersion: "v1", Kind: "Pod", Namespace: "bobble", Name: "blargle", Rank: 3},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			controller := Create(nil, search, nil, nil, "kubesearch", authorize, []string{"hooks.example.com"})
+			controller.now = func() time.Time { return now }
+
+			savedSearch := SavedSearch{
+				ObjectMeta: metav1.ObjectMeta{Name: "bobble", Namespace: c.namespace, Generation: 2},
+				Spec:       c.spec,
+			}
+
+			if c.creator != "" {
+				savedSearch.Annotations = map[string]string{CreatorAnnotation: `{"username":"` + c.creator + `"}`}
+			}
+
+			status := controller.execute(context.Background(), savedSearch)
+
+			assert.Equal(t, int64(2), status.ObservedGeneration)
+			assert.Equal(t, now, status.LastRunTime.Time)
+			assert.Equal(t, c.err, status.Error)
+			assert.Equal(t, c.hits, status.TopHits)
+			assert.Equal(t, len(c.hits), status.HitCount)
+		})
+	}
+}
+
+func TestExecute_withoutAdmission(t *testing.T) {
+	controller := Create(nil, search, nil, nil, "kubesearch", nil, nil)
+
+	status := controller.execute(context.Background(), SavedSearch{
+		ObjectMeta: metav1.ObjectMeta{Name: "bobble", Namespace: "flargle", Annotations: map[string]string{CreatorAnnotation: `{"username":"alice"}`}},
+		Spec:       Spec{Query: "blargle"},
+	})
+
+	assert.Equal(t, `SavedSearches outside the namespace "kubesearch" report hits only if their creators are recorded by the admission webhook`, status.Error)
+	assert.Empty(t, status.TopHits)
+
+	status = controller.execute(context.Background(), SavedSearch{
+		ObjectMeta: metav1.ObjectMeta{Name: "bobble", Namespace: "kubesearch"},
+		Spec:       Spec{Query: "blargle"},
+	})
+
+	assert.Equal(t, "", status.Error)
+	assert.Equal(t, 3, status.HitCount)
+}
+
+func TestCreateEventHandler(t *testing.T) {
+	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	defer queue.ShutDown()
+
+	handler := createEventHandler(queue)
+
+	old := savedSearch("flargle", "blargle", 1)
+	handler.OnUpdate(old, savedSearch("flargle", "blargle", 1))
+	assert.Equal(t, 0, queue.Len())
+
+	handler.OnUpdate(old, savedSearch("flargle", "blargle", 2))
+	assert.Equal(t, 1, queue.Len())
+}
+
+func TestRun(t *testing.T) {
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{Resource: "SavedSearchList"})
+	controller := Create(client, search, nil, func() bool { return true }, "flargle", nil, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The fake client guesses the wrong resource of objects given to
+	// it, so it's created instead.
+	_, err := client.Resource(Resource).Namespace("flargle").Create(ctx, savedSearch("flargle", "blargle", 1), metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	go controller.Run(ctx)
+
+	require.Eventually(t, func() bool {
+		obj, err := client.Resource(Resource).Namespace("flargle").Get(ctx, "blargle", metav1.GetOptions{})
+		require.NoError(t, err)
+
+		hitCount, _, _ := unstructured.NestedInt64(obj.Object, "status", "hitCount")
+		return hitCount == 3
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestRunOne_standingQueries(t *testing.T) {
+	reviews := 0
+	countingAuthorize := func(ctx context.Context, user auth.User, attributes auth.Attributes) (bool, error) {
+		reviews++
+		return authorize(ctx, user, attributes)
+	}
+
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{Resource: "SavedSearchList"})
+	notifier := webhook.Create(nil, search, nil, nil, func() bool { return true })
+	controller := Create(client, search, notifier, nil, "kubesearch", countingAuthorize, []string{"hooks.example.com"})
+	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	defer queue.ShutDown()
+
+	store := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	notifying := func(name, creator string) *unstructured.Unstructured {
+		obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&SavedSearch{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "flargle", Annotations: map[string]string{CreatorAnnotation: `{"username":"` + creator + `"}`}},
+			Spec:       Spec{Query: "blargle", Notifications: []NotificationTarget{{URL: "https://hooks.example.com/" + name}}},
+		})
+		require.NoError(t, err)
+		return &unstructured.Unstructured{Object: obj}
+	}
+
+	require.NoError(t, store.Add(notifying("blargle", "alice")))
+	controller.runOne(context.Background(), queue, store, "flargle/blargle")
+
+	assert.Equal(t, []string{"https://hooks.example.com/blargle"}, standingQueryURLs(controller))
+
+	// Only the reconciled SavedSearch is authorized again.
+	reviewsOfOne := reviews
+	require.NoError(t, store.Add(notifying("bobble", "alice")))
+	controller.runOne(context.Background(), queue, store, "flargle/bobble")
+
+	assert.Equal(t, 2*reviewsOfOne, reviews)
+	assert.Equal(t, []string{"https://hooks.example.com/blargle", "https://hooks.example.com/bobble"}, standingQueryURLs(controller))
+
+	// The creator of a changed SavedSearch may no longer be notified.
+	require.NoError(t, store.Update(notifying("bobble", "bob")))
+	controller.runOne(context.Background(), queue, store, "flargle/bobble")
+
+	assert.Equal(t, []string{"https://hooks.example.com/blargle"}, standingQueryURLs(controller))
+
+	require.NoError(t, store.Delete(notifying("blargle", "alice")))
+	controller.runOne(context.Background(), queue, store, "flargle/blargle")
+
+	assert.Empty(t, standingQueryURLs(controller))
+}
+
+func standingQueryURLs(controller *Controller) (urls []string) {
+	for _, queries := range controller.queries {
+		for _, q := range queries {
+			urls = append(urls, q.URL)
+		}
+	}
+	sort.Strings(urls)
+	return
+}
+
+func savedSearch(namespace, name string, generation int64) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(SchemeGroupVersion.String())
+	obj.SetKind(Kind)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	obj.SetGeneration(generation)
+	_ = unstructured.SetNestedField(obj.Object, "blargle", "spec", "query")
+	return obj
+}
@@ -0,0 +1,80 @@
+package savedsearch
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// GroupName is the API group of SavedSearches. It isn't
+	// search.kubideh.io, because that group is served by the
+	// aggregated API.
+	GroupName = "kubesearch.kubideh.io"
+
+	// Version is the version of SavedSearches.
+	Version = "v1alpha1"
+
+	// Kind is the kind of SavedSearches.
+	Kind = "SavedSearch"
+)
+
+// SchemeGroupVersion is the group and version of SavedSearches.
+var SchemeGroupVersion = schema.GroupVersion{Group: GroupName, Version: Version}
+
+// Resource is the resource of SavedSearches.
+var Resource = SchemeGroupVersion.WithResource("savedsearches")
+
+// SavedSearch is a query that KubeSearch runs on a schedule, and
+// whose results are reported in its status, e.g.,
+//
+//	apiVersion: kubesearch.kubideh.io/v1alpha1
+//	kind: SavedSearch
+//	metadata:
+//	  name: crashing-payments
+//	  namespace: payments
+//	spec:
+//	  query: payment restarts:>5
+//	  owner: team-payments
+//	  schedule: 5m
+//	  notifications:
+//	  - url: https://hooks.example.com/kubesearch
+type SavedSearch struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   Spec   `json:"spec"`
+	Status Status `json:"status,omitempty"`
+}
+
+// Spec is the desired state of a SavedSearch.
+type Spec struct {
+	Query         string               `json:"query"`                   // Query is a query string, the same as that of the search API
+	Owner         string               `json:"owner,omitempty"`         // Owner is who to ask about the SavedSearch
+	Schedule      string               `json:"schedule,omitempty"`      // Schedule is how often the query is run, e.g., 5m, and the default is DefaultSchedule
+	Namespaces    []string             `json:"namespaces,omitempty"`    // Namespaces are searched instead of every namespace, and they're allowed only in the admin namespace
+	Notifications []NotificationTarget `json:"notifications,omitempty"` // Notifications are told whenever an object starts matching the query
+}
+
+// NotificationTarget is a webhook to which notifications are POSTed
+// in the same way as for standing queries.
+type NotificationTarget struct {
+	URL string `json:"url"`
+}
+
+// Status is the result of the most recent run of a SavedSearch.
+type Status struct {
+	ObservedGeneration int64        `json:"observedGeneration,omitempty"` // ObservedGeneration is the generation of the spec that was run
+	HitCount           int          `json:"hitCount"`                     // HitCount is the number of matching objects
+	LastRunTime        *metav1.Time `json:"lastRunTime,omitempty"`        // LastRunTime is when the query was last run
+	TopHits            []Hit        `json:"topHits,omitempty"`            // TopHits are the highest ranked matching objects
+	Error              string       `json:"error,omitempty"`              // Error is why the SavedSearch can't be run, if it can't
+}
+
+// Hit is a matching object.
+type Hit struct {
+	APIVersion string `json:"apiVersion,omitempty"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+	Rank       int    `json:"rank"`
+}
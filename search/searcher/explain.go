@@ -0,0 +1,147 @@
+package searcher
+
+import (
+	"github.com/kubideh/kubesearch/search/index"
+	"github.com/kubideh/kubesearch/search/tokenizer"
+)
+
+// Operators of query nodes.
+const (
+	OperatorAnd = "AND"
+	OperatorNot = "NOT"
+)
+
+// Explanation describes how a posting matched a query, and how its
+// rank was computed.
+type Explanation struct {
+	Query  QueryNode          `json:"query"`
+	Terms  []TermExplanation  `json:"terms"`
+	Boosts map[string]float64 `json:"boosts"`
+	Score  ScoreExplanation   `json:"score"`
+}
+
+// QueryNode is a node of a parsed query tree. A node is either a
+// term, a range, a field term such as `image:nginx`, or an operator
+// applied to its children.
+type QueryNode struct {
+	Operator string      `json:"operator,omitempty"`
+	Term     string      `json:"term,omitempty"`
+	Range    string      `json:"range,omitempty"`
+	Field    string      `json:"field,omitempty"`
+	Children []QueryNode `json:"children,omitempty"`
+}
+
+// TermExplanation describes how a single term of a query matched a
+// posting.
+type TermExplanation struct {
+	Term              string         `json:"term"`
+	DocumentFrequency int            `json:"documentFrequency"`
+	TermFrequency     int            `json:"termFrequency"`
+	Fields            map[string]int `json:"fields,omitempty"`
+}
+
+// ScoreExplanation describes how the final score, i.e., the rank, of
+// a posting was computed from the frequency of each term.
+type ScoreExplanation struct {
+	Function string `json:"function"`
+	Term     string `json:"term,omitempty"`
+	Value    int    `json:"value"`
+}
+
+// scoreFunction describes how postings are scored when intersected.
+const scoreFunction = "max(termFrequency)"
+
+// fieldBoosts are the boosts applied to the term frequency of each
+// field. Fields aren't boosted yet.
+var fieldBoosts = map[string]float64{
+	index.FieldKind:      1,
+	index.FieldNamespace: 1,
+	index.FieldName:      1,
+}
+
+// ExplainFunc explains how the given posting matched the given query.
+type ExplainFunc func(query string, posting index.Posting) Explanation
+
+// CreateExplainer returns the default explain functor. It uses the
+// same index and tokenizer as the search functor.
+func CreateExplainer(idx *index.Index, tokenize tokenizer.TokenizeFunc) ExplainFunc {
+	return func(query string, posting index.Posting) Explanation {
+		parsed, _ := ParseQuery(query, tokenize)
+		terms := parsed.Terms
+
+		result := Explanation{
+			Query:  queryTree(parsed),
+			Boosts: fieldBoosts,
+			Score:  ScoreExplanation{Function: scoreFunction},
+		}
+
+		var scored index.Posting
+
+		for _, t := range terms {
+			postings := idx.Get(t)
+			matched, ok := find(postings, posting)
+
+			result.Terms = append(result.Terms, TermExplanation{
+				Term:              t,
+				DocumentFrequency: len(postings),
+				TermFrequency:     matched.TermFrequency,
+				Fields:            posting.ComputeFieldTermFrequencies(t),
+			})
+
+			if !ok {
+				continue
+			}
+
+			// This is how postings are scored when intersected, and
+			// ties are kept by the earlier term.
+			next := postingWithLargestTermFrequency(scored, matched)
+
+			if result.Score.Term == "" || next.TermFrequency != scored.TermFrequency {
+				result.Score.Term = t
+			}
+
+			scored = next
+		}
+
+		result.Score.Value = scored.TermFrequency
+
+		return result
+	}
+}
+
+// queryTree returns the tree of the given query. Every term, every
+// range and every field clause of a query must match. A field clause
+// that matches objects without a label is the negation of the field
+// term of the label key, e.g., `label:owner=` is NOT `label:owner`.
+func queryTree(query Query) QueryNode {
+	node := QueryNode{Operator: OperatorAnd}
+
+	for _, t := range query.Terms {
+		node.Children = append(node.Children, QueryNode{Term: t})
+	}
+
+	for _, r := range query.Ranges {
+		node.Children = append(node.Children, QueryNode{Range: r.Clause})
+	}
+
+	for _, f := range query.Fields {
+		field := QueryNode{Field: f.Term}
+
+		if f.Absent {
+			field = QueryNode{Operator: OperatorNot, Children: []QueryNode{field}}
+		}
+
+		node.Children = append(node.Children, field)
+	}
+
+	return node
+}
+
+func find(postings []index.Posting, posting index.Posting) (index.Posting, bool) {
+	for _, p := range postings {
+		if p.DocID() == posting.DocID() {
+			return p, true
+		}
+	}
+	return index.Posting{}, false
+}
@@ -0,0 +1,68 @@
+package searcher
+
+import (
+	"testing"
+
+	"github.com/kubideh/kubesearch/search/index"
+	"github.com/kubideh/kubesearch/search/tokenizer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExplain(t *testing.T) {
+	posting := index.Posting{StoredObjectKey: "flargle/blargle-blargle", K8sResourceKind: "Pod"}
+
+	idx := index.Create()
+	idx.Put([]string{"flargle", "blargle"}, posting)
+	idx.Put([]string{"flargle"}, index.Posting{StoredObjectKey: "flargle/foo", K8sResourceKind: "Pod"})
+
+	explain := CreateExplainer(idx, tokenizer.Tokenizer())
+
+	result := explain("flargle blargle", posting)
+
+	assert.Equal(t, QueryNode{
+		Operator: OperatorAnd,
+		Children: []QueryNode{{Term: "flargle"}, {Term: "blargle"}},
+	}, result.Query)
+
+	assert.Equal(t, []TermExplanation{
+		{Term: "flargle", DocumentFrequency: 2, TermFrequency: 1, Fields: map[string]int{index.FieldNamespace: 1}},
+		{Term: "blargle", DocumentFrequency: 1, TermFrequency: 2, Fields: map[string]int{index.FieldName: 2}},
+	}, result.Terms)
+
+	assert.Equal(t, ScoreExplanation{Function: scoreFunction, Term: "blargle", Value: 2}, result.Score)
+	assert.Equal(t, search(idx, "flargle blargle")[0].TermFrequency, result.Score.Value)
+}
+
+func TestExplain_fields(t *testing.T) {
+	posting := index.Posting{StoredObjectKey: "flargle/blargle", K8sResourceKind: "Pod"}
+
+	idx := index.Create()
+	idx.Put([]string{"flargle", "blargle", "label:app=web", "label:app"}, posting)
+
+	result := CreateExplainer(idx, tokenizer.Tokenizer())("label:app=web label:owner= image:nginx restarts:>5", posting)
+
+	assert.Equal(t, QueryNode{
+		Operator: OperatorAnd,
+		Children: []QueryNode{
+			{Range: "restarts:>5"},
+			{Field: "label:app=web"},
+			{Operator: OperatorNot, Children: []QueryNode{{Field: "label:owner"}}},
+			{Field: "image:nginx"},
+		},
+	}, result.Query)
+}
+
+func TestExplain_tiesAreKeptByTheEarlierTerm(t *testing.T) {
+	posting := index.Posting{StoredObjectKey: "flargle/blargle", K8sResourceKind: "Pod"}
+
+	idx := index.Create()
+	idx.Put([]string{"flargle", "blargle"}, posting)
+
+	result := CreateExplainer(idx, tokenizer.Tokenizer())("flargle blargle", posting)
+
+	assert.Equal(t, ScoreExplanation{Function: scoreFunction, Term: "flargle", Value: 1}, result.Score)
+}
+
+func search(idx *index.Index, query string) []index.Posting {
+	return Create(idx, tokenizer.Tokenizer())(query)
+}
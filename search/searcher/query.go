@@ -0,0 +1,294 @@
+package searcher
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kubideh/kubesearch/search/index"
+	"github.com/kubideh/kubesearch/search/tokenizer"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// now returns the current time, and it's replaced by tests.
+var now = time.Now
+
+// Query is a parsed query. Every term, every range and every field
+// clause must match.
+type Query struct {
+	Terms  []string
+	Ranges []RangeClause
+	Fields []FieldClause
+}
+
+// RangeClause matches objects whose numeric field is within Range.
+// Clause is the clause as given in the query, e.g., `restarts:>5`.
+type RangeClause struct {
+	Clause string
+	Field  index.NumericField
+	Range  index.Range
+}
+
+// FieldClause matches objects that are indexed under Term, or that
+// aren't if Absent is true. Clause is the clause as given in the
+// query, e.g., `image:nginx` or `label:owner=`.
+type FieldClause struct {
+	Clause string
+	Term   string
+	Absent bool
+}
+
+// ParseQuery parses the given query. A word of the form
+// `<field>:<operator><value>` is a range clause if the field is one
+// of index.NumericFields, and the operator is one of `>`, `>=`, `<`,
+// `<=` or `=`, which is also used if no operator is given. A word of
+// the form `ns:<namespace>`, `image:<image>`, `label:<key>=<value>`
+// or `label:<key>` is a field clause, and `label:<key>=` matches
+// objects without the label. Anything else is tokenized into terms.
+func ParseQuery(query string, tokenize tokenizer.TokenizeFunc) (result Query, err error) {
+	var text []string
+
+	for _, word := range strings.Fields(query) {
+		clause, ok, err := parseRangeClause(word)
+
+		if err != nil {
+			return Query{}, err
+		}
+
+		if ok {
+			result.Ranges = append(result.Ranges, clause)
+			continue
+		}
+
+		field, ok, err := parseFieldClause(word)
+
+		if err != nil {
+			return Query{}, err
+		}
+
+		if ok {
+			result.Fields = append(result.Fields, field)
+		} else {
+			text = append(text, word)
+		}
+	}
+
+	result.Terms = tokenize(strings.Join(text, " "))
+
+	return
+}
+
+// Empty returns true if this Query has no terms, ranges or field
+// clauses.
+func (q Query) Empty() bool {
+	return len(q.Terms) == 0 && len(q.Ranges) == 0 && len(q.Fields) == 0
+}
+
+// Matches returns whether a document indexed under the given terms,
+// and with the given doc values, matches this Query, the same as if
+// the document were searched for. An empty Query matches nothing.
+func (q Query) Matches(terms []string, values index.DocValues) bool {
+	if q.Empty() {
+		return false
+	}
+
+	indexed := make(map[string]bool, len(terms))
+
+	for _, t := range terms {
+		indexed[t] = true
+	}
+
+	for _, t := range q.Terms {
+		if !indexed[t] {
+			return false
+		}
+	}
+
+	for _, r := range q.Ranges {
+		value, ok := values[r.Field.DocValue]
+
+		if !ok || !value.Present || !r.Range.Contains(value.Number) {
+			return false
+		}
+	}
+
+	for _, f := range q.Fields {
+		if indexed[f.Term] == f.Absent {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Rank returns the given posting with the term frequency that it
+// would have if it were searched for using this Query, i.e., the
+// largest frequency of any term of this Query.
+func (q Query) Rank(posting index.Posting) index.Posting {
+	posting.TermFrequency = 0
+
+	for _, t := range q.Terms {
+		if n := posting.ComputeTermFrequency(t); n > posting.TermFrequency {
+			posting.TermFrequency = n
+		}
+	}
+
+	return posting
+}
+
+func parseRangeClause(word string) (RangeClause, bool, error) {
+	i := strings.Index(word, ":")
+
+	if i < 0 {
+		return RangeClause{}, false, nil
+	}
+
+	field, ok := numericField(word[:i])
+
+	if !ok {
+		return RangeClause{}, false, nil
+	}
+
+	operator, value := splitOperator(word[i+1:])
+	n, err := parseValue(field, value)
+
+	if err != nil {
+		return RangeClause{}, false, fmt.Errorf("invalid value of %s in %q: %w", field.Name, word, err)
+	}
+
+	if field.Type == index.FieldTypeAge {
+		// An age is the time since a timestamp, so a larger age is
+		// an earlier timestamp.
+		operator = invert(operator)
+	}
+
+	return RangeClause{Clause: word, Field: field, Range: toRange(operator, n)}, true, nil
+}
+
+// parseFieldClause returns the field clause of the given word, if
+// it's one, and the term under which matching objects are indexed.
+func parseFieldClause(word string) (FieldClause, bool, error) {
+	i := strings.Index(word, ":")
+
+	if i < 0 {
+		return FieldClause{}, false, nil
+	}
+
+	prefix, value := word[:i+1], word[i+1:]
+
+	switch prefix {
+	case index.TermPrefixNamespace, index.TermPrefixImage:
+	case index.TermPrefixLabel:
+		if key := strings.TrimSuffix(value, "="); key != value && !strings.Contains(key, "=") {
+			if key == "" {
+				return FieldClause{}, false, fmt.Errorf("invalid field clause %q: expected a label key", word)
+			}
+			return FieldClause{Clause: word, Term: index.LabelKeyTerm(key), Absent: true}, true, nil
+		}
+	default:
+		return FieldClause{}, false, nil
+	}
+
+	if value == "" || strings.HasPrefix(value, "=") {
+		return FieldClause{}, false, fmt.Errorf("invalid field clause %q: expected a value after %s", word, prefix)
+	}
+
+	return FieldClause{Clause: word, Term: word}, true, nil
+}
+
+func numericField(name string) (index.NumericField, bool) {
+	for _, f := range index.NumericFields() {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return index.NumericField{}, false
+}
+
+func splitOperator(s string) (operator, value string) {
+	for _, op := range []string{">=", "<=", ">", "<", "="} {
+		if strings.HasPrefix(s, op) {
+			return op, strings.TrimPrefix(s, op)
+		}
+	}
+	return "=", s
+}
+
+func invert(operator string) string {
+	switch operator {
+	case ">":
+		return "<"
+	case ">=":
+		return "<="
+	case "<":
+		return ">"
+	case "<=":
+		return ">="
+	}
+	return operator
+}
+
+// parseValue returns the given value as it's stored in the doc value
+// of the given field, e.g., timestamps are stored as Unix seconds.
+func parseValue(field index.NumericField, value string) (int64, error) {
+	switch field.Type {
+	case index.FieldTypeTimestamp:
+		return parseTimestamp(value)
+	case index.FieldTypeAge:
+		age, err := parseAge(value)
+		return now().Add(-age).Unix(), err
+	case index.FieldTypeQuantity:
+		quantity, err := resource.ParseQuantity(value)
+		if field.Milli {
+			return quantity.MilliValue(), err
+		}
+		return quantity.Value(), err
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+func parseTimestamp(value string) (int64, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.Unix(), nil
+		}
+	}
+	return 0, fmt.Errorf("expected a date or an RFC 3339 timestamp, got %q", value)
+}
+
+// parseAge is the same as time.ParseDuration, but it also accepts a
+// number of days, e.g., `7d`.
+func parseAge(value string) (time.Duration, error) {
+	if days := strings.TrimSuffix(value, "d"); days != value {
+		n, err := strconv.Atoi(days)
+		return time.Duration(n) * 24 * time.Hour, err
+	}
+	return time.ParseDuration(value)
+}
+
+func toRange(operator string, n int64) index.Range {
+	result := index.Unbounded()
+
+	switch operator {
+	case ">":
+		if n == math.MaxInt64 {
+			return index.Range{Min: 1, Max: 0} // empty
+		}
+		result.Min = n + 1
+	case ">=":
+		result.Min = n
+	case "<":
+		if n == math.MinInt64 {
+			return index.Range{Min: 1, Max: 0} // empty
+		}
+		result.Max = n - 1
+	case "<=":
+		result.Max = n
+	default:
+		result.Min, result.Max = n, n
+	}
+
+	return result
+}
@@ -0,0 +1,140 @@
+package searcher
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kubideh/kubesearch/search/index"
+	"github.com/kubideh/kubesearch/search/tokenizer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseQuery(t *testing.T) {
+	defer func(original func() time.Time) { now = original }(now)
+	now = func() time.Time { return time.Unix(100000, 0) }
+
+	cases := []struct {
+		clause   string
+		docValue string
+		expected index.Range
+	}{
+		{clause: "restarts:>5", docValue: index.DocValueRestartCount, expected: atLeast(6)},
+		{clause: "restarts:>=5", docValue: index.DocValueRestartCount, expected: atLeast(5)},
+		{clause: "replicas:0", docValue: index.DocValueReplicas, expected: index.Range{Min: 0, Max: 0}},
+		{clause: "replicas:<=2", docValue: index.DocValueReplicas, expected: atMost(2)},
+		{clause: "age:<1h", docValue: index.DocValueCreationTimestamp, expected: atLeast(100000 - 3600 + 1)},
+		{clause: "age:>=1d", docValue: index.DocValueCreationTimestamp, expected: atMost(100000 - 86400)},
+		{clause: "created:>1970-01-02", docValue: index.DocValueCreationTimestamp, expected: atLeast(86401)},
+		{clause: "created:<1970-01-01T00:01:00Z", docValue: index.DocValueCreationTimestamp, expected: atMost(59)},
+		{clause: "cpu:>500m", docValue: index.DocValueCPU, expected: atLeast(501)},
+		{clause: "memory:<1Ki", docValue: index.DocValueMemory, expected: atMost(1023)},
+	}
+
+	for _, c := range cases {
+		t.Run(c.clause, func(t *testing.T) {
+			result, err := ParseQuery("nginx "+c.clause, tokenizer.Tokenizer())
+
+			require.NoError(t, err)
+			assert.Equal(t, []string{"nginx"}, result.Terms)
+			require.Len(t, result.Ranges, 1)
+			assert.Equal(t, c.clause, result.Ranges[0].Clause)
+			assert.Equal(t, c.docValue, result.Ranges[0].Field.DocValue)
+			assert.Equal(t, c.expected, result.Ranges[0].Range)
+		})
+	}
+}
+
+func TestParseQuery_otherFieldsAreText(t *testing.T) {
+	result, err := ParseQuery("nginx:alpine", tokenizer.Tokenizer())
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"nginx", "alpine"}, result.Terms)
+	assert.Empty(t, result.Ranges)
+}
+
+func TestParseQuery_fields(t *testing.T) {
+	result, err := ParseQuery("payment image:latest ns:prod label:app=web label:tier label:owner=", tokenizer.Tokenizer())
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"payment"}, result.Terms)
+	assert.Equal(t, []FieldClause{
+		{Clause: "image:latest", Term: "image:latest"},
+		{Clause: "ns:prod", Term: "ns:prod"},
+		{Clause: "label:app=web", Term: "label:app=web"},
+		{Clause: "label:tier", Term: "label:tier"},
+		{Clause: "label:owner=", Term: "label:owner", Absent: true},
+	}, result.Fields)
+}
+
+func TestParseQuery_invalidValues(t *testing.T) {
+	for _, query := range []string{"restarts:>many", "age:<soon", "created:>yesterday", "cpu:>lots", "image:", "ns:", "label:", "label:=", "label:=web"} {
+		_, err := ParseQuery(query, tokenizer.Tokenizer())
+		assert.Error(t, err, query)
+	}
+}
+
+func TestSearch_ranges(t *testing.T) {
+	idx := index.Create()
+
+	a := index.Posting{StoredObjectKey: "flargle/blargle", K8sResourceKind: "Pod"}
+	b := index.Posting{StoredObjectKey: "flargle/foo", K8sResourceKind: "Pod"}
+
+	idx.Put([]string{"flargle", "blargle"}, a)
+	idx.Put([]string{"flargle", "foo"}, b)
+	idx.PutDocValues(a, index.DocValues{index.DocValueRestartCount: index.NumberValue(7)})
+	idx.PutDocValues(b, index.DocValues{index.DocValueRestartCount: index.NumberValue(0)})
+
+	search := Create(idx, tokenizer.Tokenizer())
+
+	assert.Equal(t, []index.Posting{{StoredObjectKey: "flargle/blargle", K8sResourceKind: "Pod", TermFrequency: 1}}, search("flargle restarts:>5"))
+	assert.Equal(t, []index.Posting{b}, search("restarts:0"))
+	assert.Empty(t, search("restarts:>5 restarts:<5"))
+	assert.Empty(t, search("restarts:>bogus"))
+}
+
+func atLeast(n int64) index.Range {
+	result := index.Unbounded()
+	result.Min = n
+	return result
+}
+
+func atMost(n int64) index.Range {
+	result := index.Unbounded()
+	result.Max = n
+	return result
+}
+
+func TestQueryMatches(t *testing.T) {
+	query, err := ParseQuery("flargle restarts:>5", tokenizer.Tokenizer())
+	require.NoError(t, err)
+
+	restarts := func(n int64) index.DocValues {
+		return index.DocValues{index.DocValueRestartCount: index.NumberValue(n)}
+	}
+
+	assert.True(t, query.Matches([]string{"flargle", "blargle"}, restarts(6)))
+	assert.False(t, query.Matches([]string{"flargle", "blargle"}, restarts(5)))
+	assert.False(t, query.Matches([]string{"blargle"}, restarts(6)))
+	assert.False(t, query.Matches([]string{"flargle"}, nil))
+	assert.False(t, Query{}.Matches([]string{"flargle"}, restarts(6)))
+}
+
+func TestQueryMatches_fields(t *testing.T) {
+	query, err := ParseQuery("image:latest ns:prod label:owner=", tokenizer.Tokenizer())
+	require.NoError(t, err)
+
+	assert.True(t, query.Matches([]string{"payment", "ns:prod", "image:latest", "label:app"}, nil))
+	assert.False(t, query.Matches([]string{"payment", "ns:prod", "image:latest", "label:owner"}, nil), "objects with an owner don't match")
+	assert.False(t, query.Matches([]string{"payment", "ns:dev", "image:latest"}, nil))
+	assert.False(t, query.Matches([]string{"payment", "ns:prod", "image:1.21"}, nil))
+}
+
+func TestQueryRank(t *testing.T) {
+	query, err := ParseQuery("flargle blargle", tokenizer.Tokenizer())
+	require.NoError(t, err)
+
+	posting := index.Posting{StoredObjectKey: "flargle/flargle-blargle", K8sResourceKind: "Pod", TermFrequency: 7}
+
+	assert.Equal(t, 2, query.Rank(posting).TermFrequency)
+}
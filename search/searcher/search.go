@@ -1,8 +1,12 @@
 package searcher
 
 import (
+	"time"
+
 	"github.com/kubideh/kubesearch/search/index"
+	"github.com/kubideh/kubesearch/search/metrics"
 	"github.com/kubideh/kubesearch/search/tokenizer"
+	"k8s.io/klog/v2"
 )
 
 // SearchFunc is a basic search function.
@@ -11,20 +15,105 @@ type SearchFunc func(query string) []index.Posting
 // Create returns the default search functor.
 func Create(idx *index.Index, tokenize tokenizer.TokenizeFunc) SearchFunc {
 	return func(query string) []index.Posting {
-		terms := tokenize(query)
+		start := time.Now()
+		parsed, err := ParseQuery(query, tokenize)
 		//sort.Strings(terms)
+		metrics.ObserveQueryPhase(metrics.PhaseParse, start)
+
+		if err != nil {
+			klog.V(2).Infoln(err)
+			return nil
+		}
+
+		start = time.Now()
+		defer metrics.ObserveQueryPhase(metrics.PhaseSearch, start)
 
 		var result []index.Posting
 
-		for _, t := range terms {
+		for _, t := range parsed.Terms {
 			postings := idx.Get(t)
 			result = intersect(result, postings)
 		}
 
+		narrowed := len(parsed.Terms) > 0
+
+		for _, r := range parsed.Ranges {
+			result = narrow(result, idx.Range(r.Field.DocValue, r.Range), narrowed)
+			narrowed = true
+		}
+
+		for _, f := range parsed.Fields {
+			if !f.Absent {
+				result = narrow(result, idx.Get(f.Term), narrowed)
+				narrowed = true
+			}
+		}
+
+		for _, f := range parsed.Fields {
+			if !f.Absent {
+				continue
+			}
+
+			if !narrowed {
+				result = idx.Documents()
+				narrowed = true
+			}
+
+			result = exclude(result, idx.Get(f.Term))
+		}
+
 		return result
 	}
 }
 
+// narrow returns the given postings whose documents are also in the
+// other given postings, or else the other postings if the given
+// postings haven't been narrowed by any clause yet.
+func narrow(postings, other []index.Posting, narrowed bool) []index.Posting {
+	if !narrowed {
+		return other
+	}
+	return retain(postings, other)
+}
+
+// exclude returns the given postings whose documents aren't in the
+// excluded postings.
+func exclude(postings, excluded []index.Posting) (result []index.Posting) {
+	skipped := make(map[index.DocID]bool, len(excluded))
+
+	for _, p := range excluded {
+		skipped[p.DocID()] = true
+	}
+
+	for _, p := range postings {
+		if !skipped[p.DocID()] {
+			result = append(result, p)
+		}
+	}
+
+	return
+}
+
+// retain returns the given postings whose documents are also in the
+// given range or field postings. Postings matched by a range or by a
+// field clause have no term frequency, so the given postings are kept
+// as they are.
+func retain(postings, ranged []index.Posting) (result []index.Posting) {
+	matched := make(map[index.DocID]bool, len(ranged))
+
+	for _, p := range ranged {
+		matched[p.DocID()] = true
+	}
+
+	for _, p := range postings {
+		if matched[p.DocID()] {
+			result = append(result, p)
+		}
+	}
+
+	return
+}
+
 // intersect returns the set-intersection (conjunction) of the two
 // given sorted lists of Postings.
 func intersect(left, right []index.Posting) (result []index.Posting) {
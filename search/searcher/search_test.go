@@ -95,3 +95,26 @@ func TestSearch_orderedByRankAndDocID(t *testing.T) {
 
 	assert.Equal(t, expected, result)
 }
+
+func TestSearch_fields(t *testing.T) {
+	idx := index.Create()
+	idx.Put([]string{"payment", "ns:prod", "image:latest", "label:owner"}, index.Posting{StoredObjectKey: "prod/payment", K8sResourceKind: "Pod"})
+	idx.Put([]string{"web", "ns:prod", "image:latest"}, index.Posting{StoredObjectKey: "prod/web", K8sResourceKind: "Pod"})
+	idx.Put([]string{"web", "ns:dev", "image:latest"}, index.Posting{StoredObjectKey: "dev/web", K8sResourceKind: "Pod"})
+	idx.Put([]string{"api", "ns:prod", "image:1.21"}, index.Posting{StoredObjectKey: "prod/api", K8sResourceKind: "Pod"})
+
+	search := Create(idx, tokenizer.Tokenizer())
+
+	assert.Equal(t, []string{"prod/payment", "prod/web"}, keys(search("image:latest ns:prod")))
+	assert.Equal(t, []string{"prod/web"}, keys(search("image:latest ns:prod label:owner=")))
+	assert.Equal(t, []string{"dev/web", "prod/api", "prod/web"}, keys(search("label:owner=")), "a query of only absent fields matches every other object")
+	assert.Equal(t, []string{"dev/web"}, keys(search("web ns:dev")))
+	assert.Empty(t, search("image:alpine"))
+}
+
+func keys(postings []index.Posting) (result []string) {
+	for _, p := range postings {
+		result = append(result, p.StoredObjectKey)
+	}
+	return
+}
@@ -0,0 +1,106 @@
+package searcher
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kubideh/kubesearch/search/index"
+)
+
+// SortField is a doc value by which postings are sorted, in
+// ascending order unless Descending is true.
+type SortField struct {
+	Field      string
+	Descending bool
+}
+
+// String returns the given SortField in the form accepted by
+// ParseSort.
+func (s SortField) String() string {
+	if s.Descending {
+		return "-" + s.Field
+	}
+	return s.Field
+}
+
+// ParseSort returns the SortFields of the given comma-separated list
+// of doc value fields, e.g., `namespace,-creationTimestamp`. Fields
+// prefixed by `-` are sorted in descending order.
+func ParseSort(param string) ([]SortField, error) {
+	var result []SortField
+
+	for _, f := range strings.Split(param, ",") {
+		f = strings.TrimSpace(f)
+
+		if f == "" {
+			continue
+		}
+
+		field := SortField{Field: strings.TrimPrefix(f, "-"), Descending: strings.HasPrefix(f, "-")}
+
+		if !isDocValueField(field.Field) {
+			return nil, fmt.Errorf("invalid sort field %q", field.Field)
+		}
+
+		result = append(result, field)
+	}
+
+	return result, nil
+}
+
+func isDocValueField(field string) bool {
+	for _, f := range index.DocValueFields() {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// SortKeyFunc returns the doc values of the given posting for each
+// of the given fields.
+type SortKeyFunc func(posting index.Posting, by []SortField) []index.Value
+
+// CreateSortKeys returns the default sort key functor, which reads
+// doc values from the given index.
+func CreateSortKeys(idx *index.Index) SortKeyFunc {
+	return func(posting index.Posting, by []SortField) []index.Value {
+		result := make([]index.Value, 0, len(by))
+
+		for _, f := range by {
+			result = append(result, idx.DocValue(f.Field, posting))
+		}
+
+		return result
+	}
+}
+
+// CompareSortKeys returns -1, 0 or +1 depending on whether the sort
+// key a comes before, is equal to, or comes after the sort key b.
+// Missing values come last in either order.
+func CompareSortKeys(a, b []index.Value, by []SortField) int {
+	for i, f := range by {
+		if a[i].Present != b[i].Present {
+			if a[i].Present {
+				return -1
+			}
+			return 1
+		}
+
+		if !a[i].Present {
+			continue
+		}
+
+		result := a[i].Compare(b[i])
+
+		if f.Descending {
+			result = -result
+		}
+
+		if result != 0 {
+			return result
+		}
+	}
+
+	return 0
+}
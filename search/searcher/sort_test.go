@@ -0,0 +1,45 @@
+package searcher
+
+import (
+	"testing"
+
+	"github.com/kubideh/kubesearch/search/index"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSort(t *testing.T) {
+	result, err := ParseSort("namespace, -creationTimestamp")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []SortField{
+		{Field: index.DocValueNamespace},
+		{Field: index.DocValueCreationTimestamp, Descending: true},
+	}, result)
+
+	_, err = ParseSort("-flargle")
+
+	assert.Error(t, err)
+}
+
+func TestCompareSortKeys(t *testing.T) {
+	by := []SortField{{Field: index.DocValueNamespace}, {Field: index.DocValueRestartCount, Descending: true}}
+
+	a := []index.Value{index.StringValue("flargle"), index.NumberValue(1)}
+	b := []index.Value{index.StringValue("flargle"), index.NumberValue(5)}
+	c := []index.Value{index.StringValue("bobble"), {}}
+
+	assert.Equal(t, 1, CompareSortKeys(a, b, by), "descending")
+	assert.Equal(t, -1, CompareSortKeys(c, a, by), "ascending")
+	assert.Equal(t, 0, CompareSortKeys(a, a, by))
+	assert.Equal(t, -1, CompareSortKeys(a, []index.Value{index.StringValue("flargle"), {}}, by), "missing values come last")
+}
+
+func TestCreateSortKeys(t *testing.T) {
+	idx := index.Create()
+	posting := index.Posting{StoredObjectKey: "flargle/blargle", K8sResourceKind: "Pod"}
+	idx.PutDocValues(posting, index.DocValues{index.DocValueName: index.StringValue("blargle")})
+
+	result := CreateSortKeys(idx)(posting, []SortField{{Field: index.DocValueName}, {Field: index.DocValueReplicas}})
+
+	assert.Equal(t, []index.Value{index.StringValue("blargle"), {}}, result)
+}
@@ -19,6 +19,52 @@ func Tokenizer() TokenizeFunc {
 	}
 }
 
+// Token is a term and the byte offsets of that term within the
+// tokenized text.
+type Token struct {
+	Term  string
+	Start int
+	End   int
+}
+
+// TokenizeWithOffsetsFunc is the same as TokenizeFunc, but it
+// returns the offsets of each term, e.g., for highlighting.
+type TokenizeWithOffsetsFunc func(text string) []Token
+
+// TokenizerWithOffsets returns the default tokenize functor that
+// returns offsets. The terms are the same as those of Tokenizer.
+func TokenizerWithOffsets() TokenizeWithOffsetsFunc {
+	return func(text string) []Token {
+		return tokenizeWithOffsets(text)
+	}
+}
+
+// tokenizeWithOffsets splits text on the same separators as
+// tokenize, i.e., anything that isn't a digit or letter.
+func tokenizeWithOffsets(text string) (results []Token) {
+	start := -1
+
+	for i, r := range text {
+		if unicode.IsDigit(r) || unicode.IsLetter(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+
+		if start >= 0 {
+			results = append(results, Token{Term: text[start:i], Start: start, End: i})
+			start = -1
+		}
+	}
+
+	if start >= 0 {
+		results = append(results, Token{Term: text[start:], Start: start, End: len(text)})
+	}
+
+	return
+}
+
 // Tokenize uses the default Golang word scanner as a base, and it
 // applies additional separators such as colons, dots, and hyphens,
 // etc.
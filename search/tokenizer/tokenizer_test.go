@@ -55,3 +55,26 @@ func TestTokenize(t *testing.T) {
 		})
 	}
 }
+
+func TestTokenizeWithOffsets(t *testing.T) {
+	expected := []Token{
+		{Term: "foo", Start: 0, End: 3},
+		{Term: "com", Start: 4, End: 7},
+		{Term: "blargle", Start: 8, End: 15},
+		{Term: "flargle", Start: 16, End: 23},
+	}
+
+	assert.Equal(t, expected, TokenizerWithOffsets()("foo.com/blargle:flargle"))
+}
+
+func TestTokenizeWithOffsets_sameTermsAsTokenize(t *testing.T) {
+	text := ":::@@@---...   multiple :::@@@---...  terms.example-com  :::@@@---..."
+
+	var terms []string
+
+	for _, token := range TokenizerWithOffsets()(text) {
+		terms = append(terms, token.Term)
+	}
+
+	assert.Equal(t, Tokenizer()(text), terms)
+}
@@ -0,0 +1,141 @@
+// Package watch provides standing queries. The indexers of a
+// Controller evaluate every standing query against each changed
+// object, and the changes that match are delivered to the
+// subscribers of those queries.
+package watch
+
+import (
+	"sync"
+
+	"github.com/kubideh/kubesearch/search/controller"
+	"github.com/kubideh/kubesearch/search/index"
+	"github.com/kubideh/kubesearch/search/searcher"
+	"k8s.io/klog/v2"
+)
+
+// subscriptionBuffer is the number of hits that a Subscription holds
+// before its subscriber is considered too slow and it's closed.
+const subscriptionBuffer = 256
+
+// Hit is a change to an object that matches a standing query, or
+// that used to match it.
+type Hit struct {
+	Change  controller.Change
+	Matched bool // Matched is whether the object matches the query after the change
+}
+
+// Subscription is a standing query. Hits are delivered until the
+// Subscription is canceled or its subscriber falls behind, and then
+// Hits is closed.
+type Subscription struct {
+	query      searcher.Query
+	namespaces map[string]bool
+	hits       chan Hit
+	seen       map[string]bool // seen are the DocIDs of objects that matched, and they're guarded by the Registry
+}
+
+// Hits returns the channel on which hits are delivered.
+func (s *Subscription) Hits() <-chan Hit {
+	return s.hits
+}
+
+// Registry holds standing queries.
+type Registry struct {
+	subscriptions map[*Subscription]bool
+	mutex         sync.Mutex
+}
+
+// CreateRegistry returns Registry objects. Register Notify with a
+// Controller using OnChange.
+func CreateRegistry() *Registry {
+	return &Registry{subscriptions: make(map[*Subscription]bool)}
+}
+
+// Subscribe registers the given query as a standing query, and only
+// objects in the given namespaces match, unless none are given.
+func (r *Registry) Subscribe(query searcher.Query, namespaces []string) *Subscription {
+	result := &Subscription{
+		query:      query,
+		namespaces: make(map[string]bool, len(namespaces)),
+		hits:       make(chan Hit, subscriptionBuffer),
+		seen:       make(map[string]bool),
+	}
+
+	for _, n := range namespaces {
+		result.namespaces[n] = true
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.subscriptions[result] = true
+
+	return result
+}
+
+// Seed records that the objects of the given postings already match
+// the query of the given Subscription, e.g., because they were found
+// by searching, so that a hit is delivered once they no longer match.
+func (r *Registry) Seed(s *Subscription, postings []index.Posting) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, p := range postings {
+		s.seen[p.DocID().String()] = true
+	}
+}
+
+// Cancel unregisters the given Subscription, and it closes its hits.
+func (r *Registry) Cancel(s *Subscription) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.cancel(s)
+}
+
+func (r *Registry) cancel(s *Subscription) {
+	if r.subscriptions[s] {
+		delete(r.subscriptions, s)
+		close(s.hits)
+	}
+}
+
+// Notify evaluates every standing query against the object of the
+// given change. A hit is delivered if the object matches, or if it
+// matched before and either no longer matches or was deleted. It's
+// a controller.ChangeFunc, and it never blocks the indexer; a
+// Subscription whose hits aren't received is canceled instead.
+func (r *Registry) Notify(change controller.Change) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	id := change.Posting.DocID().String()
+
+	for s := range r.subscriptions {
+		if len(s.namespaces) > 0 && !s.namespaces[change.Posting.Namespace()] {
+			continue
+		}
+
+		matched := !change.Deleted() && s.query.Matches(change.Terms, change.DocValues)
+
+		if !matched && !s.seen[id] {
+			continue
+		}
+
+		if matched {
+			s.seen[id] = true
+		} else {
+			delete(s.seen, id)
+		}
+
+		hit := Hit{Change: change, Matched: matched}
+		hit.Change.Posting = s.query.Rank(change.Posting)
+
+		select {
+		case s.hits <- hit:
+		default:
+			klog.Warningln("Canceling a standing query, because its subscriber fell behind")
+			r.cancel(s)
+		}
+	}
+}
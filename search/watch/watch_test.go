@@ -0,0 +1,114 @@
+package watch
+
+import (
+	"testing"
+
+	"github.com/kubideh/kubesearch/search/controller"
+	"github.com/kubideh/kubesearch/search/index"
+	"github.com/kubideh/kubesearch/search/searcher"
+	"github.com/kubideh/kubesearch/search/tokenizer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotify(t *testing.T) {
+	registry := CreateRegistry()
+	subscription := registry.Subscribe(parse(t, "blargle restarts:>5"), nil)
+
+	registry.Notify(change("flargle/blargle", 6))
+	registry.Notify(change("flargle/foo", 6))
+	registry.Notify(change("flargle/blargle", 7))
+	registry.Notify(change("flargle/blargle", 0))
+	registry.Notify(change("flargle/blargle", 0))
+
+	hits := received(subscription)
+	require.Len(t, hits, 3)
+	assert.True(t, hits[0].Matched)
+	assert.Equal(t, 1, hits[0].Change.Posting.TermFrequency)
+	assert.True(t, hits[1].Matched)
+	assert.False(t, hits[2].Matched)
+}
+
+func TestNotify_deleted(t *testing.T) {
+	registry := CreateRegistry()
+	subscription := registry.Subscribe(parse(t, "blargle"), nil)
+
+	registry.Seed(subscription, []index.Posting{{StoredObjectKey: "flargle/blargle", K8sResourceKind: "Pod"}})
+	registry.Notify(controller.Change{Posting: index.Posting{StoredObjectKey: "flargle/blargle", K8sResourceKind: "Pod"}, Terms: []string{"flargle", "blargle"}})
+	registry.Notify(controller.Change{Posting: index.Posting{StoredObjectKey: "flargle/blargle", K8sResourceKind: "Pod"}, Terms: []string{"flargle", "blargle"}})
+
+	hits := received(subscription)
+	require.Len(t, hits, 1)
+	assert.False(t, hits[0].Matched)
+	assert.True(t, hits[0].Change.Deleted())
+}
+
+func TestNotify_namespaces(t *testing.T) {
+	registry := CreateRegistry()
+	subscription := registry.Subscribe(parse(t, "blargle"), []string{"bobble"})
+
+	registry.Notify(change("flargle/blargle", 0))
+	registry.Notify(change("bobble/blargle", 0))
+
+	hits := received(subscription)
+	require.Len(t, hits, 1)
+	assert.Equal(t, "bobble", hits[0].Change.Posting.Namespace())
+}
+
+func TestNotify_cancelsSlowSubscribers(t *testing.T) {
+	registry := CreateRegistry()
+	subscription := registry.Subscribe(parse(t, "blargle"), nil)
+
+	for i := 0; i <= subscriptionBuffer; i++ {
+		registry.Notify(change("flargle/blargle", 0))
+	}
+
+	assert.Len(t, received(subscription), subscriptionBuffer)
+
+	_, ok := <-subscription.Hits()
+	assert.False(t, ok)
+}
+
+func TestCancel(t *testing.T) {
+	registry := CreateRegistry()
+	subscription := registry.Subscribe(parse(t, "blargle"), nil)
+
+	registry.Cancel(subscription)
+	registry.Cancel(subscription)
+	registry.Notify(change("flargle/blargle", 0))
+
+	_, ok := <-subscription.Hits()
+	assert.False(t, ok)
+}
+
+func parse(t *testing.T, query string) searcher.Query {
+	result, err := searcher.ParseQuery(query, tokenizer.Tokenizer())
+	require.NoError(t, err)
+	return result
+}
+
+func change(key string, restarts int64) controller.Change {
+	posting := index.Posting{StoredObjectKey: key, K8sResourceKind: "Pod"}
+
+	return controller.Change{
+		Posting:   posting,
+		Terms:     tokenizer.Tokenizer()(key),
+		DocValues: index.DocValues{index.DocValueRestartCount: index.NumberValue(restarts)},
+		Object:    key,
+	}
+}
+
+// received returns the hits delivered so far.
+func received(s *Subscription) (result []Hit) {
+	for {
+		select {
+		case hit, ok := <-s.Hits():
+			if !ok {
+				return
+			}
+			result = append(result, hit)
+		default:
+			return
+		}
+	}
+}
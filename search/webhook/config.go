@@ -0,0 +1,90 @@
+package webhook
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+
+	"github.com/kubideh/kubesearch/search/searcher"
+	"github.com/kubideh/kubesearch/search/tokenizer"
+	"sigs.k8s.io/yaml"
+)
+
+// Config declares standing queries, e.g.,
+//
+//	standingQueries:
+//	- name: crashing-payments
+//	  query: payment restarts:>5
+//	  namespaces: [prod]
+//	  url: https://hooks.example.com/kubesearch
+type Config struct {
+	StandingQueries []StandingQuery `json:"standingQueries"`
+}
+
+// StandingQuery is a query that's evaluated against every indexed
+// change. Its webhook is called whenever an object starts matching
+// it.
+type StandingQuery struct {
+	Name       string   `json:"name"`                 // Name identifies the query in notifications
+	Query      string   `json:"query"`                // Query is a query string, the same as that of the search API
+	Namespaces []string `json:"namespaces,omitempty"` // Namespaces are the only namespaces in which objects match, and empty means every namespace
+	URL        string   `json:"url"`                  // URL is the webhook to which notifications are POSTed
+}
+
+// LoadConfig reads and validates the given file of standing queries.
+func LoadConfig(path string) (Config, error) {
+	var result Config
+
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return result, err
+	}
+
+	if err := yaml.UnmarshalStrict(data, &result); err != nil {
+		return result, fmt.Errorf("invalid standing queries %s: %w", path, err)
+	}
+
+	names := make(map[string]bool)
+
+	for _, q := range result.StandingQueries {
+		if err := q.Validate(); err != nil {
+			return result, fmt.Errorf("invalid standing queries %s: %w", path, err)
+		}
+
+		if names[q.Name] {
+			return result, fmt.Errorf("invalid standing queries %s: the name %q isn't unique", path, q.Name)
+		}
+
+		names[q.Name] = true
+	}
+
+	return result, nil
+}
+
+// Validate returns an error if the given standing query has no name,
+// if its query can't be parsed or matches nothing, or if its webhook
+// isn't an absolute HTTP or HTTPS URL.
+func (q StandingQuery) Validate() error {
+	if q.Name == "" {
+		return fmt.Errorf("a standing query has no name")
+	}
+
+	parsed, err := searcher.ParseQuery(q.Query, tokenizer.Tokenizer())
+
+	if err != nil {
+		return fmt.Errorf("standing query %q: %w", q.Name, err)
+	}
+
+	if parsed.Empty() {
+		return fmt.Errorf("standing query %q has an empty query", q.Name)
+	}
+
+	u, err := url.Parse(q.URL)
+
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("standing query %q: the url must be an absolute http or https URL, got %q", q.Name, q.URL)
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package webhook
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	path := writeConfig(t, `
+standingQueries:
+- name: crashing-payments
+  query: payment restarts:>5
+  namespaces: [prod]
+  url: https://hooks.example.com/kubesearch
+`)
+
+	config, err := LoadConfig(path)
+
+	require.NoError(t, err)
+	assert.Equal(t, []StandingQuery{{Name: "crashing-payments", Query: "payment restarts:>5", Namespaces: []string{"prod"}, URL: "https://hooks.example.com/kubesearch"}}, config.StandingQueries)
+}
+
+func TestValidate_fields(t *testing.T) {
+	for _, query := range []string{"image:latest ns:prod", "label:owner="} {
+		q := StandingQuery{Name: "a", Query: query, URL: "https://hooks.example.com/kubesearch"}
+
+		assert.NoError(t, q.Validate(), query)
+	}
+}
+
+func TestLoadConfig_invalid(t *testing.T) {
+	for _, content := range []string{
+		"standingQueries: [{query: payment, url: http://example.com}]",
+		"standingQueries: [{name: a, query: '', url: http://example.com}]",
+		"standingQueries: [{name: a, query: 'restarts:>x', url: http://example.com}]",
+		"standingQueries: [{name: a, query: 'label:=web', url: http://example.com}]",
+		"standingQueries: [{name: a, query: payment, url: example.com}]",
+		"standingQueries: [{name: a, query: payment, url: http://example.com}, {name: a, query: nginx, url: http://example.com}]",
+		"standingQueries: [{name: a, query: payment, url: http://example.com, flargle: true}]",
+	} {
+		_, err := LoadConfig(writeConfig(t, content))
+
+		assert.Error(t, err, content)
+	}
+}
+
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "standing-queries.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
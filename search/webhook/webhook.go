@@ -0,0 +1,393 @@
+// Package webhook evaluates standing queries against every indexed
+// change, and it notifies the webhook of a standing query whenever
+// an object starts matching it.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/kubideh/kubesearch/search/api"
+	"github.com/kubideh/kubesearch/search/finder"
+	"github.com/kubideh/kubesearch/search/index"
+	"github.com/kubideh/kubesearch/search/searcher"
+	"github.com/kubideh/kubesearch/search/tokenizer"
+	"github.com/kubideh/kubesearch/search/watch"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// DeliveryHeader holds the ID of a notification, which is the
+	// same for every attempt to deliver it, so that webhooks can
+	// ignore duplicates.
+	DeliveryHeader = "X-Kubesearch-Delivery"
+
+	maxAttempts    = 5
+	workers        = 2
+	requestTimeout = 10 * time.Second
+)
+
+// Notification is the JSON-encoded body POSTed to the webhook of a
+// standing query when an object starts matching it.
+type Notification struct {
+	Name      string       `json:"name"`      // Name is the name of the standing query
+	Query     string       `json:"query"`     // Query is the query string of the standing query
+	Object    api.ResultV2 `json:"object"`    // Object is the object that matched
+	MatchedAt time.Time    `json:"matchedAt"` // MatchedAt is when the object started matching
+}
+
+// delivery is a notification waiting in the workqueue. Notifications
+// are queued by ID, so the same notification is queued only once.
+type delivery struct {
+	id  string
+	url string
+}
+
+// SourceConfig is the source of the standing queries given to
+// Create, e.g., those of the file given by `-standing-queries`.
+const SourceConfig = "config"
+
+// Notifier calls the webhooks of standing queries.
+type Notifier struct {
+	search      searcher.SearchFunc
+	findAll     finder.FindAllFunc
+	registry    *watch.Registry
+	ready       func() bool
+	client      *http.Client
+	rateLimiter workqueue.RateLimiter
+
+	sources map[string][]StandingQuery // sources are the standing queries of each source
+	running *run                       // running is nil unless Run is running
+	mutex   sync.Mutex
+
+	bodies      map[string][]byte // bodies of queued notifications are keyed by ID
+	bodiesMutex sync.Mutex
+}
+
+// run is what's needed to evaluate standing queries while Run is
+// running.
+type run struct {
+	ctx     context.Context
+	queue   workqueue.RateLimitingInterface
+	cancels map[string]context.CancelFunc // cancels stop evaluating the standing queries of each source
+	wg      sync.WaitGroup
+}
+
+// Create returns Notifier objects with the given standing queries
+// as those of SourceConfig. The given registry must be notified of
+// every indexed change, and ready must return true once the index is
+// up-to-date. Objects are found using findAll when catching up on
+// standing queries whose notifications fell behind.
+func Create(queries []StandingQuery, search searcher.SearchFunc, findAll finder.FindAllFunc, registry *watch.Registry, ready func() bool) *Notifier {
+	return &Notifier{
+		search:      search,
+		findAll:     findAll,
+		registry:    registry,
+		ready:       ready,
+		client:      &http.Client{Timeout: requestTimeout},
+		rateLimiter: workqueue.NewItemExponentialFailureRateLimiter(time.Second, time.Minute),
+		sources:     map[string][]StandingQuery{SourceConfig: queries},
+		bodies:      make(map[string][]byte),
+	}
+}
+
+// Set replaces the standing queries of the given source, e.g.,
+// SavedSearches. If Run is running, then the standing queries of that
+// source are evaluated again from scratch.
+func (n *Notifier) Set(source string, queries []StandingQuery) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	n.sources[source] = queries
+
+	if n.running != nil {
+		n.start(source)
+	}
+}
+
+// Run notifies webhooks until the given context is done. Objects
+// that already match once the index is ready aren't notified, and
+// an object is notified again only after it stops matching. Failed
+// notifications are retried with exponential backoff.
+func (n *Notifier) Run(ctx context.Context) {
+	if err := wait.PollImmediateUntil(time.Second, func() (bool, error) { return n.ready(), nil }, ctx.Done()); err != nil {
+		return
+	}
+
+	queue := workqueue.NewNamedRateLimitingQueue(n.rateLimiter, "webhooks")
+	defer queue.ShutDown()
+
+	for i := 0; i < workers; i++ {
+		go n.deliverAll(queue)
+	}
+
+	r := &run{ctx: ctx, queue: queue, cancels: make(map[string]context.CancelFunc)}
+
+	n.mutex.Lock()
+	n.running = r
+
+	for source := range n.sources {
+		n.start(source)
+	}
+
+	n.mutex.Unlock()
+
+	<-ctx.Done()
+
+	n.mutex.Lock()
+	n.running = nil
+	n.mutex.Unlock()
+
+	r.wg.Wait()
+}
+
+// start evaluates the standing queries of the given source, and it
+// stops evaluating those that were started before.
+func (n *Notifier) start(source string) {
+	r := n.running
+
+	if cancel, ok := r.cancels[source]; ok {
+		cancel()
+	}
+
+	ctx, cancel := context.WithCancel(r.ctx)
+	r.cancels[source] = cancel
+
+	for _, q := range n.sources[source] {
+		r.wg.Add(1)
+
+		go func(q StandingQuery) {
+			defer r.wg.Done()
+			n.evaluate(ctx, q, r.queue)
+		}(q)
+	}
+}
+
+// evaluate queues a notification for each object that starts
+// matching the given standing query, until ctx is done. If its
+// notifications fall behind, then it subscribes again, and it catches
+// up on the objects that started matching in the meantime.
+func (n *Notifier) evaluate(ctx context.Context, q StandingQuery, queue workqueue.RateLimitingInterface) {
+	parsed, err := searcher.ParseQuery(q.Query, tokenizer.Tokenizer())
+
+	if err != nil {
+		klog.Errorf("Skipping standing query %q: %v", q.Name, err)
+		return
+	}
+
+	var matched map[string]bool
+
+	for {
+		subscription := n.registry.Subscribe(parsed, q.Namespaces)
+		current := api.RetainNamespaces(n.search(q.Query), q.Namespaces)
+
+		n.registry.Seed(subscription, current)
+
+		if matched == nil {
+			matched = make(map[string]bool, len(current))
+
+			for _, p := range current {
+				matched[p.DocID().String()] = true
+			}
+
+			klog.Infof("Evaluating standing query %q", q.Name)
+		} else {
+			n.catchUp(queue, q, parsed, matched, current)
+		}
+
+		done := n.follow(ctx, subscription, q, parsed, matched, queue)
+		n.registry.Cancel(subscription)
+
+		if done {
+			return
+		}
+
+		klog.Warningf("Subscribing to standing query %q again, because its notifications fell behind", q.Name)
+	}
+}
+
+// follow queues a notification for each hit of the given
+// subscription whose object starts matching. It returns true once
+// ctx is done, or false if the subscription fell behind.
+func (n *Notifier) follow(ctx context.Context, subscription *watch.Subscription, q StandingQuery, parsed searcher.Query, matched map[string]bool, queue workqueue.RateLimitingInterface) bool {
+	for {
+		select {
+		case <-ctx.Done():
+			return true
+		case hit, ok := <-subscription.Hits():
+			if !ok {
+				return false
+			}
+
+			id := hit.Change.Posting.DocID().String()
+
+			if !hit.Matched {
+				delete(matched, id)
+				continue
+			}
+
+			if matched[id] {
+				continue
+			}
+
+			matched[id] = true
+
+			n.enqueue(queue, q, createNotification(q, parsed, hit.Change.Posting, hit.Change.Object))
+		}
+	}
+}
+
+// catchUp queues a notification for each of the given postings of
+// objects that currently match but didn't match before, e.g.,
+// because their hits were dropped, and it forgets the objects that
+// no longer match.
+func (n *Notifier) catchUp(queue workqueue.RateLimitingInterface, q StandingQuery, parsed searcher.Query, matched map[string]bool, current []index.Posting) {
+	still := make(map[string]bool, len(current))
+	started := make(map[finder.Key]index.Posting)
+	var keys []finder.Key
+
+	for _, p := range current {
+		id := p.DocID().String()
+		still[id] = true
+
+		if !matched[id] {
+			key := finder.Key{StoredObjectKey: p.StoredObjectKey, K8sResourceKind: p.K8sResourceKind}
+			started[key] = p
+			keys = append(keys, key)
+		}
+	}
+
+	for id := range matched {
+		if !still[id] {
+			delete(matched, id)
+		}
+	}
+
+	objects, err := n.findAll(keys)
+
+	if err != nil {
+		klog.Errorf("Catching up on standing query %q: %v", q.Name, err)
+	}
+
+	for _, o := range objects {
+		posting := started[o.Key]
+		matched[posting.DocID().String()] = true
+
+		n.enqueue(queue, q, createNotification(q, parsed, posting, o.Item))
+	}
+}
+
+// createNotification returns the notification of the given standing
+// query about the object of the given posting, which has just started
+// matching it.
+func createNotification(q StandingQuery, parsed searcher.Query, posting index.Posting, object interface{}) Notification {
+	return Notification{
+		Name:      q.Name,
+		Query:     q.Query,
+		Object:    api.CreateResultV2(posting, object, parsed),
+		MatchedAt: time.Now(),
+	}
+}
+
+func (n *Notifier) enqueue(queue workqueue.RateLimitingInterface, q StandingQuery, notification Notification) {
+	body, err := json.Marshal(notification)
+
+	if err != nil {
+		klog.Errorln(err)
+		return
+	}
+
+	item := delivery{id: deliveryID(notification), url: q.URL}
+
+	n.bodiesMutex.Lock()
+	n.bodies[item.id] = body
+	n.bodiesMutex.Unlock()
+
+	queue.Add(item)
+}
+
+// deliveryID identifies a notification of a standing query about an
+// object. It's the same for every attempt to deliver the notification,
+// but it differs each time the object starts matching again.
+func deliveryID(notification Notification) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\x00%s\x00%s\x00%s\x00%s\x00%d", notification.Name, notification.Object.Kind, notification.Object.Namespace, notification.Object.Name, notification.Object.UID, notification.MatchedAt.UnixNano())
+	return hex.EncodeToString(hash.Sum(nil)[:16])
+}
+
+func (n *Notifier) deliverAll(queue workqueue.RateLimitingInterface) {
+	item, shutdown := queue.Get()
+
+	for !shutdown {
+		n.deliverOne(queue, item.(delivery))
+		queue.Done(item)
+
+		item, shutdown = queue.Get()
+	}
+}
+
+// deliverOne POSTs the given notification, and it retries later if
+// that fails, unless it has failed too many times.
+func (n *Notifier) deliverOne(queue workqueue.RateLimitingInterface, item delivery) {
+	n.bodiesMutex.Lock()
+	body, ok := n.bodies[item.id]
+	n.bodiesMutex.Unlock()
+
+	if !ok {
+		// It was delivered while it was queued again.
+		queue.Forget(item)
+		return
+	}
+
+	err := n.post(item, body)
+
+	if err != nil && queue.NumRequeues(item) < maxAttempts-1 {
+		klog.V(2).Infof("Retrying the notification %s to %s: %v", item.id, item.url, err)
+		queue.AddRateLimited(item)
+		return
+	}
+
+	if err != nil {
+		klog.Errorf("Dropping the notification %s to %s after %d attempts: %v", item.id, item.url, maxAttempts, err)
+	}
+
+	queue.Forget(item)
+
+	n.bodiesMutex.Lock()
+	delete(n.bodies, item.id)
+	n.bodiesMutex.Unlock()
+}
+
+func (n *Notifier) post(item delivery, body []byte) error {
+	request, err := http.NewRequest(http.MethodPost, item.url, bytes.NewReader(body))
+
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(DeliveryHeader, item.id)
+
+	response, err := n.client.Do(request)
+
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("unexpected status: %s", response.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,232 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kubideh/kubesearch/search/controller"
+	"github.com/kubideh/kubesearch/search/finder"
+	"github.com/kubideh/kubesearch/search/index"
+	"github.com/kubideh/kubesearch/search/tokenizer"
+	"github.com/kubideh/kubesearch/search/watch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/workqueue"
+)
+
+func TestNotifier(t *testing.T) {
+	var mutex sync.Mutex
+	var received []Notification
+	var deliveries []string
+	failures := 1
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		deliveries = append(deliveries, request.Header.Get(DeliveryHeader))
+
+		if failures > 0 {
+			failures--
+			writer.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		var n Notification
+		require.NoError(t, json.NewDecoder(request.Body).Decode(&n))
+		received = append(received, n)
+	}))
+	defer server.Close()
+
+	registry := watch.CreateRegistry()
+	existing := index.Posting{StoredObjectKey: "flargle/blargle-old", K8sResourceKind: "Pod"}
+	search := func(string) []index.Posting { return []index.Posting{existing} }
+
+	notifier := Create([]StandingQuery{{Name: "blargles", Query: "blargle", Namespaces: []string{"flargle"}, URL: server.URL}}, search, findAll, registry, func() bool { return true })
+	notifier.rateLimiter = workqueue.NewItemExponentialFailureRateLimiter(time.Millisecond, time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go notifier.Run(ctx)
+
+	// Wait for the standing query to be subscribed.
+	time.Sleep(100 * time.Millisecond)
+
+	registry.Notify(change("flargle/blargle-old"))
+	registry.Notify(change("bobble/blargle"))
+	registry.Notify(change("flargle/blargle"))
+	registry.Notify(change("flargle/blargle"))
+
+	require.Eventually(t, func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return len(received) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	time.Sleep(100 * time.Millisecond)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	require.Len(t, received, 1)
+	assert.Equal(t, "blargles", received[0].Name)
+	assert.Equal(t, "blargle", received[0].Query)
+	assert.Equal(t, "blargle", received[0].Object.Name)
+	assert.Equal(t, "flargle", received[0].Object.Namespace)
+	require.Len(t, deliveries, 2)
+	assert.Equal(t, deliveries[0], deliveries[1])
+}
+
+func TestNotifierSet(t *testing.T) {
+	var mutex sync.Mutex
+	var received []Notification
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		var n Notification
+		require.NoError(t, json.NewDecoder(request.Body).Decode(&n))
+		received = append(received, n)
+	}))
+	defer server.Close()
+
+	registry := watch.CreateRegistry()
+	search := func(string) []index.Posting { return nil }
+
+	notifier := Create(nil, search, findAll, registry, func() bool { return true })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go notifier.Run(ctx)
+
+	notifier.Set("flargles", []StandingQuery{{Name: "blargles", Query: "blargle", URL: server.URL}})
+
+	// Wait for the standing query to be subscribed.
+	time.Sleep(100 * time.Millisecond)
+
+	registry.Notify(change("flargle/blargle"))
+
+	require.Eventually(t, func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return len(received) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	notifier.Set("flargles", nil)
+
+	// Wait for the standing query to be cancelled.
+	time.Sleep(100 * time.Millisecond)
+
+	registry.Notify(change("bobble/blargle"))
+
+	time.Sleep(100 * time.Millisecond)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	require.Len(t, received, 1)
+	assert.Equal(t, "flargle", received[0].Object.Namespace)
+}
+
+func TestNotifier_fallsBehind(t *testing.T) {
+	var mutex sync.Mutex
+	received := make(map[string]bool)
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		var n Notification
+		require.NoError(t, json.NewDecoder(request.Body).Decode(&n))
+		received[n.Object.Name] = true
+	}))
+	defer server.Close()
+
+	var current []index.Posting
+	var searchMutex sync.Mutex
+
+	search := func(string) []index.Posting {
+		searchMutex.Lock()
+		defer searchMutex.Unlock()
+		return current
+	}
+
+	registry := watch.CreateRegistry()
+	notifier := Create([]StandingQuery{{Name: "blargles", Query: "blargle", URL: server.URL}}, search, findAll, registry, func() bool { return true })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go notifier.Run(ctx)
+
+	// Wait for the standing query to be subscribed.
+	time.Sleep(100 * time.Millisecond)
+
+	// Block the evaluation of hits until the subscription overflows,
+	// so that the hit of blargle-late is dropped.
+	notifier.bodiesMutex.Lock()
+
+	for i := 0; i <= 256; i++ {
+		registry.Notify(change(fmt.Sprintf("flargle/blargle-%d", i)))
+	}
+
+	registry.Notify(change("flargle/blargle-late"))
+
+	searchMutex.Lock()
+	current = []index.Posting{change("flargle/blargle-late").Posting}
+	searchMutex.Unlock()
+
+	notifier.bodiesMutex.Unlock()
+
+	require.Eventually(t, func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return received["blargle-late"]
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestDeliveryID(t *testing.T) {
+	n := Notification{Name: "blargles", MatchedAt: time.Unix(1, 0)}
+	n.Object.Name = "blargle"
+
+	other := n
+	other.Object.UID = "e7b1c3f0-5a4d-4b0e-9a43-2d8f6c1e7a10"
+
+	again := n
+	again.MatchedAt = time.Unix(2, 0)
+
+	assert.Equal(t, deliveryID(n), deliveryID(n))
+	assert.NotEqual(t, deliveryID(n), deliveryID(other))
+	assert.NotEqual(t, deliveryID(n), deliveryID(again), "an object that matches again is notified again")
+}
+
+// findAll finds the objects of the given keys as they're created by
+// change.
+func findAll(keys []finder.Key) (result []finder.K8sObject, err error) {
+	for _, k := range keys {
+		result = append(result, finder.K8sObject{Key: k, Item: change(k.StoredObjectKey).Object})
+	}
+	return
+}
+
+func change(key string) controller.Change {
+	metadata := strings.SplitN(key, "/", 2)
+
+	return controller.Change{
+		Posting: index.Posting{StoredObjectKey: key, K8sResourceKind: "Pod"},
+		Terms:   tokenizer.Tokenizer()(key),
+		Object:  &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: metadata[1], Namespace: metadata[0]}},
+	}
+}